@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/vdchnsk/qrk/src/compiler"
+	"github.com/vdchnsk/qrk/src/module"
 	"github.com/vdchnsk/qrk/src/object"
 	"github.com/vdchnsk/qrk/src/runner"
 	"github.com/vdchnsk/qrk/src/vm"
@@ -18,6 +19,7 @@ func Start(in io.Reader, out io.Writer) {
 	symbolTable := compiler.NewSymbolTable()
 	constants := []object.Object{}
 	globals := make([]object.Object, vm.GlobalVarsSize)
+	loader := module.NewLoader[*compiler.Module]("", module.SearchPathsFromEnv()...)
 
 	for {
 		fmt.Print(REPL_PROMPT_MESSAGE)
@@ -28,7 +30,8 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		// TODO: add ability to specify run mode via CLI
-		output := runner.Compile(scanner.Text(), out, symbolTable, constants, globals)
+		var output object.Object
+		output, constants = runner.Compile(scanner.Text(), out, symbolTable, constants, globals, loader)
 		if output == nil {
 			continue
 		}
@@ -2,7 +2,11 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
+	"math/big"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/vdchnsk/qrk/src/token"
 )
@@ -50,14 +54,18 @@ type Identifier struct {
 
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) expressionNode()      {}
+func (i *Identifier) patternNode()         {}
 func (i *Identifier) String() string       { return i.Value }
 
 type LetStatement struct {
-	Token      token.Token // "let" token
+	Token      token.Token // "let" or "const" token
 	Identifier *Identifier
 	Value      Expression
 }
 
+// IsConst tells whether the binding is declared with const, such bindings can't be reassigned or redeclared
+func (ls *LetStatement) IsConst() bool { return ls.Token.Type == token.CONST }
+
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) String() string {
@@ -76,6 +84,118 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// Pattern is matched against a value, binding its identifiers to the matching parts of the value.
+// Let statements use irrefutable patterns: identifiers, wildcards, array and hashmap patterns,
+// match expressions additionally use literal patterns
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// WildcardPattern matches any value without binding it, e.g. _
+type WildcardPattern struct {
+	Token token.Token // "_" token
+}
+
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) String() string       { return wp.Token.Literal }
+
+// LiteralPattern matches values equal to the literal, e.g. 1, -2.5, "x" or true
+type LiteralPattern struct {
+	Token token.Token // first token of the literal
+	Value Expression
+}
+
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Token.Literal }
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) String() string       { return lp.Value.String() }
+
+// ArrayPattern binds array elements by position, e.g. [a, b, ...rest]
+type ArrayPattern struct {
+	Token    token.Token // "[" token
+	Elements []Pattern
+	Rest     *Identifier // optional, collects the remaining elements
+}
+
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, element := range ap.Elements {
+		elements = append(elements, element.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashMapPattern binds hashmap values by their keys, e.g. {"type": t},
+// {name} is a shorthand for {"name": name}
+type HashMapPattern struct {
+	Token  token.Token // "{" token
+	Keys   []Expression
+	Values []Pattern
+}
+
+func (hp *HashMapPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashMapPattern) patternNode()         {}
+func (hp *HashMapPattern) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for i, key := range hp.Keys {
+		value := hp.Values[i]
+
+		str, isStr := key.(*StringLiteral)
+		ident, isIdent := value.(*Identifier)
+		if isStr && isIdent && str.Value == ident.Value {
+			pairs = append(pairs, ident.String())
+			continue
+		}
+
+		pairs = append(pairs, key.String()+": "+value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+type LetDestructuringStatement struct {
+	Token   token.Token // "let" token
+	Pattern Pattern
+	Value   Expression
+}
+
+func (lds *LetDestructuringStatement) TokenLiteral() string { return lds.Token.Literal }
+func (lds *LetDestructuringStatement) statementNode()       {}
+func (lds *LetDestructuringStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(lds.TokenLiteral() + " ")
+	out.WriteString(lds.Pattern.String())
+	out.WriteString(" = ")
+
+	if lds.Value != nil {
+		out.WriteString(lds.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
 type AssignStatement struct {
 	Token      token.Token // "=" token
 	Identifier *Identifier
@@ -99,6 +219,54 @@ func (as *AssignStatement) String() string {
 	return out.String()
 }
 
+// IndexAssignStatement mutates an element of a collection, e.g. `a[0] = 1` or `person["age"] = 11`
+type IndexAssignStatement struct {
+	Token  token.Token // "=" token
+	Target *IndexExpression
+	Value  Expression
+}
+
+func (ias *IndexAssignStatement) TokenLiteral() string { return ias.Token.Literal }
+func (ias *IndexAssignStatement) statementNode()       {}
+func (ias *IndexAssignStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ias.Target.String())
+	out.WriteString(" = ")
+
+	if ias.Value != nil {
+		out.WriteString(ias.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// MemberAssignStatement mutates a field of a record, e.g. `p.x = 1`
+type MemberAssignStatement struct {
+	Token  token.Token // "=" token
+	Target *MemberExpression
+	Value  Expression
+}
+
+func (mas *MemberAssignStatement) TokenLiteral() string { return mas.Token.Literal }
+func (mas *MemberAssignStatement) statementNode()       {}
+func (mas *MemberAssignStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(mas.Target.String())
+	out.WriteString(" = ")
+
+	if mas.Value != nil {
+		out.WriteString(mas.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
 type ReturnStatement struct {
 	Token token.Token // "return" token
 	Value Expression
@@ -120,6 +288,140 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// YieldStatement suspends the generator function it's in, handing the value over to the code resuming the generator
+type YieldStatement struct {
+	Token token.Token // "yield" token
+	Value Expression  // optional, `yield;` hands over null
+}
+
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) statementNode()       {}
+func (ys *YieldStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ys.TokenLiteral())
+
+	if ys.Value != nil {
+		out.WriteString(" " + ys.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+type ThrowStatement struct {
+	Token token.Token // "throw" token
+	Value Expression
+}
+
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ts.TokenLiteral() + " ")
+
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// TryStatement has at least one of the catch and finally blocks
+type TryStatement struct {
+	Token      token.Token // "try" token
+	Block      *BlockStatement
+	CatchParam *Identifier // optional, `catch { ... }` ignores the caught value
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(ts.Block.String())
+
+	if ts.Catch != nil {
+		out.WriteString(" catch ")
+		if ts.CatchParam != nil {
+			out.WriteString("(" + ts.CatchParam.String() + ") ")
+		}
+		out.WriteString(ts.Catch.String())
+	}
+
+	if ts.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(ts.Finally.String())
+	}
+
+	return out.String()
+}
+
+// ImportStatement binds the exports of another file to a namespace, e.g. import "lib/strings.qrk" as s;
+type ImportStatement struct {
+	Token token.Token // "import" token
+	Path  *StringLiteral
+	Alias *Identifier
+}
+
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " " + is.Path.String() + " as " + is.Alias.String() + ";"
+}
+
+// ExportStatement makes a top-level let, function or struct declaration accessible to the importers of the file
+type ExportStatement struct {
+	Token     token.Token // "export" token
+	Statement Statement   // *LetStatement, *StructStatement, or *ExpressionStatement with a named *FuncLiteral
+}
+
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
+// Name is the name of the exported binding
+func (es *ExportStatement) Name() string {
+	switch statement := es.Statement.(type) {
+	case *LetStatement:
+		return statement.Identifier.Value
+	case *StructStatement:
+		return statement.Name.Value
+	case *ExpressionStatement:
+		return statement.Value.(*FuncLiteral).Name
+	default:
+		return ""
+	}
+}
+
+// StructStatement declares a record type with a fixed set of fields, e.g. struct Point { x, y },
+// the name is bound to the constructor taking the values of the fields in declaration order
+type StructStatement struct {
+	Token  token.Token // "struct" token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStatement) statementNode()       {}
+func (ss *StructStatement) String() string {
+	fields := []string{}
+	for _, field := range ss.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return ss.TokenLiteral() + " " + ss.Name.String() + " { " + strings.Join(fields, ", ") + " }"
+}
+
 type ExpressionStatement struct {
 	Token token.Token // first token of the expression
 	Value Expression
@@ -161,6 +463,25 @@ func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// BigIntegerLiteral is an integer literal which doesn't fit into int64
+type BigIntegerLiteral struct {
+	Token token.Token // INT token
+	Value *big.Int
+}
+
+func (bil *BigIntegerLiteral) TokenLiteral() string { return bil.Token.Literal }
+func (bil *BigIntegerLiteral) expressionNode()      {}
+func (bil *BigIntegerLiteral) String() string       { return bil.Token.Literal }
+
+type FloatLiteral struct {
+	Token token.Token // FLOAT token
+	Value float64
+}
+
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type StringLiteral struct {
 	Token token.Token // STRING token
 	Value string
@@ -168,7 +489,77 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) expressionNode()      {}
-func (sl *StringLiteral) String() string       { return sl.Token.Literal }
+func (sl *StringLiteral) String() string       { return quoteString(sl.Value) }
+
+// InterpolatedString is a string with embedded expressions, e.g. "hello ${name}".
+// Segments are the literal parts around the expressions, so there is always one more segment than expressions
+type InterpolatedString struct {
+	Token       token.Token // INTERPOLATION_START token
+	Segments    []string
+	Expressions []Expression
+}
+
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	out.WriteByte('"')
+
+	for i, segment := range is.Segments {
+		escapeString(&out, segment)
+
+		if i < len(is.Expressions) {
+			out.WriteString("${")
+			out.WriteString(is.Expressions[i].String())
+			out.WriteString("}")
+		}
+	}
+
+	out.WriteByte('"')
+
+	return out.String()
+}
+
+// quoteString is the inverse of the lexer's string decoding, so that the result can be lexed back into the same value
+func quoteString(value string) string {
+	var out bytes.Buffer
+
+	out.WriteByte('"')
+	escapeString(&out, value)
+	out.WriteByte('"')
+
+	return out.String()
+}
+
+func escapeString(out *bytes.Buffer, value string) {
+	for len(value) > 0 {
+		char, size := utf8.DecodeRuneInString(value)
+
+		switch {
+		case char == utf8.RuneError && size == 1:
+			fmt.Fprintf(out, "\\x%02x", value[0])
+		case char == '"':
+			out.WriteString(`\"`)
+		case char == '\\':
+			out.WriteString(`\\`)
+		case char == '$' && strings.HasPrefix(value, "${"):
+			out.WriteString(`\$`)
+		case char == '\n':
+			out.WriteString(`\n`)
+		case char == '\t':
+			out.WriteString(`\t`)
+		case char == '\r':
+			out.WriteString(`\r`)
+		case !unicode.IsPrint(char):
+			fmt.Fprintf(out, "\\u{%X}", char)
+		default:
+			out.WriteString(value[:size])
+		}
+
+		value = value[size:]
+	}
+}
 
 type Boolean struct {
 	Token token.Token
@@ -244,11 +635,130 @@ func (ife *IfExpression) String() string {
 	return out.String()
 }
 
+// MatchExpression evaluates the body of the first arm whose pattern matches the subject, or null if none does
+type MatchExpression struct {
+	Token   token.Token // The token "match"
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match ")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+type MatchArm struct {
+	Token   token.Token // first token of the pattern
+	Pattern Pattern
+	Guard   Expression // optional, `pattern if guard => ...`
+	Body    *BlockStatement
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+type WhileStatement struct {
+	Token     token.Token // The token "while"
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while ")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+type ForInStatement struct {
+	Token    token.Token // The token "for"
+	Key      *Identifier // only set in the `for key, value in ...` form
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatement) statementNode()       {}
+func (fs *ForInStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	if fs.Key != nil {
+		out.WriteString(fs.Key.String())
+		out.WriteString(", ")
+	}
+	out.WriteString(fs.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(" ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token token.Token // The token "break"
+}
+
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) String() string       { return bs.Token.Literal + ";" }
+
+type ContinueStatement struct {
+	Token token.Token // The token "continue"
+}
+
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) String() string       { return cs.Token.Literal + ";" }
+
+// SelfParameter is the first parameter of methods, calls like `person.greet()` pass the receiver as its argument
+const SelfParameter = "self"
+
 type FuncLiteral struct {
-	Token      token.Token // The token "fn"
+	Token      token.Token // The token "fn", or "=>" for arrow functions
 	Parameters []*Identifier
+	Defaults   []Expression // default values of the parameters, nil for the required ones
+	Rest       *Identifier  // collects the arguments passed after the parameters into an array, e.g. ...rest
 	Body       *BlockStatement
 	Identifier *Identifier
+	Name       string // name of a declared or let-bound function, shown in stack traces
+	// declared with `fn*`, calling it creates a generator instead of running the body
+	IsGenerator bool
 }
 
 func (fl *FuncLiteral) TokenLiteral() string { return fl.Token.Literal }
@@ -256,12 +766,25 @@ func (fl *FuncLiteral) expressionNode()      {}
 func (fl *FuncLiteral) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("fn ")
-	out.WriteString(fl.TokenLiteral())
+	out.WriteString("fn")
+	if fl.IsGenerator {
+		out.WriteString("*")
+	}
+	out.WriteString(" ")
+	if fl.Identifier != nil {
+		out.WriteString(fl.Identifier.String())
+	}
 
 	params := []string{}
-	for _, parameter := range fl.Parameters {
-		params = append(params, parameter.String())
+	for i, parameter := range fl.Parameters {
+		if defaultValue := fl.Default(i); defaultValue != nil {
+			params = append(params, parameter.String()+" = "+defaultValue.String())
+		} else {
+			params = append(params, parameter.String())
+		}
+	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
 	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ","))
@@ -272,6 +795,32 @@ func (fl *FuncLiteral) String() string {
 	return out.String()
 }
 
+// Default returns the default value of the i-th parameter, nil if the parameter is required
+func (fl *FuncLiteral) Default(i int) Expression {
+	if i >= len(fl.Defaults) {
+		return nil
+	}
+
+	return fl.Defaults[i]
+}
+
+// TakesSelf tells whether the function is a method, i.e. its first parameter is `self`
+func (fl *FuncLiteral) TakesSelf() bool {
+	return len(fl.Parameters) > 0 && fl.Parameters[0].Value == SelfParameter
+}
+
+// RequiredParamsCount is the amount of parameters without default values,
+// parameters with default values always follow the required ones
+func (fl *FuncLiteral) RequiredParamsCount() int {
+	for i := range fl.Parameters {
+		if fl.Default(i) != nil {
+			return i
+		}
+	}
+
+	return len(fl.Parameters)
+}
+
 type CallExpression struct {
 	Token     token.Token // "("
 	Function  Expression  // either Identifier or Function declaration
@@ -296,6 +845,19 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// SpawnExpression runs the call on a task of its own, e.g. spawn fetch(url),
+// a function which isn't called is run without arguments, e.g. spawn fn() { ... }
+type SpawnExpression struct {
+	Token token.Token // "spawn" token
+	Value Expression
+}
+
+func (se *SpawnExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpawnExpression) expressionNode()      {}
+func (se *SpawnExpression) String() string {
+	return se.TokenLiteral() + " " + se.Value.String()
+}
+
 type ArrayLiteral struct {
 	Token    token.Token // "["
 	Elements []Expression
@@ -357,3 +919,44 @@ func (ie *IndexExpression) String() string {
 
 	return out.String()
 }
+
+// SliceExpression takes the part of an array or a string between two indexes, e.g. a[1:3], omitted bounds are nil
+type SliceExpression struct {
+	Token token.Token // "["
+	Left  Expression
+	Start Expression
+	End   Expression
+}
+
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// MemberExpression accesses a named member of a value, e.g. strings.upper or p.x
+type MemberExpression struct {
+	Token  token.Token // "."
+	Object Expression
+	Member *Identifier
+}
+
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) String() string {
+	return me.Object.String() + "." + me.Member.String()
+}
@@ -26,3 +26,28 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestStringLiteralString(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"hello", `"hello"`},
+		{"say \"hi\"", `"say \"hi\""`},
+		{"a\nb\tc\\", `"a\nb\tc\\"`},
+		{"😀", `"😀"`},
+		{"\x00\u200b", `"\u{0}\u{200B}"`},
+		{"\xff", `"\xff"`},
+	}
+
+	for _, tt := range tests {
+		literal := &StringLiteral{
+			Token: token.Token{Type: token.STRING, Literal: tt.value},
+			Value: tt.value,
+		}
+
+		if literal.String() != tt.expected {
+			t.Errorf("literal.String() wrong. expected=%s, got=%s", tt.expected, literal.String())
+		}
+	}
+}
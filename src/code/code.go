@@ -24,6 +24,8 @@ func (instructions Instructions) fmt(opcodeDefinition *Operation, operands []int
 		return opcodeDefinition.Name
 	case 1:
 		return fmt.Sprintf("%s %d", opcodeDefinition.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", opcodeDefinition.Name, operands[0], operands[1])
 	}
 
 	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", opcodeDefinition.Name)
@@ -64,6 +66,13 @@ const (
 	OpSub
 	OpMul
 	OpDiv
+	OpMod
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
 
 	OpTrue
 	OpFalse
@@ -71,16 +80,28 @@ const (
 	OpEqual
 	OpNotEqual
 	OpGreaterThan
-	OpAnd
-	OpOr
+	OpGreaterThanOrEqual
+	OpLessThan
+	OpLessThanOrEqual
 
 	OpMinus
 	OpBang
+	OpBitNot
 
 	OpPop
+	OpDup  // pushes the value on top of the stack once more, used by destructuring
+	OpSwap // swaps the two values on top of the stack
 
-	OpGotoNotTruthy // goto only if value on top of the stack is not truthy
+	// match expressions test the value on top of the stack against a pattern, keeping it and pushing the result
+	OpMatchLiteral // compares hash keys of the value and the constant
+	OpMatchArray   // checks the value is an array of the operand length, or at least of it if the second operand is 1
+	OpMatchHashMap // checks the value is a hashmap containing all keys from the array constant
+
+	OpGotoNotTruthy      // goto only if value on top of the stack is not truthy
+	OpGotoNotTruthyOrPop // goto keeping the value on top of the stack if it is not truthy, pop it otherwise, used by `&&`
+	OpGotoTruthyOrPop    // goto keeping the value on top of the stack if it is truthy, pop it otherwise, used by `||`
 	OpGoto
+	OpGotoTable // goto the position the jump table constant maps the value on top of the stack to, if there is one
 
 	OpNull
 
@@ -92,15 +113,44 @@ const (
 
 	OpGetStdlib
 
+	OpGetFree
+	OpSetFree
+	OpClosure        // wraps a compiled function constant together with its captured free variables
+	OpCurrentClosure // pushes the closure of the currently executed function, used for self-reference
+	OpCaptureLocal   // pushes the cell of the local for a closure capturing it, the local is moved into the cell on the first capture
+	OpCaptureFree    // pushes the cell of the free variable for a closure capturing it further down
+
 	OpArray
 	OpHashMap
 
 	OpIndex
+	OpSetIndex  // sets the element of the collection at the index, both taken from the stack
+	OpSlice     // pushes the part of the collection between the start and end taken from the stack, null bounds are open
+	OpGetField  // pushes the field of the record on top of the stack, the operand is the constant of the field name
+	OpSetField  // sets the field of the record to the value on top of the stack, the operand is the constant of the field name
+	OpGetMethod // pushes the member of the receiver on top of the stack below the receiver, the operand is the constant of the member name
+
+	OpConcat // joins the operand amount of values from the stack into a string, used by string interpolation
+
+	OpIter     // replaces the iterable on top of the stack with its iterator
+	OpIterNext // pushes the next element(s) of the iterator, or goes to the operand once it is exhausted
+
+	OpLoop       // records the stack pointer at the beginning of the loop in the current stack frame
+	OpEndLoop    // forgets the innermost loop of the current stack frame once the loop is left
+	OpUnwindLoop // drops the values pushed since the beginning of the innermost loop, used by `break` and `continue`
+
+	OpTry    // registers the operand as the position of the exception handler in the current stack frame
+	OpEndTry // unregisters the innermost exception handler of the current stack frame
+	OpThrow  // throws the value on top of the stack
 
 	OpCall
+	OpCallMethod    // calls the member pushed by OpGetMethod, passing the receiver if the member takes `self`
+	OpGotoArgPassed // goes to the first operand if the argument of the parameter at the second operand was passed, skipping its default value
+	OpSpawn         // runs the function below the operand amount of arguments on a task of its own, the task takes their place
 
 	OpReturnValue
 	OpReturn
+	OpYield // suspends the generator, handing the value on top of the stack over to the code resuming it
 )
 
 var operations = map[Opcode]*Operation{
@@ -110,23 +160,42 @@ var operations = map[Opcode]*Operation{
 	OpSub: {Name: "OpSub"},
 	OpMul: {Name: "OpMul"},
 	OpDiv: {Name: "OpDiv"},
+	OpMod: {Name: "OpMod"},
+	OpPow: {Name: "OpPow"},
+
+	OpBitAnd:     {Name: "OpBitAnd"},
+	OpBitOr:      {Name: "OpBitOr"},
+	OpBitXor:     {Name: "OpBitXor"},
+	OpShiftLeft:  {Name: "OpShiftLeft"},
+	OpShiftRight: {Name: "OpShiftRight"},
 
 	OpTrue:  {Name: "OpTrue"},
 	OpFalse: {Name: "OpFalse"},
 
-	OpEqual:       {Name: "OpEqual"},
-	OpNotEqual:    {Name: "OpNotEqual"},
-	OpGreaterThan: {Name: "OpGreaterThan"},
-	OpAnd:         {Name: "OpAnd"},
-	OpOr:          {Name: "OpOr"},
+	OpEqual:              {Name: "OpEqual"},
+	OpNotEqual:           {Name: "OpNotEqual"},
+	OpGreaterThan:        {Name: "OpGreaterThan"},
+	OpGreaterThanOrEqual: {Name: "OpGreaterThanOrEqual"},
+	OpLessThan:           {Name: "OpLessThan"},
+	OpLessThanOrEqual:    {Name: "OpLessThanOrEqual"},
 
-	OpMinus: {Name: "OpMinus"},
-	OpBang:  {Name: "OpBang"},
+	OpMinus:  {Name: "OpMinus"},
+	OpBang:   {Name: "OpBang"},
+	OpBitNot: {Name: "OpBitNot"},
 
-	OpPop: {Name: "OpPop"},
+	OpPop:  {Name: "OpPop"},
+	OpDup:  {Name: "OpDup"},
+	OpSwap: {Name: "OpSwap"},
 
-	OpGotoNotTruthy: {Name: "OpGotoNotTruthy", OperandWidths: []int{2}},
-	OpGoto:          {Name: "OpGoto", OperandWidths: []int{2}},
+	OpMatchLiteral: {Name: "OpMatchLiteral", OperandWidths: []int{2}},
+	OpMatchArray:   {Name: "OpMatchArray", OperandWidths: []int{2, 1}},
+	OpMatchHashMap: {Name: "OpMatchHashMap", OperandWidths: []int{2}},
+
+	OpGotoNotTruthy:      {Name: "OpGotoNotTruthy", OperandWidths: []int{2}},
+	OpGotoNotTruthyOrPop: {Name: "OpGotoNotTruthyOrPop", OperandWidths: []int{2}},
+	OpGotoTruthyOrPop:    {Name: "OpGotoTruthyOrPop", OperandWidths: []int{2}},
+	OpGoto:               {Name: "OpGoto", OperandWidths: []int{2}},
+	OpGotoTable:          {Name: "OpGotoTable", OperandWidths: []int{2}},
 
 	OpNull: {Name: "OpNull"},
 
@@ -138,15 +207,45 @@ var operations = map[Opcode]*Operation{
 
 	OpGetStdlib: {Name: "OpGetStdlibFunc", OperandWidths: []int{1}},
 
+	OpGetFree: {Name: "OpGetFree", OperandWidths: []int{1}},
+	OpSetFree: {Name: "OpSetFree", OperandWidths: []int{1}},
+	OpClosure: {Name: "OpClosure", OperandWidths: []int{2, 1}},
+
+	OpCurrentClosure: {Name: "OpCurrentClosure"},
+	OpCaptureLocal:   {Name: "OpCaptureLocal", OperandWidths: []int{1}},
+	OpCaptureFree:    {Name: "OpCaptureFree", OperandWidths: []int{1}},
+
 	OpArray:   {Name: "OpArray", OperandWidths: []int{2}},
 	OpHashMap: {Name: "OpHashMap", OperandWidths: []int{2}},
 
-	OpIndex: {Name: "OpIndex"},
+	OpIndex:     {Name: "OpIndex"},
+	OpSetIndex:  {Name: "OpSetIndex"},
+	OpSlice:     {Name: "OpSlice"},
+	OpGetField:  {Name: "OpGetField", OperandWidths: []int{2}},
+	OpSetField:  {Name: "OpSetField", OperandWidths: []int{2}},
+	OpGetMethod: {Name: "OpGetMethod", OperandWidths: []int{2}},
+
+	OpConcat: {Name: "OpConcat", OperandWidths: []int{2}},
+
+	OpIter:     {Name: "OpIter"},
+	OpIterNext: {Name: "OpIterNext", OperandWidths: []int{2, 1}},
+
+	OpLoop:       {Name: "OpLoop"},
+	OpEndLoop:    {Name: "OpEndLoop"},
+	OpUnwindLoop: {Name: "OpUnwindLoop"},
+
+	OpTry:    {Name: "OpTry", OperandWidths: []int{2}},
+	OpEndTry: {Name: "OpEndTry"},
+	OpThrow:  {Name: "OpThrow"},
 
-	OpCall: {Name: "OpCall", OperandWidths: []int{1}},
+	OpCall:          {Name: "OpCall", OperandWidths: []int{1}},
+	OpCallMethod:    {Name: "OpCallMethod", OperandWidths: []int{1}},
+	OpGotoArgPassed: {Name: "OpGotoArgPassed", OperandWidths: []int{2, 1}},
+	OpSpawn:         {Name: "OpSpawn", OperandWidths: []int{1}},
 
 	OpReturnValue: {Name: "OpReturnValue"},
 	OpReturn:      {Name: "OpReturn"},
+	OpYield:       {Name: "OpYield"},
 }
 
 func LookupOperation(opcode byte) (*Operation, error) {
@@ -17,6 +17,7 @@ func TestMakeInstruction(t *testing.T) {
 		{OpConstant, []int{utils.MaxIntForBytes(2)}, []byte{byte(OpConstant), 255, 255}},     // max int instruction
 		{OpGetLocal, []int{utils.MaxIntForBytes(1)}, []byte{byte(OpGetLocal), 255}},          // get local binding instruction
 		{OpAdd, []int{}, []byte{byte(OpAdd)}},                                                // add instruction with no operands
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},               // closure instruction with two operands
 	}
 
 	for _, tt := range tests {
@@ -42,6 +43,7 @@ func TestInstructionString(t *testing.T) {
 		MakeInstruction(OpAdd),
 		MakeInstruction(OpGetLocal, utils.MaxIntForBytes(1)),
 		MakeInstruction(OpConstant, utils.MaxIntForBytes(2)),
+		MakeInstruction(OpClosure, utils.MaxIntForBytes(2), utils.MaxIntForBytes(1)),
 	}
 
 	expected := strings.Join([]string{
@@ -51,6 +53,7 @@ func TestInstructionString(t *testing.T) {
 		"0009 OpAdd",
 		"0010 OpGetLocal 255",
 		"0012 OpConstant 65535",
+		"0015 OpClosure 65535 255",
 	}, "\n") + "\n"
 
 	flattened := Instructions{}
@@ -71,6 +74,7 @@ func TestReadOperands(t *testing.T) {
 	}{
 		{OpConstant, []int{utils.MaxIntForBytes(2)}, 2},
 		{OpGetLocal, []int{utils.MaxIntForBytes(1)}, 1},
+		{OpClosure, []int{utils.MaxIntForBytes(2), utils.MaxIntForBytes(1)}, 3},
 	}
 
 	for _, tt := range tests {
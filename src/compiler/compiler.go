@@ -2,9 +2,11 @@ package compiler
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/vdchnsk/qrk/src/ast"
 	"github.com/vdchnsk/qrk/src/code"
+	"github.com/vdchnsk/qrk/src/module"
 	"github.com/vdchnsk/qrk/src/object"
 	"github.com/vdchnsk/qrk/src/stdlib"
 	"github.com/vdchnsk/qrk/src/token"
@@ -18,6 +20,9 @@ type Compiler struct {
 
 	scopes     []CompilationScope
 	scopeIndex int
+
+	// loads imported files, imports are compiled into the importing program
+	loader *module.Loader[*Module]
 }
 
 // Bytecode is the result of the compilation phase.
@@ -37,6 +42,36 @@ type CompilationScope struct {
 	instructions    code.Instructions
 	lastInstruction EmittedInstruction
 	prevInstruction EmittedInstruction
+
+	// loops enclosing the currently compiled statement, innermost last
+	loops []*LoopContext
+
+	// try statements whose exception handlers are active at the currently compiled statement, innermost last
+	tries []*TryContext
+	// amount of finally blocks being compiled on the way of a rethrown exception, each of them keeps
+	// its exception in a separate slot
+	rethrowsDepth int
+
+	// set for the scope of a generator function, the only place `yield` can be used in
+	generator bool
+
+	// parameters which aren't bound yet while the default value of a parameter is compiled
+	unboundParams []Symbol
+}
+
+// LoopContext tracks jump targets of the loop being compiled,
+// `break` jumps are emitted before the end of the loop is known, so they get patched afterwards
+type LoopContext struct {
+	continueTarget int
+	breakJumps     []int
+}
+
+// TryContext tracks the try statement being compiled, leaving it early with `break`, `continue` or `return`
+// unregisters its exception handler and runs its finally block first
+type TryContext struct {
+	finally *ast.BlockStatement
+	// amount of loops the try statement is nested in
+	loopsCount int
 }
 
 func New() *Compiler {
@@ -119,16 +154,26 @@ func NewWithState(symbolTable *SymbolTable, constants []object.Object) *Compiler
 	return compiler
 }
 
+// SetLoader sets the loader imports are resolved and cached with, it's shared by the compilations of the same program
+func (c *Compiler) SetLoader(loader *module.Loader[*Module]) {
+	c.loader = loader
+}
+
 func (c *Compiler) Compile(node ast.Node) error {
 	switch node := node.(type) {
 	case *ast.Program:
+		c.hoistDeclarations(node.Statements)
+
 		for _, statement := range node.Statements {
-			err := c.Compile(statement)
+			err := c.compileTopLevelStatement(statement)
 			if err != nil {
 				return err
 			}
 		}
 
+	case *ast.ImportStatement, *ast.ExportStatement:
+		return fmt.Errorf("%s is only allowed at the top level of a file", node.TokenLiteral())
+
 	case *ast.ExpressionStatement:
 		if err := c.Compile(node.Value); err != nil {
 			return err
@@ -148,20 +193,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 	case *ast.InfixExpression:
-		operator, left, right, err := inferInfixExpressionComponents(node)
-		if err != nil {
-			return err
+		if node.Operator == token.AND || node.Operator == token.OR {
+			return c.compileLogicalExpression(node)
 		}
 
-		if err := c.Compile(left); err != nil {
+		if err := c.Compile(node.Left); err != nil {
 			return err
 		}
 
-		if err := c.Compile(right); err != nil {
+		if err := c.Compile(node.Right); err != nil {
 			return err
 		}
 
-		if err := c.compileInfixOperator(operator); err != nil {
+		if err := c.compileInfixOperator(node.Operator); err != nil {
 			return err
 		}
 
@@ -171,12 +215,44 @@ func (c *Compiler) Compile(node ast.Node) error {
 		integerIndex := c.addConstant(integer)
 		c.emit(code.OpConstant, integerIndex)
 
+	case *ast.BigIntegerLiteral:
+		integer := &object.BigInteger{Value: node.Value}
+
+		integerIndex := c.addConstant(integer)
+		c.emit(code.OpConstant, integerIndex)
+
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+
+		floatIndex := c.addConstant(float)
+		c.emit(code.OpConstant, floatIndex)
+
 	case *ast.StringLiteral:
 		str := &object.String{Value: node.Value}
 
 		stringIndex := c.addConstant(str)
 		c.emit(code.OpConstant, stringIndex)
 
+	case *ast.InterpolatedString:
+		partsCount := 0
+
+		for i, segment := range node.Segments {
+			if segment != "" {
+				str := &object.String{Value: segment}
+				c.emit(code.OpConstant, c.addConstant(str))
+				partsCount++
+			}
+
+			if i < len(node.Expressions) {
+				if err := c.Compile(node.Expressions[i]); err != nil {
+					return err
+				}
+				partsCount++
+			}
+		}
+
+		c.emit(code.OpConcat, partsCount)
+
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
@@ -212,44 +288,214 @@ func (c *Compiler) Compile(node ast.Node) error {
 		elseBlockEnd := len(c.curInstructions())
 		c.replaceOperand(skipElseIns, elseBlockEnd)
 
+	case *ast.MatchExpression:
+		if err := c.compileMatchExpression(node); err != nil {
+			return err
+		}
+
 	case *ast.BlockStatement:
+		c.hoistDeclarations(node.Statements)
+
 		for _, statement := range node.Statements {
 			if err := c.Compile(statement); err != nil {
 				return err
 			}
 		}
 
+	case *ast.WhileStatement:
+		c.emit(code.OpLoop)
+		loopStart := len(c.curInstructions())
+
+		if err := c.Compile(node.Condition); err != nil {
+			return err
+		}
+
+		exitLoopIns := c.emit(code.OpGotoNotTruthy, -1)
+
+		c.enterLoop(loopStart)
+
+		if err := c.Compile(node.Body); err != nil {
+			return err
+		}
+
+		c.emit(code.OpGoto, loopStart)
+
+		loopEnd := c.emit(code.OpEndLoop)
+		c.replaceOperand(exitLoopIns, loopEnd)
+		c.leaveLoop(loopEnd)
+
+	case *ast.ForInStatement:
+		if err := c.Compile(node.Iterable); err != nil {
+			return err
+		}
+
+		// iterator stays on the stack for the whole loop and is dropped once the loop ends
+		c.emit(code.OpIter)
+		c.emit(code.OpLoop)
+
+		bindingsCount := 1
+		if node.Key != nil {
+			bindingsCount = 2
+		}
+
+		loopStart := c.emit(code.OpIterNext, -1, bindingsCount)
+
+		for _, binding := range []*ast.Identifier{node.Key, node.Value} {
+			if binding == nil {
+				continue
+			}
+			if err := c.checkRedeclaration(binding); err != nil {
+				return err
+			}
+		}
+
+		valueSymbol := c.symbolTable.Define(node.Value.Value)
+		c.storeSymbol(valueSymbol)
+
+		if node.Key != nil {
+			keySymbol := c.symbolTable.Define(node.Key.Value)
+			c.storeSymbol(keySymbol)
+		}
+
+		c.enterLoop(loopStart)
+
+		if err := c.Compile(node.Body); err != nil {
+			return err
+		}
+
+		c.emit(code.OpGoto, loopStart)
+
+		loopEnd := c.emit(code.OpEndLoop)
+		c.emit(code.OpPop)
+		c.replaceOperand(loopStart, loopEnd)
+		c.leaveLoop(loopEnd)
+
+	case *ast.BreakStatement:
+		loop := c.curLoop()
+		if loop == nil {
+			return fmt.Errorf("break used outside of loop")
+		}
+
+		if err := c.exitTries(c.triesInCurLoop()); err != nil {
+			return err
+		}
+
+		// values of the expression the `break` is nested in are dropped
+		c.emit(code.OpUnwindLoop)
+		breakIns := c.emit(code.OpGoto, -1)
+		loop.breakJumps = append(loop.breakJumps, breakIns)
+
+	case *ast.ContinueStatement:
+		loop := c.curLoop()
+		if loop == nil {
+			return fmt.Errorf("continue used outside of loop")
+		}
+
+		if err := c.exitTries(c.triesInCurLoop()); err != nil {
+			return err
+		}
+
+		c.emit(code.OpUnwindLoop)
+		c.emit(code.OpGoto, loop.continueTarget)
+
 	case *ast.LetStatement:
+		if err := c.checkRedeclaration(node.Identifier); err != nil {
+			return err
+		}
+
+		if node.IsConst() {
+			return c.compileConst(node)
+		}
+
 		if err := c.Compile(node.Value); err != nil {
 			return err
 		}
 
 		symbol := c.symbolTable.Define(node.Identifier.Value)
+		c.storeSymbol(symbol)
 
-		if symbol.Scope == GlobalScope {
-			c.emit(code.OpSetGlobal, symbol.Index)
-		} else {
-			c.emit(code.OpSetLocal, symbol.Index)
+	case *ast.LetDestructuringStatement:
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+
+		if err := c.compilePattern(node.Pattern); err != nil {
+			return err
+		}
+
+	case *ast.AssignStatement:
+		symbol, ok := c.symbolTable.Resolve(node.Identifier.Value)
+		if !ok {
+			return fmt.Errorf("undefined variable %s", node.Identifier.Value)
+		}
+
+		if symbol.IsConst {
+			return errorAt(node.Identifier.Token, "cannot assign to constant %s", symbol.Name)
 		}
 
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+
+		if err := c.assignSymbol(symbol); err != nil {
+			return err
+		}
+
+	case *ast.IndexAssignStatement:
+		if err := c.Compile(node.Target.Left); err != nil {
+			return err
+		}
+
+		if err := c.Compile(node.Target.Index); err != nil {
+			return err
+		}
+
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+
+		c.emit(code.OpSetIndex)
+
+	case *ast.MemberAssignStatement:
+		if err := c.compileMemberAssign(node); err != nil {
+			return err
+		}
+
+	case *ast.StructStatement:
+		if err := c.checkRedeclaration(node.Name); err != nil {
+			return err
+		}
+
+		fields := make([]string, len(node.Fields))
+		for i, field := range node.Fields {
+			fields[i] = field.Value
+		}
+
+		structType := &object.StructType{Name: node.Name.Value, Fields: fields}
+		c.emit(code.OpConstant, c.addConstant(structType))
+
+		symbol := c.defineHoistedName(node.Name.Value)
+		c.storeSymbol(symbol)
+
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
 			return fmt.Errorf("undefined variable %s", node.Value)
 		}
 
-		switch symbol.Scope {
-		case StdlibScope:
-			c.emit(code.OpGetStdlib, symbol.Index)
+		if symbol.Scope == ModuleScope {
+			return fmt.Errorf("module %s cannot be used as a value, access its exports with %s.name", node.Value, node.Value)
+		}
 
-		case GlobalScope:
-			c.emit(code.OpGetGlobal, symbol.Index)
+		if slices.Contains(c.curScope().unboundParams, symbol) {
+			return fmt.Errorf("defaults may only refer to earlier parameters: %s", node.Value)
+		}
 
-		case LocalScope:
-			c.emit(code.OpGetLocal, symbol.Index)
+		c.loadSymbol(symbol)
 
-		default:
-			return nil
+	case *ast.MemberExpression:
+		if err := c.compileMemberExpression(node); err != nil {
+			return err
 		}
 
 	case *ast.ArrayLiteral:
@@ -292,18 +538,60 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		c.emit(code.OpIndex)
 
+	case *ast.SliceExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+
+		for _, bound := range []ast.Expression{node.Start, node.End} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+
+			if err := c.Compile(bound); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpSlice)
+
 	case *ast.FuncLiteral:
+		var nameSymbol *Symbol
+		if node.Identifier != nil {
+			if err := c.checkRedeclaration(node.Identifier); err != nil {
+				return err
+			}
+
+			symbol := c.defineHoistedName(node.Identifier.Value)
+			nameSymbol = &symbol
+		}
+
 		c.enterScope()
+		c.curScope().generator = node.IsGenerator
 
+		if node.Identifier != nil {
+			c.symbolTable.DefineFunctionName(node.Identifier.Value)
+		}
+
+		params := []Symbol{}
 		for _, parameter := range node.Parameters {
-			c.symbolTable.Define(parameter.Value)
+			params = append(params, c.symbolTable.Define(parameter.Value))
+		}
+
+		if node.Rest != nil {
+			params = append(params, c.symbolTable.Define(node.Rest.Value))
+		}
+
+		if err := c.compileDefaultArguments(node, params); err != nil {
+			return err
 		}
 
 		if err := c.Compile(node.Body); err != nil {
 			return err
 		}
 
-		if c.lastInstructionIs(code.OpPop) {
+		if endsWithValue(node.Body) && c.lastInstructionIs(code.OpPop) {
 			c.replaceLastPopWithReturn()
 		}
 
@@ -311,25 +599,78 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpReturn)
 		}
 
+		freeSymbols := c.symbolTable.FreeSymbols
 		localsCount := c.symbolTable.definitionsCount
 		instructions := c.leaveScope()
 
+		// cells of the captured variables are pushed in the enclosing scope, so that OpClosure can collect them
+		for _, symbol := range freeSymbols {
+			c.captureSymbol(symbol)
+		}
+
 		compiledFunc := &object.CompiledFunction{
 			Instructions: instructions,
 			LocalsCount:  localsCount,
 			ParamsCount:  len(node.Parameters),
+			Arity:        object.FuncArity(node),
+			Name:         node.Name,
+			TakesSelf:    node.TakesSelf(),
+			IsGenerator:  node.IsGenerator,
 		}
 
-		c.emit(code.OpConstant, c.addConstant(compiledFunc))
+		c.emit(code.OpClosure, c.addConstant(compiledFunc), len(freeSymbols))
+
+		// named function is bound in the enclosing scope, but stays on the stack as the expression value
+		if nameSymbol != nil {
+			c.storeSymbol(*nameSymbol)
+			c.loadSymbol(*nameSymbol)
+		}
 
 	case *ast.ReturnStatement:
 		if err := c.Compile(node.Value); err != nil {
 			return err
 		}
 
+		if err := c.exitTries(len(c.curScope().tries)); err != nil {
+			return err
+		}
+
 		c.emit(code.OpReturnValue)
 
+	case *ast.YieldStatement:
+		if !c.curScope().generator {
+			return fmt.Errorf("yield used outside of generator function")
+		}
+
+		if node.Value != nil {
+			if err := c.Compile(node.Value); err != nil {
+				return err
+			}
+		} else {
+			c.emit(code.OpNull)
+		}
+
+		c.emit(code.OpYield)
+
+	case *ast.ThrowStatement:
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+
+		c.emit(code.OpThrow)
+
+	case *ast.TryStatement:
+		if err := c.compileTryStatement(node); err != nil {
+			return err
+		}
+
 	case *ast.CallExpression:
+		if member, ok := node.Function.(*ast.MemberExpression); ok {
+			if _, isModule := c.resolveModule(member.Object); !isModule {
+				return c.compileMethodCall(member, node.Arguments)
+			}
+		}
+
 		if err := c.Compile(node.Function); err != nil {
 			return err
 		}
@@ -341,92 +682,990 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		c.emit(code.OpCall, len(node.Arguments))
-	}
 
-	return nil
-}
+	case *ast.SpawnExpression:
+		target, arguments := node.Value, []ast.Expression{}
+		if call, ok := node.Value.(*ast.CallExpression); ok {
+			target, arguments = call.Function, call.Arguments
+		}
 
-func (c *Compiler) compileBranch(branch *ast.BlockStatement) error {
-	if err := c.Compile(branch); err != nil {
-		return err
-	}
+		if err := c.Compile(target); err != nil {
+			return err
+		}
 
-	if c.lastInstructionIs(code.OpPop) {
-		c.removeLastInstruction()
+		for _, argument := range arguments {
+			if err := c.Compile(argument); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpSpawn, len(arguments))
 	}
 
 	return nil
 }
 
-func inferInfixExpressionComponents(node *ast.InfixExpression) (string, ast.Expression, ast.Expression, error) {
-	if node.Operator == token.LT {
-		return token.GT, node.Right, node.Left, nil
+func (c *Compiler) loadSymbol(symbol Symbol) {
+	if symbol.IsInlined {
+		c.emit(code.OpConstant, symbol.ConstantIndex)
+		return
 	}
 
-	return node.Operator, node.Left, node.Right, nil
+	switch symbol.Scope {
+	case StdlibScope:
+		c.emit(code.OpGetStdlib, symbol.Index)
+
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, symbol.Index)
+
+	case LocalScope:
+		c.emit(code.OpGetLocal, symbol.Index)
+
+	case FreeScope:
+		c.emit(code.OpGetFree, symbol.Index)
+
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
 }
 
-func (c *Compiler) compilePrefixOperator(operator string) error {
-	switch operator {
-	case token.BANG:
-		c.emit(code.OpBang)
+// captureSymbol pushes the cell of the variable captured by a closure, the closure shares it with the enclosing scope
+func (c *Compiler) captureSymbol(symbol Symbol) {
+	switch symbol.Scope {
+	case LocalScope:
+		c.emit(code.OpCaptureLocal, symbol.Index)
 
-	case token.MINUS:
-		c.emit(code.OpMinus)
+	case FreeScope:
+		c.emit(code.OpCaptureFree, symbol.Index)
 
 	default:
-		return fmt.Errorf("unknown prefix operator %s", operator)
+		c.loadSymbol(symbol)
 	}
-	return nil
 }
 
-func (c *Compiler) compileInfixOperator(operator string) error {
-	switch operator {
-	case token.PLUS:
-		c.emit(code.OpAdd)
+// compilePattern binds the parts of the value on top of the stack to the pattern identifiers, consuming the value
+func (c *Compiler) compilePattern(pattern ast.Pattern) error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if err := c.checkRedeclaration(pattern); err != nil {
+			return err
+		}
 
-	case token.MINUS:
-		c.emit(code.OpSub)
+		symbol := c.symbolTable.Define(pattern.Value)
+		c.storeSymbol(symbol)
 
-	case token.ASTERISK:
-		c.emit(code.OpMul)
+	case *ast.WildcardPattern, *ast.LiteralPattern:
+		c.emit(code.OpPop)
 
-	case token.SLASH:
-		c.emit(code.OpDiv)
+	case *ast.ArrayPattern:
+		for i, element := range pattern.Elements {
+			c.emit(code.OpDup)
+			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(i)}))
+			c.emit(code.OpIndex)
 
-	case token.EQ:
-		c.emit(code.OpEqual)
+			if err := c.compilePattern(element); err != nil {
+				return err
+			}
+		}
 
-	case token.NOT_EQ:
-		c.emit(code.OpNotEqual)
+		if pattern.Rest != nil {
+			c.emit(code.OpDup)
+			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(len(pattern.Elements))}))
+			c.emit(code.OpNull)
+			c.emit(code.OpSlice)
 
-	case token.GT:
-		c.emit(code.OpGreaterThan)
+			if err := c.compilePattern(pattern.Rest); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpPop)
 
-	case token.AND:
-		c.emit(code.OpAnd)
+	case *ast.HashMapPattern:
+		for i, key := range pattern.Keys {
+			keyValue, err := literalValue(key)
+			if err != nil {
+				return err
+			}
 
-	case token.OR:
-		c.emit(code.OpOr)
+			c.emit(code.OpDup)
+			c.emit(code.OpConstant, c.addConstant(keyValue))
+			c.emit(code.OpIndex)
 
-	default:
-		return fmt.Errorf("unknown infix operator %s", operator)
+			if err := c.compilePattern(pattern.Values[i]); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpPop)
 	}
+
 	return nil
 }
 
-func (c *Compiler) addConstant(obj object.Object) int {
-	c.constants = append(c.constants, obj)
-	index := len(c.constants) - 1
+func (c *Compiler) compileMatchExpression(node *ast.MatchExpression) error {
+	if err := c.Compile(node.Subject); err != nil {
+		return err
+	}
 
-	return index
-}
+	// the subject stays on the stack while the arms are tested, and is popped right before the matched arm body
+	endIns := []int{}
 
-func (c *Compiler) emit(opcode code.Opcode, operands ...int) int {
-	instruction := code.MakeInstruction(opcode, operands...)
-	position := c.addInstruction(instruction)
+	for i := 0; i < len(node.Arms); {
+		// a run of literal arms is dispatched at once through a jump table
+		if isTableArm(node.Arms[i]) {
+			run := i
+			for run < len(node.Arms) && isTableArm(node.Arms[run]) {
+				run++
+			}
 
-	c.setLastInstruction(opcode, position)
+			armsEndIns, err := c.compileTableArms(node.Arms[i:run])
+			if err != nil {
+				return err
+			}
+
+			endIns = append(endIns, armsEndIns...)
+			i = run
+			continue
+		}
+
+		armEndIns, err := c.compileMatchArm(node.Arms[i])
+		if err != nil {
+			return err
+		}
+
+		endIns = append(endIns, armEndIns)
+		i++
+	}
+
+	// none of the arms matched
+	c.emit(code.OpPop)
+	c.emit(code.OpNull)
+
+	matchEnd := len(c.curInstructions())
+	for _, ins := range endIns {
+		c.replaceOperand(ins, matchEnd)
+	}
+
+	return nil
+}
+
+func isTableArm(arm *ast.MatchArm) bool {
+	_, isLiteral := arm.Pattern.(*ast.LiteralPattern)
+	return isLiteral && arm.Guard == nil
+}
+
+// compileTableArms compiles unguarded literal arms into a jump table, returning positions of the gotos to the end of match
+func (c *Compiler) compileTableArms(arms []*ast.MatchArm) ([]int, error) {
+	table := &object.GotoTable{Targets: map[object.HashKey]int{}}
+
+	c.emit(code.OpGotoTable, c.addConstant(table))
+	skipArmsIns := c.emit(code.OpGoto, -1)
+
+	endIns := []int{}
+
+	for _, arm := range arms {
+		value, err := literalValue(arm.Pattern.(*ast.LiteralPattern).Value)
+		if err != nil {
+			return nil, err
+		}
+
+		// the first of the arms with the same literal wins, `1` and `1.0` are the same literal for matching
+		key, _ := object.PatternKey(value)
+		if _, ok := table.Targets[key]; !ok {
+			table.Targets[key] = len(c.curInstructions())
+		}
+
+		c.emit(code.OpPop)
+
+		if err := c.compileBranch(arm.Body); err != nil {
+			return nil, err
+		}
+
+		endIns = append(endIns, c.emit(code.OpGoto, -1))
+	}
+
+	c.replaceOperand(skipArmsIns, len(c.curInstructions()))
+
+	return endIns, nil
+}
+
+// compileMatchArm returns position of the goto to the end of match
+func (c *Compiler) compileMatchArm(arm *ast.MatchArm) (int, error) {
+	nextArmIns := []int{}
+
+	if !isIrrefutable(arm.Pattern) {
+		if err := c.compileMatchTest(arm.Pattern); err != nil {
+			return 0, err
+		}
+
+		nextArmIns = append(nextArmIns, c.emit(code.OpGotoNotTruthy, -1))
+	}
+
+	switch arm.Pattern.(type) {
+	case *ast.WildcardPattern, *ast.LiteralPattern:
+	default:
+		c.emit(code.OpDup)
+
+		if err := c.compilePattern(arm.Pattern); err != nil {
+			return 0, err
+		}
+	}
+
+	if arm.Guard != nil {
+		if err := c.Compile(arm.Guard); err != nil {
+			return 0, err
+		}
+
+		nextArmIns = append(nextArmIns, c.emit(code.OpGotoNotTruthy, -1))
+	}
+
+	c.emit(code.OpPop)
+
+	if err := c.compileBranch(arm.Body); err != nil {
+		return 0, err
+	}
+
+	endIns := c.emit(code.OpGoto, -1)
+
+	nextArm := len(c.curInstructions())
+	for _, ins := range nextArmIns {
+		c.replaceOperand(ins, nextArm)
+	}
+
+	return endIns, nil
+}
+
+func isIrrefutable(pattern ast.Pattern) bool {
+	switch pattern.(type) {
+	case *ast.Identifier, *ast.WildcardPattern:
+		return true
+	default:
+		return false
+	}
+}
+
+// compileMatchTest pushes whether the value on top of the stack matches the pattern, keeping the value
+func (c *Compiler) compileMatchTest(pattern ast.Pattern) error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier, *ast.WildcardPattern:
+		c.emit(code.OpTrue)
+
+	case *ast.LiteralPattern:
+		value, err := literalValue(pattern.Value)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpMatchLiteral, c.addConstant(value))
+
+	case *ast.ArrayPattern:
+		hasRest := 0
+		if pattern.Rest != nil {
+			hasRest = 1
+		}
+
+		c.emit(code.OpMatchArray, len(pattern.Elements), hasRest)
+
+		indexes := make([]object.Object, len(pattern.Elements))
+		for i := range pattern.Elements {
+			indexes[i] = &object.Integer{Value: int64(i)}
+		}
+
+		return c.compileElementsMatchTest(indexes, pattern.Elements)
+
+	case *ast.HashMapPattern:
+		keys := make([]object.Object, len(pattern.Keys))
+		for i, key := range pattern.Keys {
+			keyValue, err := literalValue(key)
+			if err != nil {
+				return err
+			}
+			keys[i] = keyValue
+		}
+
+		c.emit(code.OpMatchHashMap, c.addConstant(&object.Array{Elements: keys}))
+
+		return c.compileElementsMatchTest(keys, pattern.Values)
+	}
+
+	return nil
+}
+
+// compileElementsMatchTest tests the elements of the collection on top of the stack one by one,
+// as long as the result of the previous test, which is on top of the stack, is truthy
+func (c *Compiler) compileElementsMatchTest(indexes []object.Object, patterns []ast.Pattern) error {
+	failedIns := []int{}
+
+	for i, pattern := range patterns {
+		if isIrrefutable(pattern) {
+			continue
+		}
+
+		failedIns = append(failedIns, c.emit(code.OpGotoNotTruthyOrPop, -1))
+
+		c.emit(code.OpDup)
+		c.emit(code.OpConstant, c.addConstant(indexes[i]))
+		c.emit(code.OpIndex)
+
+		if err := c.compileMatchTest(pattern); err != nil {
+			return err
+		}
+
+		// drop the element, keeping the result of its test
+		c.emit(code.OpSwap)
+		c.emit(code.OpPop)
+	}
+
+	testsEnd := len(c.curInstructions())
+	for _, ins := range failedIns {
+		c.replaceOperand(ins, testsEnd)
+	}
+
+	return nil
+}
+
+// compileConst inlines consts whose value is a number or a string literal, or another inlined const,
+// the rest of the consts are stored like let bindings
+func (c *Compiler) compileConst(node *ast.LetStatement) error {
+	name := node.Identifier.Value
+
+	if value, ok := inlinableValue(node.Value); ok {
+		c.symbolTable.DefineInlinedConst(name, c.addConstant(value))
+		return nil
+	}
+
+	if identifier, ok := node.Value.(*ast.Identifier); ok {
+		if symbol, ok := c.symbolTable.Resolve(identifier.Value); ok && symbol.IsInlined {
+			c.symbolTable.DefineInlinedConst(name, symbol.ConstantIndex)
+			return nil
+		}
+	}
+
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+
+	symbol := c.symbolTable.DefineConst(name)
+	c.storeSymbol(symbol)
+
+	return nil
+}
+
+// inlinableValue is the value of the literal known at compile time, booleans aren't inlined,
+// since the VM compares them by identity with its own true and false
+func inlinableValue(expression ast.Expression) (object.Object, bool) {
+	switch expression := expression.(type) {
+	case *ast.IntegerLiteral, *ast.BigIntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral:
+		value, err := literalValue(expression)
+		return value, err == nil
+
+	case *ast.PrefixExpression:
+		if expression.Operator != token.MINUS {
+			return nil, false
+		}
+
+		if _, ok := inlinableValue(expression.Right); !ok {
+			return nil, false
+		}
+
+		value, err := literalValue(expression)
+		return value, err == nil
+	}
+
+	return nil, false
+}
+
+// checkRedeclaration rejects bindings which would shadow a const declared in the same scope
+func (c *Compiler) checkRedeclaration(identifier *ast.Identifier) error {
+	if c.symbolTable.DefinesConst(identifier.Value) {
+		return errorAt(identifier.Token, "cannot redeclare constant %s", identifier.Value)
+	}
+
+	return nil
+}
+
+// errorAt reports the compilation error at the position of the token in the source
+func errorAt(tok token.Token, format string, args ...any) error {
+	return fmt.Errorf("%s at line %d, column %d", fmt.Sprintf(format, args...), tok.Line, tok.Column)
+}
+
+// literalValue converts the literal of a pattern into an object
+func literalValue(literal ast.Expression) (object.Object, error) {
+	switch literal := literal.(type) {
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: literal.Value}, nil
+	case *ast.BigIntegerLiteral:
+		return object.NewBigInteger(literal.Value), nil
+	case *ast.FloatLiteral:
+		return &object.Float{Value: literal.Value}, nil
+	case *ast.StringLiteral:
+		return &object.String{Value: literal.Value}, nil
+	case *ast.Boolean:
+		return &object.Boolean{Value: literal.Value}, nil
+	case *ast.PrefixExpression:
+		value, err := literalValue(literal.Right)
+		if err != nil {
+			return nil, err
+		}
+
+		switch value := value.(type) {
+		case *object.Integer, *object.BigInteger:
+			return object.NegateInteger(value), nil
+		case *object.Float:
+			return &object.Float{Value: -value.Value}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported literal in pattern: %s", literal)
+}
+
+func (c *Compiler) storeSymbol(symbol Symbol) {
+	if symbol.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+}
+
+func (c *Compiler) assignSymbol(symbol Symbol) error {
+	switch symbol.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, symbol.Index)
+
+	case LocalScope:
+		c.emit(code.OpSetLocal, symbol.Index)
+
+	case FreeScope:
+		c.emit(code.OpSetFree, symbol.Index)
+
+	case StdlibScope:
+		return fmt.Errorf("cannot assign to stdlib function %s", symbol.Name)
+
+	case FunctionScope:
+		return fmt.Errorf("cannot assign to function %s inside of its own body", symbol.Name)
+
+	case ModuleScope:
+		return fmt.Errorf("cannot assign to module %s", symbol.Name)
+	}
+
+	return nil
+}
+
+// defineHoistedName reuses a binding that was already declared in the current scope,
+// e.g. by hoisting, so all references to the function or struct point to the same slot
+func (c *Compiler) defineHoistedName(name string) Symbol {
+	symbol, ok := c.symbolTable.store[name]
+	if ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		return symbol
+	}
+
+	return c.symbolTable.Define(name)
+}
+
+// compileDefaultArguments assigns the default values to the parameters whose arguments weren't passed,
+// the values are evaluated on every call, after the preceding parameters are bound,
+// so referring to the parameter itself or to the following ones is an error
+func (c *Compiler) compileDefaultArguments(node *ast.FuncLiteral, params []Symbol) error {
+	defer func() { c.curScope().unboundParams = nil }()
+
+	for i := range node.Parameters {
+		defaultValue := node.Default(i)
+		if defaultValue == nil {
+			continue
+		}
+
+		skipDefaultIns := c.emit(code.OpGotoArgPassed, -1, i)
+
+		c.curScope().unboundParams = params[i:]
+		if err := c.Compile(defaultValue); err != nil {
+			return err
+		}
+		c.emit(code.OpSetLocal, i)
+
+		c.replaceOperand(skipDefaultIns, len(c.curInstructions()))
+	}
+
+	return nil
+}
+
+// hoistDeclarations declares the named functions and structs of a file, function body or block before compiling
+// any of its statements, so they are able to refer to each other regardless of their declaration order
+func (c *Compiler) hoistDeclarations(statements []ast.Statement) {
+	for _, statement := range statements {
+		if export, ok := statement.(*ast.ExportStatement); ok {
+			statement = export.Statement
+		}
+
+		switch statement := statement.(type) {
+		case *ast.StructStatement:
+			c.defineHoistedName(statement.Name.Value)
+
+		case *ast.ExpressionStatement:
+			funcLiteral, ok := statement.Value.(*ast.FuncLiteral)
+			if !ok || funcLiteral.Identifier == nil {
+				continue
+			}
+
+			c.defineHoistedName(funcLiteral.Identifier.Value)
+		}
+	}
+}
+
+// compileTopLevelStatement compiles a statement of a file, where imports and exports are allowed
+func (c *Compiler) compileTopLevelStatement(statement ast.Statement) error {
+	switch statement := statement.(type) {
+	case *ast.ImportStatement:
+		return c.compileImportStatement(statement)
+
+	case *ast.ExportStatement:
+		return c.Compile(statement.Statement)
+
+	default:
+		return c.Compile(statement)
+	}
+}
+
+func (c *Compiler) compileImportStatement(node *ast.ImportStatement) error {
+	if c.loader == nil {
+		c.loader = module.NewLoader[*Module]("")
+	}
+
+	imported, err := c.loader.Load(node.Path.Value, c.compileModule)
+	if err != nil {
+		return err
+	}
+
+	c.symbolTable.DefineModule(node.Alias.Value, imported)
+
+	return nil
+}
+
+// compileModule compiles the imported file in place of its first import, so the module's top-level code runs once,
+// the module gets its own global scope, so its names don't clash with the importer's ones
+func (c *Compiler) compileModule(path string, program *ast.Program) (*Module, error) {
+	importerSymbolTable := c.symbolTable
+	defer func() { c.symbolTable = importerSymbolTable }()
+
+	moduleSymbolTable := NewModuleSymbolTable(importerSymbolTable)
+	for i, f := range stdlib.Funcs {
+		moduleSymbolTable.DefineStdlibFunc(i, f.Name)
+	}
+
+	c.symbolTable = moduleSymbolTable
+	if err := c.Compile(program); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// globals defined by the importer from now on must not reuse the module's slots
+	importerSymbolTable.definitionsCount = moduleSymbolTable.definitionsCount
+
+	exports := make(map[string]Symbol)
+	for _, name := range module.ExportedNames(program) {
+		symbol, _ := moduleSymbolTable.Resolve(name)
+		exports[name] = symbol
+	}
+
+	return &Module{Path: path, Exports: exports}, nil
+}
+
+// compileMemberExpression resolves exports of modules at compile time, members of other values are looked up at runtime
+func (c *Compiler) compileMemberExpression(node *ast.MemberExpression) error {
+	if symbol, ok := c.resolveModule(node.Object); ok {
+		export, ok := symbol.Module.Exports[node.Member.Value]
+		if !ok {
+			return fmt.Errorf("module %s has no export %s", node.Object, node.Member.Value)
+		}
+
+		c.loadSymbol(export)
+
+		return nil
+	}
+
+	if err := c.Compile(node.Object); err != nil {
+		return err
+	}
+
+	c.emit(code.OpGetField, c.addConstant(&object.String{Value: node.Member.Value}))
+
+	return nil
+}
+
+// compileMethodCall keeps the receiver on the stack between the method and the arguments,
+// so that OpCallMethod can pass it to the methods taking `self`
+func (c *Compiler) compileMethodCall(member *ast.MemberExpression, arguments []ast.Expression) error {
+	if err := c.Compile(member.Object); err != nil {
+		return err
+	}
+
+	c.emit(code.OpGetMethod, c.addConstant(&object.String{Value: member.Member.Value}))
+
+	for _, argument := range arguments {
+		if err := c.Compile(argument); err != nil {
+			return err
+		}
+	}
+
+	c.emit(code.OpCallMethod, len(arguments))
+
+	return nil
+}
+
+func (c *Compiler) compileMemberAssign(node *ast.MemberAssignStatement) error {
+	if _, ok := c.resolveModule(node.Target.Object); ok {
+		return fmt.Errorf("cannot assign to export %s of module %s", node.Target.Member.Value, node.Target.Object)
+	}
+
+	if err := c.Compile(node.Target.Object); err != nil {
+		return err
+	}
+
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+
+	c.emit(code.OpSetField, c.addConstant(&object.String{Value: node.Target.Member.Value}))
+
+	return nil
+}
+
+// resolveModule resolves the expression to the symbol of an imported module, if it names one
+func (c *Compiler) resolveModule(node ast.Expression) (Symbol, bool) {
+	identifier, ok := node.(*ast.Identifier)
+	if !ok {
+		return Symbol{}, false
+	}
+
+	symbol, ok := c.symbolTable.Resolve(identifier.Value)
+
+	return symbol, ok && symbol.Scope == ModuleScope
+}
+
+func (c *Compiler) compileBranch(branch *ast.BlockStatement) error {
+	if err := c.Compile(branch); err != nil {
+		return err
+	}
+
+	if endsWithValue(branch) && c.lastInstructionIs(code.OpPop) {
+		c.removeLastInstruction()
+		return nil
+	}
+
+	// branch ended with a statement that produces no value, but `if` is an expression and must leave one
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+// endsWithValue tells whether the trailing OpPop of the block drops the value of an expression statement
+// or of a try statement, other statements (e.g. for-in loops) may emit OpPop that doesn't produce the value of the block
+func endsWithValue(block *ast.BlockStatement) bool {
+	if len(block.Statements) == 0 {
+		return false
+	}
+
+	switch block.Statements[len(block.Statements)-1].(type) {
+	case *ast.ExpressionStatement, *ast.TryStatement:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Compiler) curLoop() *LoopContext {
+	loops := c.curScope().loops
+	if len(loops) == 0 {
+		return nil
+	}
+
+	return loops[len(loops)-1]
+}
+
+func (c *Compiler) enterLoop(continueTarget int) {
+	loop := &LoopContext{continueTarget: continueTarget}
+	c.curScope().loops = append(c.curScope().loops, loop)
+}
+
+// leaveLoop points all `break` jumps of the innermost loop to its end
+func (c *Compiler) leaveLoop(loopEnd int) {
+	loops := c.curScope().loops
+	loop := loops[len(loops)-1]
+
+	for _, breakIns := range loop.breakJumps {
+		c.replaceOperand(breakIns, loopEnd)
+	}
+
+	c.curScope().loops = loops[:len(loops)-1]
+}
+
+// compileTryStatement compiles the try block guarded by an exception handler, the handler runs the catch block
+// and finally is compiled into every path leaving the statement. The statement produces the value of the try block,
+// or of the catch block once it has caught an exception
+func (c *Compiler) compileTryStatement(node *ast.TryStatement) error {
+	handlerIns := c.emit(code.OpTry, -1)
+
+	c.enterTry(node.Finally)
+	if err := c.compileBranch(node.Block); err != nil {
+		return err
+	}
+	c.leaveTry()
+
+	c.emit(code.OpEndTry)
+	if err := c.compileFinally(node.Finally); err != nil {
+		return err
+	}
+
+	endIns := []int{c.emit(code.OpGoto, -1)}
+
+	// the handler starts with the caught value on top of the stack
+	c.replaceOperand(handlerIns, len(c.curInstructions()))
+
+	if node.Catch != nil {
+		if node.CatchParam != nil {
+			if err := c.checkRedeclaration(node.CatchParam); err != nil {
+				return err
+			}
+
+			symbol := c.symbolTable.Define(node.CatchParam.Value)
+			c.storeSymbol(symbol)
+		} else {
+			c.emit(code.OpPop)
+		}
+
+		if node.Finally == nil {
+			if err := c.compileBranch(node.Catch); err != nil {
+				return err
+			}
+
+			endIns = append(endIns, c.emit(code.OpGoto, -1))
+		} else {
+			// exceptions thrown by the catch block have to run finally before leaving the statement
+			rethrowHandlerIns := c.emit(code.OpTry, -1)
+
+			c.enterTry(node.Finally)
+			if err := c.compileBranch(node.Catch); err != nil {
+				return err
+			}
+			c.leaveTry()
+
+			c.emit(code.OpEndTry)
+			if err := c.compileFinally(node.Finally); err != nil {
+				return err
+			}
+
+			endIns = append(endIns, c.emit(code.OpGoto, -1))
+
+			c.replaceOperand(rethrowHandlerIns, len(c.curInstructions()))
+			if err := c.compileRethrow(node.Finally); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := c.compileRethrow(node.Finally); err != nil {
+			return err
+		}
+	}
+
+	// like an expression statement, the value is dropped unless it's the value of the enclosing block
+	statementEnd := c.emit(code.OpPop)
+	for _, ins := range endIns {
+		c.replaceOperand(ins, statementEnd)
+	}
+
+	return nil
+}
+
+// compileRethrow runs finally for the exception on top of the stack, and throws the exception further
+func (c *Compiler) compileRethrow(finally *ast.BlockStatement) error {
+	// the exception is kept aside, so that finally runs on the same stack as it would without it
+	symbol := c.exceptionSymbol()
+	c.storeSymbol(symbol)
+
+	c.curScope().rethrowsDepth++
+	if err := c.compileFinally(finally); err != nil {
+		return err
+	}
+	c.curScope().rethrowsDepth--
+
+	c.loadSymbol(symbol)
+	c.emit(code.OpThrow)
+
+	return nil
+}
+
+// exceptionSymbol is the slot keeping aside the exception rethrown at the current depth, slots are defined
+// once per scope and reused by the following try statements
+func (c *Compiler) exceptionSymbol() Symbol {
+	name := fmt.Sprintf("$exception%d", c.curScope().rethrowsDepth)
+	if symbol, ok := c.symbolTable.store[name]; ok {
+		return symbol
+	}
+
+	return c.symbolTable.Define(name)
+}
+
+func (c *Compiler) compileFinally(finally *ast.BlockStatement) error {
+	if finally == nil {
+		return nil
+	}
+
+	return c.Compile(finally)
+}
+
+func (c *Compiler) enterTry(finally *ast.BlockStatement) {
+	try := &TryContext{finally: finally, loopsCount: len(c.curScope().loops)}
+	c.curScope().tries = append(c.curScope().tries, try)
+}
+
+func (c *Compiler) leaveTry() {
+	tries := c.curScope().tries
+	c.curScope().tries = tries[:len(tries)-1]
+}
+
+// triesInCurLoop counts the innermost try statements which are inside of the innermost loop
+func (c *Compiler) triesInCurLoop() int {
+	tries := c.curScope().tries
+	loopsCount := len(c.curScope().loops)
+
+	count := 0
+	for i := len(tries) - 1; i >= 0 && tries[i].loopsCount == loopsCount; i-- {
+		count++
+	}
+
+	return count
+}
+
+// exitTries unregisters exception handlers of the innermost try statements and runs their finally blocks,
+// each finally is compiled outside of its own try statement, so it isn't guarded by its handler
+func (c *Compiler) exitTries(count int) error {
+	tries := c.curScope().tries
+	defer func() { c.curScope().tries = tries }()
+
+	for i := len(tries) - 1; i >= len(tries)-count; i-- {
+		c.emit(code.OpEndTry)
+
+		c.curScope().tries = tries[:i]
+		if err := c.compileFinally(tries[i].finally); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compileLogicalExpression compiles `&&` and `||` so that the right operand is evaluated only when the left one
+// doesn't decide the result already. The result is the value of the last evaluated operand
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+
+	gotoOpcode := code.OpGotoNotTruthyOrPop
+	if node.Operator == token.OR {
+		gotoOpcode = code.OpGotoTruthyOrPop
+	}
+
+	gotoPos := c.emit(gotoOpcode, -1)
+
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+
+	c.replaceOperand(gotoPos, len(c.curInstructions()))
+
+	return nil
+}
+
+func (c *Compiler) compilePrefixOperator(operator string) error {
+	switch operator {
+	case token.BANG:
+		c.emit(code.OpBang)
+
+	case token.MINUS:
+		c.emit(code.OpMinus)
+
+	case token.TILDE:
+		c.emit(code.OpBitNot)
+
+	default:
+		return fmt.Errorf("unknown prefix operator %s", operator)
+	}
+	return nil
+}
+
+func (c *Compiler) compileInfixOperator(operator string) error {
+	switch operator {
+	case token.PLUS:
+		c.emit(code.OpAdd)
+
+	case token.MINUS:
+		c.emit(code.OpSub)
+
+	case token.ASTERISK:
+		c.emit(code.OpMul)
+
+	case token.SLASH:
+		c.emit(code.OpDiv)
+
+	case token.PERCENT:
+		c.emit(code.OpMod)
+
+	case token.POWER:
+		c.emit(code.OpPow)
+
+	case token.BIT_AND:
+		c.emit(code.OpBitAnd)
+
+	case token.BIT_OR:
+		c.emit(code.OpBitOr)
+
+	case token.BIT_XOR:
+		c.emit(code.OpBitXor)
+
+	case token.SHIFT_LEFT:
+		c.emit(code.OpShiftLeft)
+
+	case token.SHIFT_RIGHT:
+		c.emit(code.OpShiftRight)
+
+	case token.EQ:
+		c.emit(code.OpEqual)
+
+	case token.NOT_EQ:
+		c.emit(code.OpNotEqual)
+
+	case token.GT:
+		c.emit(code.OpGreaterThan)
+
+	case token.GT_EQ:
+		c.emit(code.OpGreaterThanOrEqual)
+
+	case token.LT:
+		c.emit(code.OpLessThan)
+
+	case token.LT_EQ:
+		c.emit(code.OpLessThanOrEqual)
+
+	default:
+		return fmt.Errorf("unknown infix operator %s", operator)
+	}
+	return nil
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	index := len(c.constants) - 1
+
+	return index
+}
+
+func (c *Compiler) emit(opcode code.Opcode, operands ...int) int {
+	instruction := code.MakeInstruction(opcode, operands...)
+	position := c.addInstruction(instruction)
+
+	c.setLastInstruction(opcode, position)
 
 	return position
 }
@@ -443,10 +1682,19 @@ func (c *Compiler) replaceInstruction(position int, newInstruction []byte) {
 }
 
 // we can only replace operands of the same type, with the same non-variable length
+// only the first operand is replaced, the rest of them are kept as is
 func (c *Compiler) replaceOperand(replaceAt int, operand int) {
 	opcode := code.Opcode(c.curInstructions()[replaceAt])
 
-	newInstruction := code.MakeInstruction(opcode, operand) // 15, 1 -> 15, 7
+	operation, err := code.LookupOperation(byte(opcode))
+	if err != nil {
+		return
+	}
+
+	operands, _ := code.ReadOperands(operation, c.curInstructions()[replaceAt+1:])
+	operands[0] = operand
+
+	newInstruction := code.MakeInstruction(opcode, operands...) // 15, 1 -> 15, 7
 
 	c.replaceInstruction(replaceAt, newInstruction)
 }
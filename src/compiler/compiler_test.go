@@ -2,11 +2,14 @@ package compiler
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/vdchnsk/qrk/src/ast"
 	"github.com/vdchnsk/qrk/src/code"
 	"github.com/vdchnsk/qrk/src/lexer"
+	"github.com/vdchnsk/qrk/src/module"
 	"github.com/vdchnsk/qrk/src/object"
 	"github.com/vdchnsk/qrk/src/parser"
 )
@@ -63,6 +66,33 @@ func testConstants(expected []any, actual []object.Object) error {
 				return fmt.Errorf("constant %d - testIntegerObject failed: %s", index, err)
 			}
 
+		case map[int]int:
+			table, ok := actual[index].(*object.GotoTable)
+			if !ok {
+				return fmt.Errorf("object is not GotoTable. got=%T (%+v)", actual[index], actual[index])
+			}
+
+			if len(table.Targets) != len(constant) {
+				return fmt.Errorf("constant %d - wrong number of targets. got=%d, expected=%d", index, len(table.Targets), len(constant))
+			}
+
+			for literal, expectedTarget := range constant {
+				key := (&object.Integer{Value: int64(literal)}).HashKey()
+				if table.Targets[key] != expectedTarget {
+					return fmt.Errorf("constant %d - wrong target for %d. got=%d, expected=%d", index, literal, table.Targets[key], expectedTarget)
+				}
+			}
+
+		case *object.StructType:
+			structType, ok := actual[index].(*object.StructType)
+			if !ok {
+				return fmt.Errorf("object is not StructType. got=%T (%+v)", actual[index], actual[index])
+			}
+
+			if structType.Inspect() != constant.Inspect() {
+				return fmt.Errorf("constant %d - wrong struct type. got=%s, expected=%s", index, structType.Inspect(), constant.Inspect())
+			}
+
 		case []code.Instructions:
 			fn, ok := actual[index].(*object.CompiledFunction)
 			if !ok {
@@ -178,6 +208,45 @@ func TestIntegerArithmetic(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+	operators := map[string]code.Opcode{
+		"%":  code.OpMod,
+		"**": code.OpPow,
+		"&":  code.OpBitAnd,
+		"|":  code.OpBitOr,
+		"^":  code.OpBitXor,
+		"<<": code.OpShiftLeft,
+		">>": code.OpShiftRight,
+	}
+
+	tests := []compilerTestCase{
+		{
+			input:             "~1",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpBitNot),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	for operator, opcode := range operators {
+		tests = append(tests, compilerTestCase{
+			input:             fmt.Sprintf("1 %s 2", operator),
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(opcode),
+				code.MakeInstruction(code.OpPop),
+			},
+		})
+	}
+
+	runCompilerTests(t, tests)
+}
+
 func TestBooleanExpressions(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -208,11 +277,31 @@ func TestBooleanExpressions(t *testing.T) {
 		},
 		{
 			input:             "1 < 2",
-			expectedConstants: []interface{}{2, 1},
+			expectedConstants: []interface{}{1, 2},
 			expectedInstructions: []code.Instructions{
 				code.MakeInstruction(code.OpConstant, 0),
 				code.MakeInstruction(code.OpConstant, 1),
-				code.MakeInstruction(code.OpGreaterThan),
+				code.MakeInstruction(code.OpLessThan),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             "1 <= 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpLessThanOrEqual),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             "1 >= 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpGreaterThanOrEqual),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
@@ -250,9 +339,13 @@ func TestBooleanExpressions(t *testing.T) {
 			input:             "true && true",
 			expectedConstants: []interface{}{},
 			expectedInstructions: []code.Instructions{
+				// 0000
 				code.MakeInstruction(code.OpTrue),
+				// 0001
+				code.MakeInstruction(code.OpGotoNotTruthyOrPop, 5),
+				// 0004
 				code.MakeInstruction(code.OpTrue),
-				code.MakeInstruction(code.OpAnd),
+				// 0005
 				code.MakeInstruction(code.OpPop),
 			},
 		},
@@ -260,9 +353,13 @@ func TestBooleanExpressions(t *testing.T) {
 			input:             "true || false",
 			expectedConstants: []interface{}{},
 			expectedInstructions: []code.Instructions{
+				// 0000
 				code.MakeInstruction(code.OpTrue),
+				// 0001
+				code.MakeInstruction(code.OpGotoTruthyOrPop, 5),
+				// 0004
 				code.MakeInstruction(code.OpFalse),
-				code.MakeInstruction(code.OpOr),
+				// 0005
 				code.MakeInstruction(code.OpPop),
 			},
 		},
@@ -270,9 +367,13 @@ func TestBooleanExpressions(t *testing.T) {
 			input:             "false || false",
 			expectedConstants: []interface{}{},
 			expectedInstructions: []code.Instructions{
+				// 0000
 				code.MakeInstruction(code.OpFalse),
+				// 0001
+				code.MakeInstruction(code.OpGotoTruthyOrPop, 5),
+				// 0004
 				code.MakeInstruction(code.OpFalse),
-				code.MakeInstruction(code.OpOr),
+				// 0005
 				code.MakeInstruction(code.OpPop),
 			},
 		},
@@ -451,6 +552,36 @@ func TestStringExpression(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `"a ${1} b ${2}"`,
+			expectedConstants: []interface{}{"a ", 1, " b ", 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpConstant, 3),
+				code.MakeInstruction(code.OpConcat, 4),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             `"${1 + 2}"`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpAdd),
+				code.MakeInstruction(code.OpConcat, 1),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
 func TestArrayExpression(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -596,69 +727,77 @@ func TestIndexExpression(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
-func TestFunctions(t *testing.T) {
+func TestSliceExpressions(t *testing.T) {
 	tests := []compilerTestCase{
 		{
-			input: `fn() { return 5 + 10 }`,
-			expectedConstants: []interface{}{
-				5,
-				10,
-				[]code.Instructions{
-					code.MakeInstruction(code.OpConstant, 0),
-					code.MakeInstruction(code.OpConstant, 1),
-					code.MakeInstruction(code.OpAdd),
-					code.MakeInstruction(code.OpReturnValue),
-				},
-			},
+			input:             `[1, 2, 3][1:2]`,
+			expectedConstants: []interface{}{1, 2, 3, 1, 2},
 			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
 				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpArray, 3),
+				code.MakeInstruction(code.OpConstant, 3),
+				code.MakeInstruction(code.OpConstant, 4),
+				code.MakeInstruction(code.OpSlice),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
 		{
-			input: `fn() { 5 + 10 }`,
-			expectedConstants: []interface{}{
-				5,
-				10,
-				[]code.Instructions{
-					code.MakeInstruction(code.OpConstant, 0),
-					code.MakeInstruction(code.OpConstant, 1),
-					code.MakeInstruction(code.OpAdd),
-					code.MakeInstruction(code.OpReturnValue),
-				},
-			},
+			input:             `"qrk"[:-1]`,
+			expectedConstants: []interface{}{"qrk", 1},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpNull),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpMinus),
+				code.MakeInstruction(code.OpSlice),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
 		{
-			input: `fn() { 5; 10 }`,
-			expectedConstants: []interface{}{
-				5,
-				10,
-				[]code.Instructions{
-					code.MakeInstruction(code.OpConstant, 0),
-					code.MakeInstruction(code.OpPop),
-					code.MakeInstruction(code.OpConstant, 1),
-					code.MakeInstruction(code.OpReturnValue),
-				},
-			},
+			input:             `"qrk"[1:]`,
+			expectedConstants: []interface{}{"qrk", 1},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpNull),
+				code.MakeInstruction(code.OpSlice),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestIndexAssignStatements(t *testing.T) {
+	tests := []compilerTestCase{
 		{
-			input: `fn() { }`,
-			expectedConstants: []interface{}{
-				[]code.Instructions{
-					code.MakeInstruction(code.OpReturn),
-				},
+			input:             `let a = [1]; a[0] = 2;`,
+			expectedConstants: []interface{}{1, 0, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpArray, 1),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpSetIndex),
 			},
+		},
+		{
+			input:             `let m = {}; m["a"] = 1 + 1;`,
+			expectedConstants: []interface{}{"a", 1, 1},
 			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpHashMap, 0),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpGetGlobal, 0),
 				code.MakeInstruction(code.OpConstant, 0),
-				code.MakeInstruction(code.OpPop),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpAdd),
+				code.MakeInstruction(code.OpSetIndex),
 			},
 		},
 	}
@@ -666,196 +805,1481 @@ func TestFunctions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
-func TestCompilerScopes(t *testing.T) {
-	compiler := New()
-	if compiler.scopeIndex != 0 {
-		t.Fatalf("initial scope index is not 0. got=%d", compiler.scopeIndex)
-	}
-
-	globalScopeTestIns := code.OpMul
-
-	t.Run("emit test instructions in main scope", func(t *testing.T) {
-		compiler.emit(globalScopeTestIns)
-	})
-
-	innerScopeTestIns := code.OpSub
-
-	t.Run("emit test instructions in the inner scope", func(t *testing.T) {
-		compiler.enterScope()
-		if compiler.scopeIndex != 1 {
-			t.Fatalf("scope index is not 1 after entering scope. got=%d", compiler.scopeIndex)
-		}
-
-		compiler.emit(innerScopeTestIns)
-
-		if len(compiler.curInstructions()) != 1 {
-			t.Fatalf("wrong number of instructions in scope. got=%d", len(compiler.curInstructions()))
-		}
-
-		lastIns := compiler.curScope().lastInstruction
-		if lastIns.Opcode != innerScopeTestIns {
-			t.Fatalf("wrong last instruction in scope. got=%d", lastIns.Opcode)
-		}
-	})
-
-	t.Run("leave inner scope and emit test instructions in the main scope & expect inner scope instructions to be invisible", func(t *testing.T) {
-		compiler.leaveScope()
-		if compiler.scopeIndex != 0 {
-			t.Fatalf("scope index is not 0 after leaving scope. got=%d", compiler.scopeIndex)
-		}
-
-		compiler.emit(code.OpAdd)
-
-		if len(compiler.curInstructions()) != 2 {
-			t.Fatalf("wrong number of instructions in scope. got=%d", len(compiler.curInstructions()))
-		}
-
-		lastIns := compiler.curScope().lastInstruction
-		if lastIns.Opcode != code.OpAdd {
-			t.Fatalf("wrong last instruction in scope. got=%d", lastIns.Opcode)
-		}
-
-		prevIns := compiler.curScope().prevInstruction
-		if prevIns.Opcode != globalScopeTestIns {
-			t.Fatalf("wrong previous instruction in scope. got=%d", prevIns.Opcode)
-		}
-	})
-}
-
-func TestFunctionCalls(t *testing.T) {
+func TestLetDestructuringStatements(t *testing.T) {
 	tests := []compilerTestCase{
 		{
-			input: `fn() { 24 }();`,
-			expectedConstants: []any{
-				24,
-				[]code.Instructions{
-					code.MakeInstruction(code.OpConstant, 0),
-					code.MakeInstruction(code.OpReturnValue),
-				},
-			},
+			input:             `let [a, ...b] = [1, 2];`,
+			expectedConstants: []interface{}{1, 2, 0, 1},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 1), // the compiled function
-				code.MakeInstruction(code.OpCall, 0),
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpArray, 2),
+				code.MakeInstruction(code.OpDup),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpIndex),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpDup),
+				code.MakeInstruction(code.OpConstant, 3),
+				code.MakeInstruction(code.OpNull),
+				code.MakeInstruction(code.OpSlice),
+				code.MakeInstruction(code.OpSetGlobal, 1),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
 		{
-			input: `
-				let noargs = fn() { 1 };
-				noargs();
-			`,
-			expectedConstants: []any{
-				1,
-				[]code.Instructions{
-					code.MakeInstruction(code.OpConstant, 0),
-					code.MakeInstruction(code.OpReturnValue),
-				},
-			},
+			input:             `let {name} = {}; name;`,
+			expectedConstants: []interface{}{"name"},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 1), // the compiled function
+				code.MakeInstruction(code.OpHashMap, 0),
+				code.MakeInstruction(code.OpDup),
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpIndex),
 				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpPop),
 				code.MakeInstruction(code.OpGetGlobal, 0),
-				code.MakeInstruction(code.OpCall, 0),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
 		{
-			input: `
-				let one_arg = fn(a) { };
-				one_arg(1);
-			`,
+			input: `fn() { let [x, [y]] = []; }`,
+			expectedConstants: []interface{}{
+				0,
+				1,
+				0,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpArray, 0),
+					code.MakeInstruction(code.OpDup),
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpIndex),
+					code.MakeInstruction(code.OpSetLocal, 0),
+					code.MakeInstruction(code.OpDup),
+					code.MakeInstruction(code.OpConstant, 1),
+					code.MakeInstruction(code.OpIndex),
+					code.MakeInstruction(code.OpDup),
+					code.MakeInstruction(code.OpConstant, 2),
+					code.MakeInstruction(code.OpIndex),
+					code.MakeInstruction(code.OpSetLocal, 1),
+					code.MakeInstruction(code.OpPop),
+					code.MakeInstruction(code.OpPop),
+					code.MakeInstruction(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 3, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `match 1 { 1 => 10, _ => 20 }`,
+			expectedConstants: []interface{}{1, map[int]int{1: 9}, 10, 20},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0003
+				code.MakeInstruction(code.OpGotoTable, 1),
+				// 0006
+				code.MakeInstruction(code.OpGoto, 16),
+				// 0009
+				code.MakeInstruction(code.OpPop),
+				// 0010
+				code.MakeInstruction(code.OpConstant, 2),
+				// 0013
+				code.MakeInstruction(code.OpGoto, 25),
+				// 0016
+				code.MakeInstruction(code.OpPop),
+				// 0017
+				code.MakeInstruction(code.OpConstant, 3),
+				// 0020
+				code.MakeInstruction(code.OpGoto, 25),
+				// 0023
+				code.MakeInstruction(code.OpPop),
+				// 0024
+				code.MakeInstruction(code.OpNull),
+				// 0025
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             `match [] { [a, 2] => a }`,
+			expectedConstants: []interface{}{1, 2, 0, 1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpArray, 0),
+				// 0003
+				code.MakeInstruction(code.OpMatchArray, 2, 0),
+				// 0007
+				code.MakeInstruction(code.OpGotoNotTruthyOrPop, 20),
+				// 0010
+				code.MakeInstruction(code.OpDup),
+				// 0011
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0014
+				code.MakeInstruction(code.OpIndex),
+				// 0015
+				code.MakeInstruction(code.OpMatchLiteral, 1),
+				// 0018
+				code.MakeInstruction(code.OpSwap),
+				// 0019
+				code.MakeInstruction(code.OpPop),
+				// 0020
+				code.MakeInstruction(code.OpGotoNotTruthy, 46),
+				// 0023
+				code.MakeInstruction(code.OpDup),
+				// 0024
+				code.MakeInstruction(code.OpDup),
+				// 0025
+				code.MakeInstruction(code.OpConstant, 2),
+				// 0028
+				code.MakeInstruction(code.OpIndex),
+				// 0029
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0032
+				code.MakeInstruction(code.OpDup),
+				// 0033
+				code.MakeInstruction(code.OpConstant, 3),
+				// 0036
+				code.MakeInstruction(code.OpIndex),
+				// 0037
+				code.MakeInstruction(code.OpPop),
+				// 0038
+				code.MakeInstruction(code.OpPop),
+				// 0039
+				code.MakeInstruction(code.OpPop),
+				// 0040
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0043
+				code.MakeInstruction(code.OpGoto, 48),
+				// 0046
+				code.MakeInstruction(code.OpPop),
+				// 0047
+				code.MakeInstruction(code.OpNull),
+				// 0048
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestTryStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `try { throw 1; } catch (e) { e; }`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpTry, 12),
+				// 0003
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0006
+				code.MakeInstruction(code.OpThrow),
+				// 0007
+				code.MakeInstruction(code.OpNull),
+				// 0008
+				code.MakeInstruction(code.OpEndTry),
+				// 0009
+				code.MakeInstruction(code.OpGoto, 21),
+				// 0012
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0015
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0018
+				code.MakeInstruction(code.OpGoto, 21),
+				// 0021
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             `try { 1; } finally { 2; }`,
+			expectedConstants: []interface{}{1, 2, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpTry, 14),
+				// 0003
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0006
+				code.MakeInstruction(code.OpEndTry),
+				// 0007
+				code.MakeInstruction(code.OpConstant, 1),
+				// 0010
+				code.MakeInstruction(code.OpPop),
+				// 0011
+				code.MakeInstruction(code.OpGoto, 25),
+				// 0014
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0017
+				code.MakeInstruction(code.OpConstant, 2),
+				// 0020
+				code.MakeInstruction(code.OpPop),
+				// 0021
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0024
+				code.MakeInstruction(code.OpThrow),
+				// 0025
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             `while true { try { break; } finally { 1; } }`,
+			expectedConstants: []interface{}{1, 1, 1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpLoop),
+				// 0001
+				code.MakeInstruction(code.OpTrue),
+				// 0002
+				code.MakeInstruction(code.OpGotoNotTruthy, 41),
+				// 0005
+				code.MakeInstruction(code.OpTry, 26),
+				// 0008
+				code.MakeInstruction(code.OpEndTry),
+				// 0009
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0012
+				code.MakeInstruction(code.OpPop),
+				// 0013
+				code.MakeInstruction(code.OpUnwindLoop),
+				// 0014
+				code.MakeInstruction(code.OpGoto, 41),
+				// 0017
+				code.MakeInstruction(code.OpNull),
+				// 0018
+				code.MakeInstruction(code.OpEndTry),
+				// 0019
+				code.MakeInstruction(code.OpConstant, 1),
+				// 0022
+				code.MakeInstruction(code.OpPop),
+				// 0023
+				code.MakeInstruction(code.OpGoto, 37),
+				// 0026
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0029
+				code.MakeInstruction(code.OpConstant, 2),
+				// 0032
+				code.MakeInstruction(code.OpPop),
+				// 0033
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0036
+				code.MakeInstruction(code.OpThrow),
+				// 0037
+				code.MakeInstruction(code.OpPop),
+				// 0038
+				code.MakeInstruction(code.OpGoto, 1),
+				// 0041
+				code.MakeInstruction(code.OpEndLoop),
+			},
+		},
+		{
+			input:             `try { 1; } finally { 2; } try { 3; } finally { 4; }`,
+			expectedConstants: []interface{}{1, 2, 2, 3, 4, 4},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpTry, 14),
+				// 0003
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0006
+				code.MakeInstruction(code.OpEndTry),
+				// 0007
+				code.MakeInstruction(code.OpConstant, 1),
+				// 0010
+				code.MakeInstruction(code.OpPop),
+				// 0011
+				code.MakeInstruction(code.OpGoto, 25),
+				// 0014
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0017
+				code.MakeInstruction(code.OpConstant, 2),
+				// 0020
+				code.MakeInstruction(code.OpPop),
+				// 0021
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0024
+				code.MakeInstruction(code.OpThrow),
+				// 0025
+				code.MakeInstruction(code.OpPop),
+				// 0026
+				code.MakeInstruction(code.OpTry, 40),
+				// 0029
+				code.MakeInstruction(code.OpConstant, 3),
+				// 0032
+				code.MakeInstruction(code.OpEndTry),
+				// 0033
+				code.MakeInstruction(code.OpConstant, 4),
+				// 0036
+				code.MakeInstruction(code.OpPop),
+				// 0037
+				code.MakeInstruction(code.OpGoto, 51),
+				// 0040
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0043
+				code.MakeInstruction(code.OpConstant, 5),
+				// 0046
+				code.MakeInstruction(code.OpPop),
+				// 0047
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0050
+				code.MakeInstruction(code.OpThrow),
+				// 0051
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { try { 1; } catch (e) { 2; } }`,
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					// 0000
+					code.MakeInstruction(code.OpTry, 10),
+					// 0003
+					code.MakeInstruction(code.OpConstant, 0),
+					// 0006
+					code.MakeInstruction(code.OpEndTry),
+					// 0007
+					code.MakeInstruction(code.OpGoto, 18),
+					// 0010
+					code.MakeInstruction(code.OpSetLocal, 0),
+					// 0012
+					code.MakeInstruction(code.OpConstant, 1),
+					// 0015
+					code.MakeInstruction(code.OpGoto, 18),
+					// 0018
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn() { return 5 + 10 }`,
+			expectedConstants: []interface{}{
+				5,
+				10,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpConstant, 1),
+					code.MakeInstruction(code.OpAdd),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { 5 + 10 }`,
+			expectedConstants: []interface{}{
+				5,
+				10,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpConstant, 1),
+					code.MakeInstruction(code.OpAdd),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { 5; 10 }`,
+			expectedConstants: []interface{}{
+				5,
+				10,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpPop),
+					code.MakeInstruction(code.OpConstant, 1),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { }`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.MakeInstruction(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 0, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `fn(a, b = 5) { b }`,
+			expectedConstants: []interface{}{
+				5,
+				[]code.Instructions{
+					// 0000
+					code.MakeInstruction(code.OpGotoArgPassed, 9, 1),
+					// 0004
+					code.MakeInstruction(code.OpConstant, 0),
+					// 0007
+					code.MakeInstruction(code.OpSetLocal, 1),
+					// 0009
+					code.MakeInstruction(code.OpGetLocal, 1),
+					// 0011
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 1, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `fn(a, ...rest) { rest }`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.MakeInstruction(code.OpGetLocal, 1),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 0, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestCompilerScopes(t *testing.T) {
+	compiler := New()
+	if compiler.scopeIndex != 0 {
+		t.Fatalf("initial scope index is not 0. got=%d", compiler.scopeIndex)
+	}
+
+	globalScopeTestIns := code.OpMul
+
+	t.Run("emit test instructions in main scope", func(t *testing.T) {
+		compiler.emit(globalScopeTestIns)
+	})
+
+	innerScopeTestIns := code.OpSub
+
+	t.Run("emit test instructions in the inner scope", func(t *testing.T) {
+		compiler.enterScope()
+		if compiler.scopeIndex != 1 {
+			t.Fatalf("scope index is not 1 after entering scope. got=%d", compiler.scopeIndex)
+		}
+
+		compiler.emit(innerScopeTestIns)
+
+		if len(compiler.curInstructions()) != 1 {
+			t.Fatalf("wrong number of instructions in scope. got=%d", len(compiler.curInstructions()))
+		}
+
+		lastIns := compiler.curScope().lastInstruction
+		if lastIns.Opcode != innerScopeTestIns {
+			t.Fatalf("wrong last instruction in scope. got=%d", lastIns.Opcode)
+		}
+	})
+
+	t.Run("leave inner scope and emit test instructions in the main scope & expect inner scope instructions to be invisible", func(t *testing.T) {
+		compiler.leaveScope()
+		if compiler.scopeIndex != 0 {
+			t.Fatalf("scope index is not 0 after leaving scope. got=%d", compiler.scopeIndex)
+		}
+
+		compiler.emit(code.OpAdd)
+
+		if len(compiler.curInstructions()) != 2 {
+			t.Fatalf("wrong number of instructions in scope. got=%d", len(compiler.curInstructions()))
+		}
+
+		lastIns := compiler.curScope().lastInstruction
+		if lastIns.Opcode != code.OpAdd {
+			t.Fatalf("wrong last instruction in scope. got=%d", lastIns.Opcode)
+		}
+
+		prevIns := compiler.curScope().prevInstruction
+		if prevIns.Opcode != globalScopeTestIns {
+			t.Fatalf("wrong previous instruction in scope. got=%d", prevIns.Opcode)
+		}
+	})
+}
+
+func TestFunctionCalls(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn() { 24 }();`,
+			expectedConstants: []any{
+				24,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 1, 0), // the compiled function
+				code.MakeInstruction(code.OpCall, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				let noargs = fn() { 1 };
+				noargs();
+			`,
+			expectedConstants: []any{
+				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 1, 0), // the compiled function
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpCall, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				let one_arg = fn(a) { };
+				one_arg(1);
+			`,
+			expectedConstants: []any{
+				[]code.Instructions{
+					code.MakeInstruction(code.OpReturn),
+				},
+				1,
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 0, 0), // the compiled function
+				code.MakeInstruction(code.OpSetGlobal, 0),
+
+				code.MakeInstruction(code.OpGetGlobal, 0), // get function by identifier
+				code.MakeInstruction(code.OpConstant, 1),  // argument
+				code.MakeInstruction(code.OpCall, 1),      // call function with 1 argument
+
+				code.MakeInstruction(code.OpPop), // exit
+			},
+		},
+		{
+			input: `
+				let multiple_args = fn(a, b, c) { };
+				multiple_args(1, 2, 3);
+			`,
+			expectedConstants: []any{
+				[]code.Instructions{
+					code.MakeInstruction(code.OpReturn),
+				},
+				1, 2, 3,
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 0, 0), // the compiled function
+				code.MakeInstruction(code.OpSetGlobal, 0),
+
+				code.MakeInstruction(code.OpGetGlobal, 0), // get function by identifier
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpConstant, 3),
+				code.MakeInstruction(code.OpCall, 3), // call function with 3 arguments
+
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				let one_arg = fn(a) { a };
+				one_arg(1);
+			`,
+			expectedConstants: []any{
+				[]code.Instructions{
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+				1,
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 0, 0), // the compiled function
+				code.MakeInstruction(code.OpSetGlobal, 0),
+
+				code.MakeInstruction(code.OpGetGlobal, 0), // get function 1 by identifier
+				code.MakeInstruction(code.OpConstant, 1),  // argument
+				code.MakeInstruction(code.OpCall, 1),      // call function with 1 argument
+
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				let multiple_args = fn(a, b, c) { a + b + c };
+				multiple_args(1, 2, 3);
+			`,
+			expectedConstants: []any{
+				[]code.Instructions{
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpGetLocal, 1),
+					code.MakeInstruction(code.OpAdd),
+
+					code.MakeInstruction(code.OpGetLocal, 2),
+					code.MakeInstruction(code.OpAdd),
+
+					code.MakeInstruction(code.OpReturnValue),
+				},
+				1, 2, 3,
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 0, 0), // the compiled function
+				code.MakeInstruction(code.OpSetGlobal, 0),
+
+				code.MakeInstruction(code.OpGetGlobal, 0), // get function by identifier
+				code.MakeInstruction(code.OpConstant, 1),  // arguments
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpConstant, 3),
+				code.MakeInstruction(code.OpCall, 3), // call function with 3 arguments
+
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestDefaultArgumentErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"fn f(a = b, b = 1) { a }", "defaults may only refer to earlier parameters: b"},
+		{"let b = 5; fn f(a = b, b = 1) { a }", "defaults may only refer to earlier parameters: b"},
+		{"fn f(a = a) { a }", "defaults may only refer to earlier parameters: a"},
+		{"fn f(a = rest, ...rest) { a }", "defaults may only refer to earlier parameters: rest"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compilation error for %q but got none", tt.input)
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong compilation error. got=%q, expected=%q", err.Error(), tt.expectedError)
+		}
+	}
+}
+
+func TestLetStatementScopes(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+				let num = 55;
+				fn() { num; };
+			`,
+			expectedConstants: []any{
+				55,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpGetGlobal, 0),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpClosure, 1, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				fn() {
+					let num = 55;
+					num
+				}
+			`,
+			expectedConstants: []any{
+				55,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpSetLocal, 0),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 1, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				fn() {
+					let a = 55;
+					let b = 77;
+
+					a + b;
+				}
+			`,
+			expectedConstants: []any{
+				55,
+				77,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpSetLocal, 0),
+					code.MakeInstruction(code.OpConstant, 1),
+					code.MakeInstruction(code.OpSetLocal, 1),
+
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpGetLocal, 1),
+					code.MakeInstruction(code.OpAdd),
+
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestStdLibFunctionCalls(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `len("hello")`,
+			expectedConstants: []any{"hello"},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpGetStdlib, 0),
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpCall, 1),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             `print("hello world")`,
+			expectedConstants: []any{"hello world"},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpGetStdlib, 1),
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpCall, 1),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+				fn(a) {
+					fn(b) {
+						a + b
+					}
+				}
+			`,
+			expectedConstants: []any{
+				[]code.Instructions{
+					code.MakeInstruction(code.OpGetFree, 0),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpAdd),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.MakeInstruction(code.OpCaptureLocal, 0), // captured `a`
+					code.MakeInstruction(code.OpClosure, 0, 1),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 1, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				fn(a) {
+					fn(b) {
+						fn(c) {
+							a + b + c
+						}
+					}
+				};
+			`,
+			expectedConstants: []any{
+				[]code.Instructions{
+					code.MakeInstruction(code.OpGetFree, 0),
+					code.MakeInstruction(code.OpGetFree, 1),
+					code.MakeInstruction(code.OpAdd),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpAdd),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.MakeInstruction(code.OpCaptureFree, 0), // `a` captured by the middle function is passed further down
+					code.MakeInstruction(code.OpCaptureLocal, 0),
+					code.MakeInstruction(code.OpClosure, 0, 2),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.MakeInstruction(code.OpCaptureLocal, 0),
+					code.MakeInstruction(code.OpClosure, 1, 1),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				let global = 55;
+
+				fn() {
+					let a = 66;
+
+					fn() {
+						let b = 77;
+
+						global + a + b
+					}
+				}
+			`,
 			expectedConstants: []any{
+				55,
+				66,
+				77,
 				[]code.Instructions{
-					code.MakeInstruction(code.OpReturn),
+					code.MakeInstruction(code.OpConstant, 2),
+					code.MakeInstruction(code.OpSetLocal, 0),
+					code.MakeInstruction(code.OpGetGlobal, 0),
+					code.MakeInstruction(code.OpGetFree, 0),
+					code.MakeInstruction(code.OpAdd),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpAdd),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 1),
+					code.MakeInstruction(code.OpSetLocal, 0),
+					code.MakeInstruction(code.OpCaptureLocal, 0),
+					code.MakeInstruction(code.OpClosure, 3, 1),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpClosure, 4, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+				fn countdown(x) { countdown(x - 1); };
+				countdown(1);
+			`,
+			expectedConstants: []any{
+				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpCurrentClosure),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpSub),
+					code.MakeInstruction(code.OpCall, 1),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+				1,
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 1, 0),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpPop),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpCall, 1),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				let wrapper = fn() {
+					fn countdown(x) { countdown(x - 1); };
+					countdown(1);
+				};
+				wrapper();
+			`,
+			expectedConstants: []any{
+				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpCurrentClosure),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpSub),
+					code.MakeInstruction(code.OpCall, 1),
+					code.MakeInstruction(code.OpReturnValue),
 				},
 				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpClosure, 1, 0),
+					code.MakeInstruction(code.OpSetLocal, 0),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpPop),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpConstant, 2),
+					code.MakeInstruction(code.OpCall, 1),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 3, 0),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpCall, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				fn() {
+					fn a() { b() }
+					fn b() { 1 }
+					a()
+				}
+			`,
+			expectedConstants: []any{
+				[]code.Instructions{
+					code.MakeInstruction(code.OpGetFree, 0),
+					code.MakeInstruction(code.OpCall, 0),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 1),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.MakeInstruction(code.OpCaptureLocal, 1), // b is declared before a is compiled
+					code.MakeInstruction(code.OpClosure, 0, 1),
+					code.MakeInstruction(code.OpSetLocal, 0),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpPop),
+					code.MakeInstruction(code.OpClosure, 2, 0),
+					code.MakeInstruction(code.OpSetLocal, 1),
+					code.MakeInstruction(code.OpGetLocal, 1),
+					code.MakeInstruction(code.OpPop),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpCall, 0),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 3, 0),
+				code.MakeInstruction(code.OpPop),
 			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestAssignStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+				let one = 1;
+				one = 2;
+			`,
+			expectedConstants: []any{1, 2},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 0), // the compiled function
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 1),
 				code.MakeInstruction(code.OpSetGlobal, 0),
+			},
+		},
+		{
+			input: `
+				fn() {
+					let num = 1;
+					num = num + 1;
+				}
+			`,
+			expectedConstants: []any{
+				1,
+				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpSetLocal, 0),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpConstant, 1),
+					code.MakeInstruction(code.OpAdd),
+					code.MakeInstruction(code.OpSetLocal, 0),
+					code.MakeInstruction(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				fn(a) {
+					fn() { a = 1; }
+				}
+			`,
+			expectedConstants: []any{
+				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpSetFree, 0),
+					code.MakeInstruction(code.OpReturn),
+				},
+				[]code.Instructions{
+					code.MakeInstruction(code.OpCaptureLocal, 0),
+					code.MakeInstruction(code.OpClosure, 1, 1),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestAssignStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"foobar = 1;", "undefined variable foobar"},
+		{"len = 1;", "cannot assign to stdlib function len"},
+		{"fn inner() { inner = 1; }", "cannot assign to function inner inside of its own body"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compilation error but got none")
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong compilation error. got=%q, expected=%q", err.Error(), tt.expectedError)
+		}
+	}
+}
+
+func TestConsts(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+				const ONE = 1;
+				const MINUS_TWO = -2;
+				ONE + MINUS_TWO;
+			`,
+			expectedConstants: []any{1, -2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpAdd),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				const NAME = "qrk";
+				const ALIAS = NAME;
+				fn() { ALIAS }
+			`,
+			expectedConstants: []any{
+				"qrk",
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 1, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: `
+				const ONE = 1;
+				const LIST = [ONE];
+				LIST;
+			`,
+			expectedConstants: []any{1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpArray, 1),
+				code.MakeInstruction(code.OpSetGlobal, 1),
+				code.MakeInstruction(code.OpGetGlobal, 1),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestConstErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"const x = 1;\nx = 2;", "cannot assign to constant x at line 2, column 1"},
+		{"const x = [1];\nfn() { x = 2; }", "cannot assign to constant x at line 2, column 8"},
+		{"const x = 1; let x = 2;", "cannot redeclare constant x at line 1, column 18"},
+		{"const x = 1; fn x() {}", "cannot redeclare constant x at line 1, column 17"},
+		{"const x = 1; let [x] = [2];", "cannot redeclare constant x at line 1, column 19"},
+		{"const Point = 1; struct Point { x }", "cannot redeclare constant Point at line 1, column 25"},
+		{"const x = 1; for x in [5, 6] {}", "cannot redeclare constant x at line 1, column 18"},
+		{"const x = 1; for i, x in [5, 6] {}", "cannot redeclare constant x at line 1, column 21"},
+		{"const x = 1; try { throw 5; } catch (x) {}", "cannot redeclare constant x at line 1, column 38"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compilation error for %q but got none", tt.input)
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong compilation error. got=%q, expected=%q", err.Error(), tt.expectedError)
+		}
+	}
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+				while true { 10; }
+			`,
+			expectedConstants: []any{10},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpLoop),
+				// 0001
+				code.MakeInstruction(code.OpTrue),
+				// 0002
+				code.MakeInstruction(code.OpGotoNotTruthy, 12),
+				// 0005
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0008
+				code.MakeInstruction(code.OpPop),
+				// 0009
+				code.MakeInstruction(code.OpGoto, 1),
+				// 0012
+				code.MakeInstruction(code.OpEndLoop),
+			},
+		},
+		{
+			input: `
+				while true { break; continue; }
+			`,
+			expectedConstants: []any{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpLoop),
+				// 0001
+				code.MakeInstruction(code.OpTrue),
+				// 0002
+				code.MakeInstruction(code.OpGotoNotTruthy, 16),
+				// 0005
+				code.MakeInstruction(code.OpUnwindLoop),
+				// 0006
+				code.MakeInstruction(code.OpGoto, 16), // break
+				// 0009
+				code.MakeInstruction(code.OpUnwindLoop),
+				// 0010
+				code.MakeInstruction(code.OpGoto, 1), // continue
+				// 0013
+				code.MakeInstruction(code.OpGoto, 1),
+				// 0016
+				code.MakeInstruction(code.OpEndLoop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLoopControlErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"break;", "break used outside of loop"},
+		{"continue;", "continue used outside of loop"},
+		{"while true { fn() { break; } }", "break used outside of loop"},
+	}
 
-				code.MakeInstruction(code.OpGetGlobal, 0), // get function by identifier
-				code.MakeInstruction(code.OpConstant, 1),  // argument
-				code.MakeInstruction(code.OpCall, 1),      // call function with 1 argument
+	for _, tt := range tests {
+		compiler := New()
 
-				code.MakeInstruction(code.OpPop), // exit
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compilation error but got none")
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong compilation error. got=%q, expected=%q", err.Error(), tt.expectedError)
+		}
+	}
+}
+
+func TestForInStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+				for x in [1] { x; }
+			`,
+			expectedConstants: []any{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0003
+				code.MakeInstruction(code.OpArray, 1),
+				// 0006
+				code.MakeInstruction(code.OpIter),
+				// 0007
+				code.MakeInstruction(code.OpLoop),
+				// 0008
+				code.MakeInstruction(code.OpIterNext, 22, 1),
+				// 0012
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0015
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0018
+				code.MakeInstruction(code.OpPop),
+				// 0019
+				code.MakeInstruction(code.OpGoto, 8),
+				// 0022
+				code.MakeInstruction(code.OpEndLoop),
+				// 0023
+				code.MakeInstruction(code.OpPop), // drop the iterator
 			},
 		},
 		{
 			input: `
-				let multiple_args = fn(a, b, c) { };
-				multiple_args(1, 2, 3);
+				fn() {
+					for k, v in {} { break; }
+				}
 			`,
 			expectedConstants: []any{
 				[]code.Instructions{
+					// 0000
+					code.MakeInstruction(code.OpHashMap, 0),
+					// 0003
+					code.MakeInstruction(code.OpIter),
+					// 0004
+					code.MakeInstruction(code.OpLoop),
+					// 0005
+					code.MakeInstruction(code.OpIterNext, 20, 2),
+					// 0009
+					code.MakeInstruction(code.OpSetLocal, 0), // value
+					// 0011
+					code.MakeInstruction(code.OpSetLocal, 1), // key
+					// 0013
+					code.MakeInstruction(code.OpUnwindLoop),
+					// 0014
+					code.MakeInstruction(code.OpGoto, 20), // break
+					// 0017
+					code.MakeInstruction(code.OpGoto, 5),
+					// 0020
+					code.MakeInstruction(code.OpEndLoop),
+					// 0021
+					code.MakeInstruction(code.OpPop),
+					// 0022
 					code.MakeInstruction(code.OpReturn),
 				},
-				1, 2, 3,
 			},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 0), // the compiled function
-				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpClosure, 0, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
 
-				code.MakeInstruction(code.OpGetGlobal, 0), // get function by identifier
+	runCompilerTests(t, tests)
+}
+
+// compileWithModules compiles the input as the main file of a directory containing the modules
+func compileWithModules(t *testing.T, input string, modules map[string]string) (*Bytecode, error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range modules {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	compiler := New()
+	compiler.SetLoader(module.NewLoader[*Module](filepath.Join(dir, "main.qrk")))
+
+	if err := compiler.Compile(parse(input)); err != nil {
+		return nil, err
+	}
+
+	return compiler.Bytecode(), nil
+}
+
+func TestStructs(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `struct Point { x, y } let p = Point(1, 2); p.x = 3; p.x`,
+			expectedConstants: []interface{}{
+				&object.StructType{Name: "Point", Fields: []string{"x", "y"}},
+				1,
+				2,
+				3,
+				"x",
+				"x",
+			},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0003
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0006
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0009
 				code.MakeInstruction(code.OpConstant, 1),
+				// 0012
 				code.MakeInstruction(code.OpConstant, 2),
+				// 0015
+				code.MakeInstruction(code.OpCall, 2),
+				// 0017
+				code.MakeInstruction(code.OpSetGlobal, 1),
+				// 0020
+				code.MakeInstruction(code.OpGetGlobal, 1),
+				// 0023
 				code.MakeInstruction(code.OpConstant, 3),
-				code.MakeInstruction(code.OpCall, 3), // call function with 3 arguments
-
+				// 0026
+				code.MakeInstruction(code.OpSetField, 4),
+				// 0029
+				code.MakeInstruction(code.OpGetGlobal, 1),
+				// 0032
+				code.MakeInstruction(code.OpGetField, 5),
+				// 0035
 				code.MakeInstruction(code.OpPop),
 			},
 		},
 		{
-			input: `
-				let one_arg = fn(a) { a };
-				one_arg(1);
-			`,
-			expectedConstants: []any{
+			input: `fn() { struct Unit {} Unit() }`,
+			expectedConstants: []interface{}{
+				&object.StructType{Name: "Unit", Fields: []string{}},
 				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpSetLocal, 0),
 					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpCall, 0),
 					code.MakeInstruction(code.OpReturnValue),
 				},
-				1,
 			},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 0), // the compiled function
-				code.MakeInstruction(code.OpSetGlobal, 0),
-
-				code.MakeInstruction(code.OpGetGlobal, 0), // get function 1 by identifier
-				code.MakeInstruction(code.OpConstant, 1),  // argument
-				code.MakeInstruction(code.OpCall, 1),      // call function with 1 argument
-
+				code.MakeInstruction(code.OpClosure, 1, 0),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
-		{
-			input: `
-				let multiple_args = fn(a, b, c) { a + b + c };
-				multiple_args(1, 2, 3);
-			`,
-			expectedConstants: []any{
-				[]code.Instructions{
-					code.MakeInstruction(code.OpGetLocal, 0),
-					code.MakeInstruction(code.OpGetLocal, 1),
-					code.MakeInstruction(code.OpAdd),
+	}
 
-					code.MakeInstruction(code.OpGetLocal, 2),
-					code.MakeInstruction(code.OpAdd),
+	runCompilerTests(t, tests)
+}
 
-					code.MakeInstruction(code.OpReturnValue),
-				},
-				1, 2, 3,
+func TestMemberAccess(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `let m = {}; m.x = 1; m.x`,
+			expectedConstants: []interface{}{1, "x", "x"},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpHashMap, 0),
+				// 0003
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0006
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0009
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0012
+				code.MakeInstruction(code.OpSetField, 1),
+				// 0015
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0018
+				code.MakeInstruction(code.OpGetField, 2),
+				// 0021
+				code.MakeInstruction(code.OpPop),
 			},
+		},
+		{
+			input:             `let m = {}; m.greet(1, 2)`,
+			expectedConstants: []interface{}{"greet", 1, 2},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 0), // the compiled function
+				// 0000
+				code.MakeInstruction(code.OpHashMap, 0),
+				// 0003
 				code.MakeInstruction(code.OpSetGlobal, 0),
-
-				code.MakeInstruction(code.OpGetGlobal, 0), // get function by identifier
-				code.MakeInstruction(code.OpConstant, 1),  // arguments
+				// 0006
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0009
+				code.MakeInstruction(code.OpGetMethod, 0),
+				// 0012
+				code.MakeInstruction(code.OpConstant, 1),
+				// 0015
 				code.MakeInstruction(code.OpConstant, 2),
-				code.MakeInstruction(code.OpConstant, 3),
-				code.MakeInstruction(code.OpCall, 3), // call function with 3 arguments
-
+				// 0018
+				code.MakeInstruction(code.OpCallMethod, 2),
+				// 0020
 				code.MakeInstruction(code.OpPop),
 			},
 		},
@@ -864,75 +2288,75 @@ func TestFunctionCalls(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
-func TestLetStatementScopes(t *testing.T) {
+func TestGenerators(t *testing.T) {
 	tests := []compilerTestCase{
 		{
-			input: `
-				let num = 55;
-				fn() { num; };
-			`,
-			expectedConstants: []any{
-				55,
+			input: `fn*(x) { yield x; yield; }`,
+			expectedConstants: []interface{}{
 				[]code.Instructions{
-					code.MakeInstruction(code.OpGetGlobal, 0),
-					code.MakeInstruction(code.OpReturnValue),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpYield),
+					code.MakeInstruction(code.OpNull),
+					code.MakeInstruction(code.OpYield),
+					code.MakeInstruction(code.OpReturn),
 				},
 			},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 0),
-				code.MakeInstruction(code.OpSetGlobal, 0),
-				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpClosure, 0, 0),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	tests := []string{
+		"yield 1;",
+		"fn() { yield 1; }",
+		"fn*() { fn() { yield 1; } }",
+	}
+
+	for _, input := range tests {
+		compiler := New()
+
+		err := compiler.Compile(parse(input))
+		if err == nil {
+			t.Fatalf("expected compilation error for %q but got none", input)
+		}
+
+		expectedError := "yield used outside of generator function"
+		if err.Error() != expectedError {
+			t.Errorf("wrong compilation error. got=%q, expected=%q", err.Error(), expectedError)
+		}
+	}
+}
+
+func TestSpawn(t *testing.T) {
+	tests := []compilerTestCase{
 		{
-			input: `
-				fn() {
-					let num = 55;
-					num
-				}
-			`,
-			expectedConstants: []any{
-				55,
-				[]code.Instructions{
-					code.MakeInstruction(code.OpConstant, 0),
-					code.MakeInstruction(code.OpSetLocal, 0),
-					code.MakeInstruction(code.OpGetLocal, 0),
-					code.MakeInstruction(code.OpReturnValue),
-				},
-			},
+			input:             `spawn len("abc")`,
+			expectedConstants: []interface{}{"abc"},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpGetStdlib, 0),
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpSpawn, 1),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
 		{
-			input: `
-				fn() {
-					let a = 55;
-					let b = 77;
-
-					a + b;
-				}
-			`,
-			expectedConstants: []any{
-				55,
-				77,
+			input: `spawn fn() { 1 }`,
+			expectedConstants: []interface{}{
+				1,
 				[]code.Instructions{
 					code.MakeInstruction(code.OpConstant, 0),
-					code.MakeInstruction(code.OpSetLocal, 0),
-					code.MakeInstruction(code.OpConstant, 1),
-					code.MakeInstruction(code.OpSetLocal, 1),
-
-					code.MakeInstruction(code.OpGetLocal, 0),
-					code.MakeInstruction(code.OpGetLocal, 1),
-					code.MakeInstruction(code.OpAdd),
-
 					code.MakeInstruction(code.OpReturnValue),
 				},
 			},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpClosure, 1, 0),
+				code.MakeInstruction(code.OpSpawn, 0),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
@@ -941,29 +2365,116 @@ func TestLetStatementScopes(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
-func TestStdLibFunctionCalls(t *testing.T) {
+func TestImports(t *testing.T) {
+	modules := map[string]string{
+		"lib.qrk": "let hidden = 2; export let x = hidden + 1;",
+	}
+
 	tests := []compilerTestCase{
 		{
-			input:             `len("hello")`,
-			expectedConstants: []any{"hello"},
+			input:             `let a = 1; import "lib.qrk" as l; let b = l.x;`,
+			expectedConstants: []interface{}{1, 2, 1},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpGetStdlib, 0),
+				// 0000
 				code.MakeInstruction(code.OpConstant, 0),
-				code.MakeInstruction(code.OpCall, 1),
-				code.MakeInstruction(code.OpPop),
+				// 0003
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0006
+				code.MakeInstruction(code.OpConstant, 1),
+				// 0009
+				code.MakeInstruction(code.OpSetGlobal, 1),
+				// 0012
+				code.MakeInstruction(code.OpGetGlobal, 1),
+				// 0015
+				code.MakeInstruction(code.OpConstant, 2),
+				// 0018
+				code.MakeInstruction(code.OpAdd),
+				// 0019
+				code.MakeInstruction(code.OpSetGlobal, 2),
+				// 0022
+				code.MakeInstruction(code.OpGetGlobal, 2),
+				// 0025
+				code.MakeInstruction(code.OpSetGlobal, 3),
 			},
 		},
 		{
-			input:             `print("hello world")`,
-			expectedConstants: []any{"hello world"},
+			input:             `import "lib.qrk" as l; import "lib.qrk" as again; fn() { again.x }`,
+			expectedConstants: []interface{}{2, 1, []code.Instructions{code.MakeInstruction(code.OpGetGlobal, 1), code.MakeInstruction(code.OpReturnValue)}},
 			expectedInstructions: []code.Instructions{
-				code.MakeInstruction(code.OpGetStdlib, 1),
+				// 0000
 				code.MakeInstruction(code.OpConstant, 0),
-				code.MakeInstruction(code.OpCall, 1),
+				// 0003
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				// 0006
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				// 0009
+				code.MakeInstruction(code.OpConstant, 1),
+				// 0012
+				code.MakeInstruction(code.OpAdd),
+				// 0013
+				code.MakeInstruction(code.OpSetGlobal, 1),
+				// 0016
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				// 0020
 				code.MakeInstruction(code.OpPop),
 			},
 		},
 	}
 
-	runCompilerTests(t, tests)
+	for i, tt := range tests {
+		bytecode, err := compileWithModules(t, tt.input, modules)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		if err := testInstructions(tt.expectedInstructions, bytecode.Instructions); err != nil {
+			t.Fatalf("[%d] testInstructions failed: %s", i+1, err)
+		}
+
+		if err := testConstants(tt.expectedConstants, bytecode.Constants); err != nil {
+			t.Fatalf("[%d] testConstants failed: %s", i+1, err)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	modules := map[string]string{
+		"lib.qrk":    "let hidden = 1; export fn shout(s) { s }",
+		"broken.qrk": "export let x = missing;",
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib.qrk" as l; l.hidden`, "module l has no export hidden"},
+		{`import "lib.qrk" as l; l`, "module l cannot be used as a value, access its exports with l.name"},
+		{`import "lib.qrk" as l; l = 1;`, "cannot assign to module l"},
+		{`import "lib.qrk" as l; l.shout = 1;`, "cannot assign to export shout of module l"},
+		{`missing.y`, "undefined variable missing"},
+		{`fn() { import "lib.qrk" as l; }`, "import is only allowed at the top level of a file"},
+		{`if true { export let x = 1; }`, "export is only allowed at the top level of a file"},
+		{`import "missing.qrk" as m;`, "module not found: missing.qrk"},
+	}
+
+	for _, tt := range tests {
+		_, err := compileWithModules(t, tt.input, modules)
+		if err == nil {
+			t.Fatalf("expected compiler error for %q, got none", tt.input)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. expected=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+
+	_, err := compileWithModules(t, `import "broken.qrk" as b;`, modules)
+	if err == nil {
+		t.Fatalf("expected compiler error for broken.qrk, got none")
+	}
+
+	expected := "broken.qrk: undefined variable missing"
+	if filepath.Base(err.Error()) != expected {
+		t.Errorf("wrong compiler error. expected=%q, got=%q", expected, err.Error())
+	}
 }
@@ -3,15 +3,34 @@ package compiler
 type SymbolScope string
 
 const (
-	GlobalScope SymbolScope = "GLOBAL"
-	LocalScope  SymbolScope = "LOCAL"
-	StdlibScope SymbolScope = "STD_LIB"
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	StdlibScope   SymbolScope = "STD_LIB"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
+	ModuleScope   SymbolScope = "MODULE"
 )
 
 type Symbol struct {
 	Name  string
 	Scope SymbolScope
 	Index int
+
+	// set for bindings declared with const, they can't be assigned to
+	IsConst bool
+	// set for consts whose value is known at compile time, loading them pushes the constant at ConstantIndex
+	IsInlined     bool
+	ConstantIndex int
+
+	// set for module scope only, module names are resolved at compile time and never reach the VM
+	Module *Module
+}
+
+// Module is an imported file compiled into the program,
+// its exports are global symbols of the module's own symbol table
+type Module struct {
+	Path    string
+	Exports map[string]Symbol
 }
 
 type SymbolTable struct {
@@ -19,6 +38,10 @@ type SymbolTable struct {
 
 	store            map[string]Symbol
 	definitionsCount int
+
+	// local symbols of the enclosing scopes referenced from the current one,
+	// in the order they have to be pushed when creating a closure
+	FreeSymbols []Symbol
 }
 
 func NewSymbolTable() *SymbolTable {
@@ -34,6 +57,15 @@ func NewEnclosedSymbolTable(outerSymbolTable *SymbolTable) *SymbolTable {
 	return store
 }
 
+// NewModuleSymbolTable creates the global scope of an imported module,
+// its globals are numbered after the importer's ones, so that modules don't share global slots
+func NewModuleSymbolTable(importer *SymbolTable) *SymbolTable {
+	symbolTable := NewSymbolTable()
+	symbolTable.definitionsCount = importer.definitionsCount
+
+	return symbolTable
+}
+
 func (s *SymbolTable) Define(name string) Symbol {
 	symbol := Symbol{
 		Name:  name,
@@ -54,6 +86,32 @@ func (s *SymbolTable) Define(name string) Symbol {
 	return symbol
 }
 
+func (s *SymbolTable) DefineConst(name string) Symbol {
+	symbol := s.Define(name)
+	symbol.IsConst = true
+
+	s.store[name] = symbol
+
+	return symbol
+}
+
+// DefineInlinedConst binds the name to the constant, the binding still takes a slot,
+// but the value is never stored into it
+func (s *SymbolTable) DefineInlinedConst(name string, constantIndex int) Symbol {
+	symbol := s.DefineConst(name)
+	symbol.IsInlined = true
+	symbol.ConstantIndex = constantIndex
+
+	s.store[name] = symbol
+
+	return symbol
+}
+
+// DefinesConst tells whether the name is bound by const in the current scope, not in the enclosing ones
+func (s *SymbolTable) DefinesConst(name string) bool {
+	return s.store[name].IsConst
+}
+
 func (s *SymbolTable) DefineStdlibFunc(index int, name string) Symbol {
 	sym := Symbol{
 		Name:  name,
@@ -66,12 +124,68 @@ func (s *SymbolTable) DefineStdlibFunc(index int, name string) Symbol {
 	return sym
 }
 
+func (s *SymbolTable) DefineModule(name string, module *Module) Symbol {
+	symbol := Symbol{
+		Name:   name,
+		Scope:  ModuleScope,
+		Module: module,
+	}
+
+	s.store[name] = symbol
+
+	return symbol
+}
+
+// DefineFunctionName binds the name of a named function inside its own body,
+// so the function can refer to itself without going through the enclosing scope
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{
+		Name:  name,
+		Scope: FunctionScope,
+		Index: 0,
+	}
+
+	s.store[name] = symbol
+
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{
+		Name:    original.Name,
+		Scope:   FreeScope,
+		Index:   len(s.FreeSymbols) - 1,
+		IsConst: original.IsConst,
+	}
+
+	s.store[original.Name] = symbol
+
+	return symbol
+}
+
 func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
 	obj, inCurScope := s.store[name]
 
-	if !inCurScope && s.Outer != nil {
-		return s.Outer.Resolve(name)
+	if inCurScope || s.Outer == nil {
+		return obj, inCurScope
+	}
+
+	obj, inOuterScope := s.Outer.Resolve(name)
+	if !inOuterScope {
+		return obj, inOuterScope
+	}
+
+	// inlined consts don't live in the enclosing scope at runtime, there is nothing to capture
+	if obj.IsInlined {
+		return obj, inOuterScope
+	}
+
+	isCapturable := obj.Scope == LocalScope || obj.Scope == FreeScope || obj.Scope == FunctionScope
+	if !isCapturable {
+		return obj, inOuterScope
 	}
 
-	return obj, inCurScope
+	return s.defineFree(obj), true
 }
@@ -180,3 +180,215 @@ func TestDefineResolveStdlib(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+	firstLocal.Define("d")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+	secondLocal.Define("f")
+
+	tests := []struct {
+		table               *SymbolTable
+		expectedSymbols     []Symbol
+		expectedFreeSymbols []Symbol
+	}{
+		{
+			firstLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "b", Scope: GlobalScope, Index: 1},
+				{Name: "c", Scope: LocalScope, Index: 0},
+				{Name: "d", Scope: LocalScope, Index: 1},
+			},
+			[]Symbol{},
+		},
+		{
+			secondLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "b", Scope: GlobalScope, Index: 1},
+				{Name: "c", Scope: FreeScope, Index: 0},
+				{Name: "d", Scope: FreeScope, Index: 1},
+				{Name: "e", Scope: LocalScope, Index: 0},
+				{Name: "f", Scope: LocalScope, Index: 1},
+			},
+			[]Symbol{
+				{Name: "c", Scope: LocalScope, Index: 0},
+				{Name: "d", Scope: LocalScope, Index: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, symbol := range tt.expectedSymbols {
+			result, ok := tt.table.Resolve(symbol.Name)
+			if !ok {
+				t.Errorf("name %s not resolvable", symbol.Name)
+				continue
+			}
+
+			if result != symbol {
+				t.Errorf("expected %s to resolve to %+v, got %+v", symbol.Name, symbol, result)
+			}
+		}
+
+		if len(tt.table.FreeSymbols) != len(tt.expectedFreeSymbols) {
+			t.Errorf("wrong number of free symbols. got=%d, expected=%d", len(tt.table.FreeSymbols), len(tt.expectedFreeSymbols))
+			continue
+		}
+
+		for i, symbol := range tt.expectedFreeSymbols {
+			result := tt.table.FreeSymbols[i]
+			if result != symbol {
+				t.Errorf("wrong free symbol. got=%+v, expected=%+v", result, symbol)
+			}
+		}
+	}
+}
+
+func TestResolveUnresolvableFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+	secondLocal.Define("f")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "c", Scope: FreeScope, Index: 0},
+		{Name: "e", Scope: LocalScope, Index: 0},
+		{Name: "f", Scope: LocalScope, Index: 1},
+	}
+
+	for _, symbol := range expected {
+		result, ok := secondLocal.Resolve(symbol.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", symbol.Name)
+			continue
+		}
+
+		if result != symbol {
+			t.Errorf("expected %s to resolve to %+v, got %+v", symbol.Name, symbol, result)
+		}
+	}
+
+	for _, name := range []string{"b", "d"} {
+		if _, ok := secondLocal.Resolve(name); ok {
+			t.Errorf("name %s resolved, but was expected not to", name)
+		}
+	}
+}
+
+func TestDefineAndResolveFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+
+	expected := Symbol{Name: "a", Scope: FunctionScope, Index: 0}
+
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got %+v", expected.Name, expected, result)
+	}
+}
+
+func TestShadowingFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+	global.Define("a")
+
+	expected := Symbol{Name: "a", Scope: GlobalScope, Index: 0}
+
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got %+v", expected.Name, expected, result)
+	}
+}
+
+func TestDefineResolveConst(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineConst("a")
+	global.DefineInlinedConst("b", 3)
+
+	local := NewEnclosedSymbolTable(global)
+	local.DefineConst("c")
+
+	nested := NewEnclosedSymbolTable(local)
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0, IsConst: true},
+		{Name: "b", Scope: GlobalScope, Index: 1, IsConst: true, IsInlined: true, ConstantIndex: 3},
+		{Name: "c", Scope: FreeScope, Index: 0, IsConst: true},
+	}
+
+	for _, sym := range expected {
+		result, ok := nested.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got %+v", sym.Name, sym, result)
+		}
+	}
+
+	if len(nested.FreeSymbols) != 1 {
+		t.Errorf("wrong number of free symbols. got=%d, expected=1", len(nested.FreeSymbols))
+	}
+
+	if !global.DefinesConst("a") || nested.DefinesConst("a") {
+		t.Errorf("DefinesConst must only report the consts of its own scope")
+	}
+}
+
+func TestModuleSymbolTable(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	moduleTable := NewModuleSymbolTable(global)
+
+	b := moduleTable.Define("b")
+	if b != (Symbol{Name: "b", Scope: GlobalScope, Index: 1}) {
+		t.Errorf("b is not defined after the importer's globals. got=%+v", b)
+	}
+
+	if _, ok := moduleTable.Resolve("a"); ok {
+		t.Errorf("importer's global a is visible in the module")
+	}
+
+	module := &Module{Path: "lib.qrk", Exports: map[string]Symbol{"b": b}}
+	global.DefineModule("lib", module)
+
+	local := NewEnclosedSymbolTable(global)
+	lib, ok := local.Resolve("lib")
+	if !ok {
+		t.Fatalf("lib is not resolvable")
+	}
+
+	if lib.Scope != ModuleScope || lib.Module != module {
+		t.Errorf("lib is not resolved as module. got=%+v", lib)
+	}
+
+	if len(local.FreeSymbols) != 0 {
+		t.Errorf("module is captured as free symbol. got=%+v", local.FreeSymbols)
+	}
+}
@@ -11,4 +11,23 @@ const (
 	PROVIDED_OBJECT_IS_NOT_HASHMAP               = "provided object is not HashMap"
 	PROVIDED_INDEX_CANNOT_BE_USED_AS_HASHMAP_KEY = "provided index cannot be used as a HashMap key"
 	NOT_A_HASHMAP                                = "not a HashMap"
+	CANNOT_ASSIGN_TO_BUILT_IN                    = "cannot assign to built-in function"
+	OUTSIDE_OF_LOOP                              = "used outside of loop"
+	OUTSIDE_OF_GENERATOR                         = "used outside of generator function"
+	NOT_ITERABLE                                 = "not iterable"
+	INDEX_OUT_OF_BOUNDS                          = "index out of bounds"
+	INDEX_ASSIGNMENT_NOT_SUPPORTED               = "index assignment not supported"
+	ARRAY_INDEX_MUST_BE_INTEGER                  = "array index must be INTEGER"
+	SLICE_OPERATOR_NOT_SUPPORTED                 = "slice operator not supported"
+	SLICE_BOUND_MUST_BE_INTEGER                  = "slice bound must be an integer"
+	UNCAUGHT_EXCEPTION                           = "uncaught exception"
+	NOT_AT_TOP_LEVEL                             = "only allowed at the top level of a file"
+	MODULE_USED_AS_VALUE                         = "module cannot be used as a value"
+	CANNOT_ASSIGN_TO_MODULE                      = "cannot assign to module"
+	CANNOT_ASSIGN_TO_CONSTANT                    = "cannot assign to constant"
+	CANNOT_REDECLARE_CONSTANT                    = "cannot redeclare constant"
+	NO_SUCH_EXPORT                               = "no such export"
+	MEMBER_ACCESS_NOT_SUPPORTED                  = "member access not supported"
+	NO_SUCH_FIELD                                = "no such field"
+	DEFAULT_REFERS_TO_LATER_PARAM                = "defaults may only refer to earlier parameters"
 )
@@ -1,9 +1,13 @@
 package evaluator
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/vdchnsk/qrk/src/ast"
+	"github.com/vdchnsk/qrk/src/module"
 	"github.com/vdchnsk/qrk/src/object"
 	"github.com/vdchnsk/qrk/src/stdlib"
 	"github.com/vdchnsk/qrk/src/token"
@@ -13,6 +17,13 @@ var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
+
+	// placeholder of the parameters which aren't bound yet while the default values are evaluated,
+	// it isn't a Null, since pointers to zero-sized values aren't guaranteed to be distinct
+	UNBOUND_PARAM = &object.Error{Message: DEFAULT_REFERS_TO_LATER_PARAM}
 )
 
 func isTruthy(obj object.Object) bool {
@@ -25,11 +36,19 @@ func isTruthy(obj object.Object) bool {
 	return true
 }
 
-func isError(obj object.Object) bool {
-	if obj == nil {
+func isLoopControl(obj object.Object) bool {
+	return obj == BREAK || obj == CONTINUE
+}
+
+// isAbrupt tells whether the evaluation was cut short by an error, or by `return`, `break` or `continue`
+// in a block nested in the expression, such results are passed up to the statement handling them
+func isAbrupt(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Error, *object.ReturnWrapper, *object.Break, *object.Continue:
+		return true
+	default:
 		return false
 	}
-	return obj.Type() == object.ERROR_OBJ
 }
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
@@ -48,6 +67,19 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			Value: node.Value,
 		}
 
+	case *ast.BigIntegerLiteral:
+		return &object.BigInteger{
+			Value: node.Value,
+		}
+
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
+
+	case *ast.FloatLiteral:
+		return &object.Float{
+			Value: node.Value,
+		}
+
 	case *ast.StringLiteral:
 		return &object.String{
 			Value: node.Value,
@@ -58,45 +90,123 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
+		if node.Operator == token.AND || node.Operator == token.OR {
+			return evalLogicalExpression(node.Operator, left, node.Right, env)
+		}
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
 		return evalInfixExpression(node.Operator, left, right)
 
 	case *ast.LetStatement:
+		if err := checkRedeclaration(node.Identifier.Value, env); err != nil {
+			return err
+		}
+
+		val := Eval(node.Value, env)
+		if isAbrupt(val) {
+			return val
+		}
+
+		if node.IsConst() {
+			env.PutConst(node.Identifier.Value, val)
+		} else {
+			env.Put(node.Identifier.Value, val)
+		}
+
+	case *ast.LetDestructuringStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
-		env.Put(node.Identifier.Value, val)
+		if err := evalPattern(node.Pattern, val, env); err != nil {
+			return err
+		}
 
 	case *ast.AssignStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if isAbrupt(val) {
+			return val
+		}
+		if err := evalAssignment(node.Identifier.Value, val, env); err != nil {
+			return err
+		}
+
+	case *ast.IndexAssignStatement:
+		if result := evalIndexAssignment(node, env); result != nil {
+			return result
+		}
+
+	case *ast.MemberAssignStatement:
+		if result := evalMemberAssignment(node, env); result != nil {
+			return result
+		}
+
+	case *ast.StructStatement:
+		if err := checkRedeclaration(node.Name.Value, env); err != nil {
+			return err
+		}
+
+		fields := make([]string, len(node.Fields))
+		for i, field := range node.Fields {
+			fields[i] = field.Value
+		}
+		env.Put(node.Name.Value, &object.StructType{Name: node.Name.Value, Fields: fields})
+
+	case *ast.YieldStatement:
+		return evalYieldStatement(node, env)
+
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isAbrupt(val) {
 			return val
 		}
-		env.Put(node.Identifier.Value, val)
+		return newThrownError(val)
+
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
 
 	case *ast.Identifier:
 		return evalIdentifier(node.Value, env)
 
+	case *ast.MemberExpression:
+		return evalMemberExpression(node, env)
+
+	case *ast.ImportStatement, *ast.ExportStatement:
+		return newError("%s: %s", NOT_AT_TOP_LEVEL, node.TokenLiteral())
+
 	case *ast.IfExpression:
 		return evalIfExpression(node.Condition, node.Consequence, node.Alternative, env)
 
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
 	case *ast.ReturnStatement:
 		returningVal := Eval(node.Value, env)
-		if isError(returningVal) {
+		if isAbrupt(returningVal) {
 			return returningVal
 		}
 		return &object.ReturnWrapper{Value: returningVal}
@@ -107,30 +217,47 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 		funcObj := &object.Function{
 			Parameters: params,
+			Defaults:   node.Defaults,
+			Rest:       node.Rest,
 			Body:       body,
 			Env:        env,
+			Name:       node.Name,
+			Arity:      object.FuncArity(node),
+			TakesSelf:  node.TakesSelf(),
+
+			IsGenerator: node.IsGenerator,
 		}
 
 		if node.Identifier != nil {
+			if err := checkRedeclaration(node.Identifier.Value, env); err != nil {
+				return err
+			}
 			env.Put(node.Identifier.Value, funcObj)
 		}
 
 		return funcObj
 
 	case *ast.CallExpression:
+		if member, ok := node.Function.(*ast.MemberExpression); ok {
+			return evalMethodCall(member, node.Arguments, env)
+		}
+
 		fn := Eval(node.Function, env)
-		if isError(fn) {
+		if isAbrupt(fn) {
 			return fn
 		}
 		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		if len(args) == 1 && isAbrupt(args[0]) {
 			return args[0]
 		}
-		return applyFunction(fn, args)
+		return applyFunction(fn, args, env)
+
+	case *ast.SpawnExpression:
+		return evalSpawnExpression(node, env)
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		if len(elements) == 1 && isAbrupt(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{
@@ -139,15 +266,30 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
 		index := Eval(node.Index, env)
-		if isError(index) {
+		if isAbrupt(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
 
+	case *ast.SliceExpression:
+		left := Eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		start := evalSliceBoundExpression(node.Start, env)
+		if isAbrupt(start) {
+			return start
+		}
+		end := evalSliceBoundExpression(node.End, env)
+		if isAbrupt(end) {
+			return end
+		}
+		return evalSliceExpression(left, start, end)
+
 	case *ast.HashMapLiteral:
 		return evalHashMap(node, env)
 	}
@@ -171,18 +313,138 @@ func evalProgram(statements []ast.Statement, env *object.Environment) object.Obj
 	var result object.Object
 
 	for _, statement := range statements {
-		result = Eval(statement, env)
+		result = evalTopLevelStatement(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnWrapper:
 			return result.Value
 		case *object.Error:
-			return result
+			return withStackTrace(result, env)
+		case *object.Break, *object.Continue:
+			return newError("%s: %s", OUTSIDE_OF_LOOP, result.Inspect())
 		}
 	}
 	return result
 }
 
+// evalTopLevelStatement evaluates a statement of a file, where imports and exports are allowed
+func evalTopLevelStatement(statement ast.Statement, env *object.Environment) object.Object {
+	switch statement := statement.(type) {
+	case *ast.ImportStatement:
+		return evalImportStatement(statement, env)
+
+	case *ast.ExportStatement:
+		return Eval(statement.Statement, env)
+
+	default:
+		return Eval(statement, env)
+	}
+}
+
+func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	loader := env.ModuleLoader()
+
+	// the module's own error is returned as is, so that it can be caught with its stack trace
+	var moduleErr *object.Error
+
+	imported, err := loader.Load(node.Path.Value, func(path string, program *ast.Program) (*object.Module, error) {
+		moduleEnv := object.NewModuleEnvironment(loader)
+
+		if err, ok := Eval(program, moduleEnv).(*object.Error); ok {
+			moduleErr = err
+			return nil, errors.New(err.Message)
+		}
+
+		return &object.Module{Path: path, Env: moduleEnv, Exports: module.ExportedNames(program)}, nil
+	})
+	if moduleErr != nil {
+		return moduleErr
+	}
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	env.Put(node.Alias.Value, imported)
+
+	return nil
+}
+
+func evalMemberExpression(node *ast.MemberExpression, env *object.Environment) object.Object {
+	value := evalMemberObject(node, env)
+	if isAbrupt(value) {
+		return value
+	}
+
+	return evalMember(value, node)
+}
+
+// evalMember looks up the export of a module, the field of a record, or the key of a hashmap or an exception,
+// `person.name` is the same as `person["name"]`
+func evalMember(value object.Object, node *ast.MemberExpression) object.Object {
+	switch value := value.(type) {
+	case *object.Module:
+		export, ok := value.Export(node.Member.Value)
+		if !ok {
+			return newError("%s: %s", NO_SUCH_EXPORT, node)
+		}
+		return export
+
+	case *object.Struct:
+		field, ok := value.Field(node.Member.Value)
+		if !ok {
+			return newError("%s: %s.%s", NO_SUCH_FIELD, value.StructType.Name, node.Member.Value)
+		}
+		return field
+
+	case *object.HashMap:
+		return evalHashMapIndexExpression(value, &object.String{Value: node.Member.Value})
+
+	case *object.Exception:
+		field, ok := value.Field(&object.String{Value: node.Member.Value})
+		if !ok {
+			return NULL
+		}
+		return field
+
+	default:
+		return newError("%s: %s", MEMBER_ACCESS_NOT_SUPPORTED, value.Type())
+	}
+}
+
+// evalMethodCall calls the member of the receiver, members of modules are called as plain functions
+func evalMethodCall(member *ast.MemberExpression, arguments []ast.Expression, env *object.Environment) object.Object {
+	receiver := evalMemberObject(member, env)
+	if isAbrupt(receiver) {
+		return receiver
+	}
+
+	method := evalMember(receiver, member)
+	if isAbrupt(method) {
+		return method
+	}
+
+	args := evalExpressions(arguments, env)
+	if len(args) == 1 && isAbrupt(args[0]) {
+		return args[0]
+	}
+
+	if _, ok := receiver.(*object.Module); ok {
+		return applyFunction(method, args, env)
+	}
+
+	return applyMethod(method, receiver, args, env)
+}
+
+// evalMemberObject evaluates the value the member is accessed on,
+// a module is only accessible through its members, so it's looked up bypassing evalIdentifier
+func evalMemberObject(node *ast.MemberExpression, env *object.Environment) object.Object {
+	if identifier, ok := node.Object.(*ast.Identifier); ok {
+		return evalIdentifierValue(identifier.Value, env)
+	}
+
+	return Eval(node.Object, env)
+}
+
 func evalBlockStatements(statements []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
@@ -190,21 +452,68 @@ func evalBlockStatements(statements []ast.Statement, env *object.Environment) ob
 		result = Eval(statement, env)
 
 		_, isReturnWrapper := result.(*object.ReturnWrapper)
-		_, isError := result.(*object.Error)
+		err, isError := result.(*object.Error)
+
+		if isError {
+			return withStackTrace(err, env)
+		}
 
-		if result != nil && (isReturnWrapper || isError) {
+		if result != nil && (isReturnWrapper || isLoopControl(result)) {
 			return result
 		}
 	}
 	return result
 }
 
+// evalSpawnExpression evaluates the function and its arguments, then runs the call on a task of its own.
+// The function and the arguments are copied along with their environments, so that the task shares no mutable values
+// with the code spawning it
+func evalSpawnExpression(node *ast.SpawnExpression, env *object.Environment) object.Object {
+	target, arguments := node.Value, []ast.Expression{}
+	if call, ok := node.Value.(*ast.CallExpression); ok {
+		target, arguments = call.Function, call.Arguments
+	}
+
+	fn := Eval(target, env)
+	if isAbrupt(fn) {
+		return fn
+	}
+	args := evalExpressions(arguments, env)
+	if len(args) == 1 && isAbrupt(args[0]) {
+		return args[0]
+	}
+
+	name := ""
+	if function, ok := fn.(*object.Function); ok {
+		name = function.Name
+	}
+
+	copier := object.NewCopier()
+	fn = copier.Copy(fn)
+	for i, arg := range args {
+		args[i] = copier.Copy(arg)
+	}
+
+	return object.NewTask(name, func() (object.Object, *object.Error) {
+		// the task starts a stack trace of its own
+		result := applyFunction(fn, args, object.NewEnvironment())
+		if err, ok := result.(*object.Error); ok {
+			return nil, err
+		}
+
+		if result == nil {
+			return NULL, nil
+		}
+		return result, nil
+	})
+}
+
 func evalExpressions(expressions []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
 	for _, e := range expressions {
 		evaluated := Eval(e, env)
-		if isError(evaluated) {
+		if isAbrupt(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -218,6 +527,8 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case token.MINUS:
 		return evalMinusOperatorExpression(right)
+	case token.TILDE:
+		return evalBitNotOperatorExpression(right)
 	default:
 		return newError("%s: %s%s", UNKNOWN_OPERATOR, operator, right.Type())
 	}
@@ -227,15 +538,21 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	lType := left.Type()
 	rType := right.Type()
 
-	if lType != rType {
-		return newError("%s: %s %s %s", TYPE_MISMATCH, left.Type(), operator, right.Type())
-	}
-
-	bothOperandsInts := lType == object.INTEGER_OBJ && rType == object.INTEGER_OBJ
+	bothOperandsInts := object.IsIntegral(left) && object.IsIntegral(right)
 	if bothOperandsInts {
 		return evalInfixIntExpression(operator, left, right)
 	}
 
+	// ints are widened to floats when mixed with them
+	bothOperandsNumeric := isNumeric(left) && isNumeric(right)
+	if bothOperandsNumeric {
+		return evalInfixFloatExpression(operator, left, right)
+	}
+
+	if lType != rType {
+		return newError("%s: %s %s %s", TYPE_MISMATCH, left.Type(), operator, right.Type())
+	}
+
 	bothOperandsStrings := lType == object.STRING_OBJ && rType == object.STRING_OBJ
 	if bothOperandsStrings {
 		return evalInfixStringExpression(operator, left, right)
@@ -250,31 +567,54 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 }
 
 func evalInfixIntExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
-
 	switch operator {
 	case token.PLUS:
-		return &object.Integer{Value: leftVal + rightVal}
+		return object.AddIntegers(left, right)
 	case token.MINUS:
-		return &object.Integer{Value: leftVal - rightVal}
+		return object.SubIntegers(left, right)
 	case token.SLASH:
-		return &object.Integer{Value: leftVal / rightVal}
+		return integerResultOrError(object.DivIntegers(left, right))
 	case token.ASTERISK:
-		return &object.Integer{Value: leftVal * rightVal}
+		return object.MulIntegers(left, right)
+	case token.PERCENT:
+		return integerResultOrError(object.ModIntegers(left, right))
+	case token.POWER:
+		return integerResultOrError(object.PowIntegers(left, right))
+	case token.BIT_AND:
+		return object.BitAndIntegers(left, right)
+	case token.BIT_OR:
+		return object.BitOrIntegers(left, right)
+	case token.BIT_XOR:
+		return object.BitXorIntegers(left, right)
+	case token.SHIFT_LEFT:
+		return integerResultOrError(object.ShiftLeftIntegers(left, right))
+	case token.SHIFT_RIGHT:
+		return integerResultOrError(object.ShiftRightIntegers(left, right))
 	case token.LT:
-		return hostToGuestBoolean(leftVal < rightVal)
+		return hostToGuestBoolean(object.CompareIntegers(left, right) < 0)
 	case token.GT:
-		return hostToGuestBoolean(leftVal > rightVal)
+		return hostToGuestBoolean(object.CompareIntegers(left, right) > 0)
+	case token.LT_EQ:
+		return hostToGuestBoolean(object.CompareIntegers(left, right) <= 0)
+	case token.GT_EQ:
+		return hostToGuestBoolean(object.CompareIntegers(left, right) >= 0)
 	case token.EQ:
-		return hostToGuestBoolean(leftVal == rightVal)
+		return hostToGuestBoolean(object.CompareIntegers(left, right) == 0)
 	case token.NOT_EQ:
-		return hostToGuestBoolean(leftVal != rightVal)
+		return hostToGuestBoolean(object.CompareIntegers(left, right) != 0)
 	default:
 		return newError("%s: %s %s %s", UNKNOWN_OPERATOR, left.Type(), operator, right.Type())
 	}
 }
 
+func integerResultOrError(result object.Object, err error) object.Object {
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	return result
+}
+
 func evalInfixStringExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
@@ -287,19 +627,23 @@ func evalInfixStringExpression(operator string, left, right object.Object) objec
 	}
 }
 
-func evalInfixBooleanExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Boolean).Value
-	rightVal := right.(*object.Boolean).Value
+// evalLogicalExpression evaluates the right operand of `&&` and `||` only when the left one doesn't decide the result.
+// The result is the value of the last evaluated operand
+func evalLogicalExpression(operator string, left object.Object, right ast.Expression, env *object.Environment) object.Object {
+	isDecided := isTruthy(left) == (operator == token.OR)
+	if isDecided {
+		return left
+	}
+
+	return Eval(right, env)
+}
 
+func evalInfixBooleanExpression(operator string, left, right object.Object) object.Object {
 	switch operator {
 	case token.EQ:
 		return hostToGuestBoolean(left == right)
 	case token.NOT_EQ:
 		return hostToGuestBoolean(left != right)
-	case token.AND:
-		return hostToGuestBoolean(leftVal && rightVal)
-	case token.OR:
-		return hostToGuestBoolean(leftVal || rightVal)
 	default:
 		return newError("%s: %s %s %s", UNKNOWN_OPERATOR, left.Type(), operator, right.Type())
 	}
@@ -318,19 +662,99 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+
+	for i, segment := range node.Segments {
+		out.WriteString(segment)
+
+		if i < len(node.Expressions) {
+			value := Eval(node.Expressions[i], env)
+			if isAbrupt(value) {
+				return value
+			}
+
+			out.WriteString(value.Inspect())
+		}
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+func evalInfixFloatExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case token.PLUS:
+		return &object.Float{Value: leftVal + rightVal}
+	case token.MINUS:
+		return &object.Float{Value: leftVal - rightVal}
+	case token.SLASH:
+		return &object.Float{Value: leftVal / rightVal}
+	case token.ASTERISK:
+		return &object.Float{Value: leftVal * rightVal}
+	case token.PERCENT:
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case token.POWER:
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+	case token.LT:
+		return hostToGuestBoolean(leftVal < rightVal)
+	case token.GT:
+		return hostToGuestBoolean(leftVal > rightVal)
+	case token.LT_EQ:
+		return hostToGuestBoolean(leftVal <= rightVal)
+	case token.GT_EQ:
+		return hostToGuestBoolean(leftVal >= rightVal)
+	case token.EQ:
+		return hostToGuestBoolean(leftVal == rightVal)
+	case token.NOT_EQ:
+		return hostToGuestBoolean(leftVal != rightVal)
+	default:
+		return newError("%s: %s %s %s", UNKNOWN_OPERATOR, left.Type(), operator, right.Type())
+	}
+}
+
+func isNumeric(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInteger, *object.Float:
+		return true
+	default:
+		return false
+	}
+}
+
+func toFloat(obj object.Object) float64 {
+	if float, ok := obj.(*object.Float); ok {
+		return float.Value
+	}
+
+	return object.IntegerToFloat(obj)
+}
+
 func evalMinusOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer, *object.BigInteger:
+		return object.NegateInteger(right)
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("%s: -%s", UNKNOWN_OPERATOR, right.Type())
 	}
+}
+
+func evalBitNotOperatorExpression(right object.Object) object.Object {
+	if !object.IsIntegral(right) {
+		return newError("%s: ~%s", UNKNOWN_OPERATOR, right.Type())
+	}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return object.BitNotInteger(right)
 }
 
 func evalIfExpression(condition ast.Expression, consequence, alternative *ast.BlockStatement, env *object.Environment) object.Object {
 	conditionResult := Eval(condition, env)
 
-	if isError(conditionResult) {
+	if isAbrupt(conditionResult) {
 		return conditionResult
 	}
 	if isTruthy(conditionResult) {
@@ -342,8 +766,158 @@ func evalIfExpression(condition ast.Expression, consequence, alternative *ast.Bl
 	return NULL
 }
 
+func evalTryStatement(node *ast.TryStatement, env *object.Environment) object.Object {
+	if node.CatchParam != nil {
+		if err := checkRedeclaration(node.CatchParam.Value, env); err != nil {
+			return err
+		}
+	}
+
+	result := Eval(node.Block, env)
+
+	if err, ok := result.(*object.Error); ok && node.Catch != nil {
+		if node.CatchParam != nil {
+			env.Put(node.CatchParam.Value, caughtValue(err))
+		}
+
+		result = Eval(node.Catch, env)
+	}
+
+	// finally leaving the statement early overrides the way the rest of the statement is left
+	if node.Finally != nil {
+		finallyResult := Eval(node.Finally, env)
+		if leavesBlock(finallyResult) {
+			return finallyResult
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+
+	return result
+}
+
+// leavesBlock tells whether the result of a statement stops the execution of the enclosing block
+func leavesBlock(result object.Object) bool {
+	switch result.(type) {
+	case *object.ReturnWrapper, *object.Error, *object.Break, *object.Continue:
+		return true
+	default:
+		return false
+	}
+}
+
+func newThrownError(value object.Object) *object.Error {
+	if exception, ok := value.(*object.Exception); ok {
+		return &object.Error{Message: exception.Message, Value: exception, StackTrace: exception.StackTrace}
+	}
+
+	return &object.Error{Message: fmt.Sprintf("%s: %s", UNCAUGHT_EXCEPTION, value.Inspect()), Value: value}
+}
+
+// caughtValue is what `catch` binds: the thrown value, or the exception made from the runtime error
+func caughtValue(err *object.Error) object.Object {
+	if err.Value != nil {
+		return err.Value
+	}
+
+	return &object.Exception{Message: err.Message, StackTrace: err.StackTrace}
+}
+
+// withStackTrace records the functions being called at the moment the error reached a block,
+// the first block it reaches is the one it happened in, or the one the value was thrown in
+func withStackTrace(err *object.Error, env *object.Environment) *object.Error {
+	if err.StackTrace == nil {
+		err.StackTrace = env.StackTrace()
+	}
+
+	return err
+}
+
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		result := Eval(node.Body, env)
+
+		switch result.(type) {
+		case *object.ReturnWrapper, *object.Error:
+			return result
+		case *object.Break:
+			return nil
+		}
+	}
+}
+
+func evalForInStatement(node *ast.ForInStatement, env *object.Environment) object.Object {
+	for _, binding := range []*ast.Identifier{node.Key, node.Value} {
+		if binding == nil {
+			continue
+		}
+		if err := checkRedeclaration(binding.Value, env); err != nil {
+			return err
+		}
+	}
+
+	iterable := Eval(node.Iterable, env)
+	if isAbrupt(iterable) {
+		return iterable
+	}
+
+	iterator, ok := object.NewIterator(iterable)
+	if !ok {
+		return newError("%s: %s", NOT_ITERABLE, iterable.Type())
+	}
+
+	for {
+		key, value, ok := iterator.Next()
+		if !ok {
+			if fallible, isFallible := iterator.(object.FallibleIterator); isFallible && fallible.Err() != nil {
+				return fallible.Err()
+			}
+			return nil
+		}
+
+		if node.Key != nil {
+			env.Put(node.Key.Value, key)
+			env.Put(node.Value.Value, value)
+		} else {
+			env.Put(node.Value.Value, object.SingleBinding(iterator, key, value))
+		}
+
+		result := Eval(node.Body, env)
+
+		switch result.(type) {
+		case *object.ReturnWrapper, *object.Error:
+			return result
+		case *object.Break:
+			return nil
+		}
+	}
+}
+
 func evalIdentifier(identifier string, env *object.Environment) object.Object {
+	value := evalIdentifierValue(identifier, env)
+	if value.Type() == object.MODULE_OBJ {
+		return newError("%s: %s", MODULE_USED_AS_VALUE, identifier)
+	}
+
+	return value
+}
+
+func evalIdentifierValue(identifier string, env *object.Environment) object.Object {
 	if envFunc, ok := env.Get(identifier); ok {
+		if envFunc == UNBOUND_PARAM {
+			return newError("%s: %s", DEFAULT_REFERS_TO_LATER_PARAM, identifier)
+		}
 		return envFunc
 	}
 
@@ -354,28 +928,156 @@ func evalIdentifier(identifier string, env *object.Environment) object.Object {
 	return newError("%s: %s", IDENTIFIER_NOT_FOUND, identifier)
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func evalAssignment(identifier string, val object.Object, env *object.Environment) *object.Error {
+	if current, ok := env.Get(identifier); ok && current.Type() == object.MODULE_OBJ {
+		return newError("%s: %s", CANNOT_ASSIGN_TO_MODULE, identifier)
+	}
+
+	if env.IsConst(identifier) {
+		return newError("%s: %s", CANNOT_ASSIGN_TO_CONSTANT, identifier)
+	}
+
+	if _, ok := env.Set(identifier, val); ok {
+		return nil
+	}
+
+	if _, ok := stdlib.FuncsMap[identifier]; ok {
+		return newError("%s: %s", CANNOT_ASSIGN_TO_BUILT_IN, identifier)
+	}
+
+	return newError("%s: %s", IDENTIFIER_NOT_FOUND, identifier)
+}
+
+// checkRedeclaration rejects bindings which would shadow a const declared in the same environment
+func checkRedeclaration(identifier string, env *object.Environment) *object.Error {
+	if env.HasConst(identifier) {
+		return newError("%s: %s", CANNOT_REDECLARE_CONSTANT, identifier)
+	}
+
+	return nil
+}
+
+func applyFunction(fn object.Object, args []object.Object, callerEnv *object.Environment) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		bodyEvalRes := Eval(fn.Body, extendFuncEnv(fn, args))
-		return unwrapReturnWrapper(bodyEvalRes)
+		if !fn.Arity.Accepts(len(args)) {
+			return newError("%s", object.WrongArgumentsCountMessage(fn.Name, fn.Arity, len(args)))
+		}
+
+		if fn.IsGenerator {
+			return newGenerator(fn, args, callerEnv)
+		}
+
+		funcEnv := object.NewCallEnv(fn.Env, callerEnv, fn.Name)
+		if err := bindArguments(fn, args, funcEnv); err != nil {
+			return err
+		}
+
+		return evalFunctionBody(fn, funcEnv)
 
 	case *object.BuiltInFunction:
+		if !fn.Arity.Accepts(len(args)) {
+			return newError("%s", object.WrongArgumentsCountMessage(fn.Name, fn.Arity, len(args)))
+		}
+
 		return fn.Fn(args...)
 
+	case *object.StructType:
+		if !fn.Arity().Accepts(len(args)) {
+			return newError("%s", object.WrongArgumentsCountMessage(fn.Name, fn.Arity(), len(args)))
+		}
+
+		return fn.New(args)
+
 	default:
 		return newError("%s %s", NOT_A_FUNCTION, fn.Type())
 	}
 }
 
-func extendFuncEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnv(fn.Env)
+// applyMethod passes the receiver as the first argument to the methods taking `self`
+func applyMethod(method, receiver object.Object, args []object.Object, callerEnv *object.Environment) object.Object {
+	if object.TakesSelf(method) {
+		args = append([]object.Object{receiver}, args...)
+	}
+
+	return applyFunction(method, args, callerEnv)
+}
+
+func evalFunctionBody(fn *object.Function, env *object.Environment) object.Object {
+	bodyEvalRes := Eval(fn.Body, env)
+	if isLoopControl(bodyEvalRes) {
+		return newError("%s: %s", OUTSIDE_OF_LOOP, bodyEvalRes.Inspect())
+	}
+	return unwrapReturnWrapper(bodyEvalRes)
+}
+
+// newGenerator runs the body of the generator function as a coroutine, up to the next yield on every resumption.
+// As in the VM, the arguments are bound once the generator is resumed for the first time
+func newGenerator(fn *object.Function, args []object.Object, callerEnv *object.Environment) object.Object {
+	env := object.NewGeneratorEnv(fn.Env, callerEnv, fn.Name, func(env *object.Environment) *object.Error {
+		if err := bindArguments(fn, args, env); err != nil {
+			return err
+		}
+
+		err, _ := evalFunctionBody(fn, env).(*object.Error)
+		return err
+	})
+
+	return object.NewCoroutineGenerator(fn.Name, env.Coroutine())
+}
+
+func evalYieldStatement(node *ast.YieldStatement, env *object.Environment) object.Object {
+	coroutine := env.Coroutine()
+	if coroutine == nil {
+		return newError("%s: %s", OUTSIDE_OF_GENERATOR, node.TokenLiteral())
+	}
+
+	var value object.Object = NULL
+	if node.Value != nil {
+		value = Eval(node.Value, env)
+		if isAbrupt(value) {
+			return value
+		}
+	}
+
+	coroutine.Yield(value)
+
+	return nil
+}
+
+// bindArguments binds the arguments to the parameters, default values are evaluated in the function environment,
+// so they can refer to the preceding parameters. The following parameters shadow the enclosing bindings
+// of the same name meanwhile, referring to them is an error
+func bindArguments(fn *object.Function, args []object.Object, env *object.Environment) *object.Error {
+	for _, paramData := range fn.Parameters[min(len(args), len(fn.Parameters)):] {
+		env.Put(paramData.Value, UNBOUND_PARAM)
+	}
+	if fn.Rest != nil {
+		env.Put(fn.Rest.Value, UNBOUND_PARAM)
+	}
 
 	for paramId, paramData := range fn.Parameters {
-		env.Put(paramData.Value, args[paramId])
+		if paramId < len(args) {
+			env.Put(paramData.Value, args[paramId])
+			continue
+		}
+
+		defaultValue := Eval(fn.Defaults[paramId], env)
+		if err, ok := defaultValue.(*object.Error); ok {
+			return err
+		}
+		env.Put(paramData.Value, defaultValue)
 	}
 
-	return env
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Put(fn.Rest.Value, &object.Array{Elements: rest})
+	}
+
+	return nil
 }
 
 func unwrapReturnWrapper(obj object.Object) object.Object {
@@ -395,28 +1097,302 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	case left.Type() == object.HASH_MAP_OBJ:
 		return evalHashMapIndexExpression(left, index)
 
+	case left.Type() == object.EXCEPTION_OBJ:
+		field, ok := left.(*object.Exception).Field(index)
+		if !ok {
+			return NULL
+		}
+		return field
+
 	default:
 		return newError("%s %s", INDEX_OPERATOR_NOT_SUPPORTED, left.Type())
 	}
 }
 
-func evalArrayIndexExpression(array, index object.Object) object.Object {
-	arrayObject := array.(*object.Array)
-	guestIndex := index.(*object.Integer).Value
+// evalPattern binds the parts of value to the pattern identifiers, the same way the compiled pattern does
+func evalPattern(pattern ast.Pattern, value object.Object, env *object.Environment) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if err := checkRedeclaration(pattern.Value, env); err != nil {
+			return err
+		}
+		env.Put(pattern.Value, value)
+
+	case *ast.ArrayPattern:
+		for i, element := range pattern.Elements {
+			elementValue := evalIndexExpression(value, &object.Integer{Value: int64(i)})
+			if err, ok := elementValue.(*object.Error); ok {
+				return err
+			}
+			if err := evalPattern(element, elementValue, env); err != nil {
+				return err
+			}
+		}
+
+		if pattern.Rest != nil {
+			start := &object.Integer{Value: int64(len(pattern.Elements))}
+			rest := evalSliceExpression(value, start, NULL)
+			if err, ok := rest.(*object.Error); ok {
+				return err
+			}
+			env.Put(pattern.Rest.Value, rest)
+		}
+
+	case *ast.HashMapPattern:
+		for i, key := range pattern.Keys {
+			elementValue := evalIndexExpression(value, Eval(key, env))
+			if err, ok := elementValue.(*object.Error); ok {
+				return err
+			}
+			if err := evalPattern(pattern.Values[i], elementValue, env); err != nil {
+				return err
+			}
+		}
+	}
 
-	maxIndex := int64(len(arrayObject.Elements)) - 1
-	if guestIndex > maxIndex {
+	return nil
+}
+
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(node.Subject, env)
+	if isAbrupt(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		if !patternMatches(arm.Pattern, subject, env) {
+			continue
+		}
+
+		if err := evalPattern(arm.Pattern, subject, env); err != nil {
+			return err
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, env)
+			if isAbrupt(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, env)
+	}
+
+	return NULL
+}
+
+// patternMatches tells whether the value has the shape of the pattern, without binding anything
+func patternMatches(pattern ast.Pattern, value object.Object, env *object.Environment) bool {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier, *ast.WildcardPattern:
+		return true
+
+	case *ast.LiteralPattern:
+		return object.SamePatternKey(value, Eval(pattern.Value, env))
+
+	case *ast.ArrayPattern:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return false
+		}
+
+		length := len(array.Elements)
+		if length != len(pattern.Elements) && (pattern.Rest == nil || length < len(pattern.Elements)) {
+			return false
+		}
+
+		for i, element := range pattern.Elements {
+			if !patternMatches(element, array.Elements[i], env) {
+				return false
+			}
+		}
+
+		return true
+
+	case *ast.HashMapPattern:
+		hashMap, ok := value.(*object.HashMap)
+		if !ok {
+			return false
+		}
+
+		for i, key := range pattern.Keys {
+			hashableKey, ok := Eval(key, env).(object.Hashable)
+			if !ok {
+				return false
+			}
+
+			pair, ok := hashMap.Pairs[hashableKey.HashKey()]
+			if !ok || !patternMatches(pattern.Values[i], pair.Value, env) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func evalSliceExpression(left, start, end object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		startIdx, endIdx, err := evalSliceBounds(start, end, int64(len(left.Elements)))
+		if err != nil {
+			return err
+		}
+
+		elements := make([]object.Object, endIdx-startIdx)
+		copy(elements, left.Elements[startIdx:endIdx])
+
+		return &object.Array{Elements: elements}
+
+	case *object.String:
+		chars := []rune(left.Value)
+
+		startIdx, endIdx, err := evalSliceBounds(start, end, int64(len(chars)))
+		if err != nil {
+			return err
+		}
+
+		return &object.String{Value: string(chars[startIdx:endIdx])}
+
+	default:
+		return newError("%s: %s", SLICE_OPERATOR_NOT_SUPPORTED, left.Type())
+	}
+}
+
+// evalSliceBoundExpression evaluates the bound of a slice, omitted bound is null
+func evalSliceBoundExpression(bound ast.Expression, env *object.Environment) object.Object {
+	if bound == nil {
 		return NULL
 	}
 
-	var hostIndex int64
-	if guestIndex >= 0 {
-		hostIndex = guestIndex
-	} else {
-		hostIndex = int64(len(arrayObject.Elements)) + int64(guestIndex)
+	return Eval(bound, env)
+}
+
+// evalSliceBounds resolves the bounds of a slice of the collection of the given length,
+// the end never precedes the start
+func evalSliceBounds(start, end object.Object, length int64) (int64, int64, *object.Error) {
+	startIdx, err := evalSliceBound(start, 0, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	endIdx, err := evalSliceBound(end, length, length)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return min(startIdx, endIdx), endIdx, nil
+}
+
+// evalSliceBound clamps the bound of a slice into [0, length], negative bound counts from the end,
+// null bound falls back to fallback
+func evalSliceBound(bound object.Object, fallback, length int64) (int64, *object.Error) {
+	if bound == NULL {
+		return fallback, nil
+	}
+
+	integer, ok := bound.(*object.Integer)
+	if !ok {
+		return 0, newError("%s, got %s", SLICE_BOUND_MUST_BE_INTEGER, bound.Type())
+	}
+
+	index := integer.Value
+	if index < 0 {
+		index += length
+	}
+
+	return min(max(index, 0), length), nil
+}
+
+func evalIndexAssignment(node *ast.IndexAssignStatement, env *object.Environment) object.Object {
+	left := Eval(node.Target.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	index := Eval(node.Target.Index, env)
+	if isAbrupt(index) {
+		return index
+	}
+
+	value := Eval(node.Value, env)
+	if isAbrupt(value) {
+		return value
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("%s, got %s", ARRAY_INDEX_MUST_BE_INTEGER, index.Type())
+		}
+
+		position, ok := left.Position(idx.Value)
+		if !ok {
+			return newError("%s: %d, length is %d", INDEX_OUT_OF_BOUNDS, idx.Value, len(left.Elements))
+		}
+
+		left.Elements[position] = value
+
+	case *object.HashMap:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("%s %s", KEY_IS_NOT_HASHABLE, index.Type())
+		}
+
+		left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: value}
+
+	default:
+		return newError("%s: %s", INDEX_ASSIGNMENT_NOT_SUPPORTED, left.Type())
+	}
+
+	return nil
+}
+
+func evalMemberAssignment(node *ast.MemberAssignStatement, env *object.Environment) object.Object {
+	left := evalMemberObject(node.Target, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	value := Eval(node.Value, env)
+	if isAbrupt(value) {
+		return value
+	}
+
+	switch left := left.(type) {
+	case *object.Module:
+		return newError("%s: %s", CANNOT_ASSIGN_TO_MODULE, node.Target)
+
+	case *object.Struct:
+		if !left.SetField(node.Target.Member.Value, value) {
+			return newError("%s: %s.%s", NO_SUCH_FIELD, left.StructType.Name, node.Target.Member.Value)
+		}
+
+	case *object.HashMap:
+		key := &object.String{Value: node.Target.Member.Value}
+		left.Pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+
+	default:
+		return newError("%s: %s", MEMBER_ACCESS_NOT_SUPPORTED, left.Type())
+	}
+
+	return nil
+}
+
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	arrayObject := array.(*object.Array)
+
+	position, ok := arrayObject.Position(index.(*object.Integer).Value)
+	if !ok {
+		return NULL
 	}
 
-	return arrayObject.Elements[hostIndex]
+	return arrayObject.Elements[position]
 }
 
 func evalHashMap(node *ast.HashMapLiteral, env *object.Environment) object.Object {
@@ -424,7 +1400,7 @@ func evalHashMap(node *ast.HashMapLiteral, env *object.Environment) object.Objec
 
 	for keyNode, valueNode := range node.Pairs {
 		key := Eval(keyNode, env)
-		if isError(key) {
+		if isAbrupt(key) {
 			return key
 		}
 
@@ -434,7 +1410,7 @@ func evalHashMap(node *ast.HashMapLiteral, env *object.Environment) object.Objec
 		}
 
 		value := Eval(valueNode, env)
-		if isError(value) {
+		if isAbrupt(value) {
 			return value
 		}
 
@@ -2,9 +2,14 @@ package evaluator
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/vdchnsk/qrk/src/lexer"
+	"github.com/vdchnsk/qrk/src/module"
 	"github.com/vdchnsk/qrk/src/object"
 	"github.com/vdchnsk/qrk/src/parser"
 )
@@ -52,6 +57,25 @@ func testIntegerObject(t *testing.T, obj object.Object, expectedValue int64) boo
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.Object, expectedValue float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf(
+			"object is not Float. got=%T (%+v)",
+			obj, obj,
+		)
+		return false
+	}
+	if result.Value != expectedValue {
+		t.Errorf(
+			"float != expected value. got=%g, expected=%g",
+			result.Value, expectedValue,
+		)
+		return false
+	}
+	return true
+}
+
 func testStringObject(t *testing.T, obj object.Object, expectedValue string) bool {
 	result, ok := obj.(*object.String)
 	if !ok {
@@ -189,6 +213,165 @@ func TestEvalInfixExpression(t *testing.T) {
 	}
 }
 
+func TestEvalIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775808 - 1", "-9223372036854775809"},
+		{"4611686018427387904 * 4", "18446744073709551616"},
+		{"-(-9223372036854775808)", "9223372036854775808"},
+		{"99999999999999999999 + 1", "100000000000000000000"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		result, ok := evaluated.(*object.BigInteger)
+		if !ok {
+			t.Errorf("object is not BigInteger. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if result.Value.String() != tt.expected {
+			t.Errorf("big integer != expected value. got=%s, expected=%s", result.Value, tt.expected)
+		}
+	}
+}
+
+func TestEvalBigIntegerDemotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"9223372036854775807 + 1 - 1", 9223372036854775807},
+		{"99999999999999999999 - 99999999999999999998", 1},
+		{"-9223372036854775808", -9223372036854775808},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestEvalLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 && 2", int64(2)},
+		{`"a" || "b"`, "a"},
+		{"false || 5", int64(5)},
+		{"false && 5", false},
+		{"true || false && false", true},
+		{"(true || false) && false", false},
+		{"false && undefinedIdentifier", false},
+		{"true || undefinedIdentifier", true},
+		{
+			`let calls = 0;
+			let touch = fn() { calls = calls + 1; true };
+			false && touch();
+			true || touch();
+			true && touch();
+			false || touch();
+			calls`,
+			int64(2),
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		case bool:
+			testBoooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestEvalExtendedOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"7 % 3", int64(1)},
+		{"-7 % 3", int64(-1)},
+		{"7.5 % 2", 1.5},
+		{"2 ** 10", int64(1024)},
+		{"2 ** 3 ** 2", int64(512)},
+		{"-2 ** 2", int64(-4)},
+		{"2 ** -1", 0.5},
+		{"1 << 100 >> 100", int64(1)},
+		{"-16 >> 2", int64(-4)},
+		{"6 & 3", int64(2)},
+		{"6 | 3", int64(7)},
+		{"6 ^ 3", int64(5)},
+		{"~5", int64(-6)},
+		{"1 | 2 ^ 3 & 4", int64(3)},
+		{"1 + 2 << 1", int64(6)},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 2", false},
+		{"2.5 >= 2", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case bool:
+			testBoooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5", 1.5},
+		{"1e3", 1000},
+		{"-2.5", -2.5},
+		{"1.5 + 2.25", 3.75},
+		{"1.5 + 2", 3.5},
+		{"2 - 0.5", 1.5},
+		{"3 / 2.0", 1.5},
+		{"(1 + 0.5) * 2", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestEvalMixedNumericComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1.5 > 1", true},
+		{"1 < 1.5", true},
+		{"2 == 2.0", true},
+		{"2.0 != 2", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBoooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestEvalIfExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -318,6 +501,310 @@ func TestLetStatement(t *testing.T) {
 	}
 }
 
+func TestConstStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"const A = 4; A", 4},
+		{"const A = 4; fn f() { A * 2 } f()", 8},
+		{"const A = 4; fn f() { let A = 1; A = A + 1; A } f()", 2},
+		{"const A = [1]; A[0] = 2; A[0]", 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestAssignStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 4; a = 5; a", 5},
+		{"let a = 4; a = a + 1; a", 5},
+		{"let a = 4; let change = fn() { a = 10; }; change(); a", 10},
+		{`
+		let new_counter = fn() {
+			let count = 0;
+			fn() { count = count + 1; count };
+		};
+		let counter = new_counter();
+		counter();
+		counter();
+		counter();
+		`, 3},
+		{"let f = fn() { let c = 0; let inc = fn() { c = c + 1 }; inc(); inc(); c }; f()", 2},
+		{"let f = fn() { let i = 0; let g = fn() { i }; i = 5; g() }; f()", 5},
+		{"let f = fn() { let x = 1; let mid = fn() { fn() { x = x + 10 } }; mid()(); x }; f()", 11},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestIndexAssignStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = [1, 2, 3]; a[0] = 10; a[0]", 10},
+		{"let a = [1, 2, 3]; let b = a; b[2] = 30; a[2]", 30},
+		{"let grid = [[0, 0], [0, 0]]; grid[1][0] = 5; grid[1][0]", 5},
+		{`let m = {"age": 10}; m["age"] = m["age"] + 1; m["age"]`, 11},
+		{`let m = {}; m[true] = 1; m[true]`, 1},
+		{"let a = [0]; let inc = fn(arr) { arr[0] = arr[0] + 1; }; inc(a); inc(a); a[0]", 2},
+		{"let a = [1, 2, 3]; a[-1] = 7; a[2]", 7},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestLetDestructuringStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a + b", 3},
+		{"let [head, ...tail] = [1, 2, 3]; tail[1]", 3},
+		{"let [a, b, ...rest] = [1, 2]; rest[0]", nil},
+		{"let [a, b] = [1]; b", nil},
+		{"let [x, [y, z]] = [1, [2, 3]]; x + y + z", 6},
+		{`let {name, age} = {"name": "Ann", "age": 30}; age`, 30},
+		{`let {missing} = {}; missing`, nil},
+		{`let [first, {age}] = [1, {"age": 30}]; first + age`, 31},
+		{"let a = [1, 2]; let [x, ...rest] = a; rest[0] = 5; a[1]", 2},
+		{"let x = 1; let [x, y] = [x + 1, x + 2]; x + y", 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		if expected, ok := tt.expected.(int); ok {
+			testIntegerObject(t, evaluated, int64(expected))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	describe := `
+		let describe = fn(v) {
+			match v {
+				0 => "zero",
+				-1 => "minus one",
+				"x" => "ex",
+				true => "yes",
+				[] => "empty",
+				[a, b] if a == b => "same",
+				[a, b] => "pair",
+				[1, ...rest] => "rest",
+				{"type": "circle", "r": r} => "circle",
+				{"type": t} => t,
+				_ => "other",
+			}
+		};
+	`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{describe + "describe(0)", "zero"},
+		{describe + "describe(-1)", "minus one"},
+		{describe + `describe("x")`, "ex"},
+		{describe + "describe(true)", "yes"},
+		{describe + "describe([])", "empty"},
+		{describe + "describe([3, 3])", "same"},
+		{describe + "describe([3, 4])", "pair"},
+		{describe + "describe([1, 2, 3])", "rest"},
+		{describe + "describe([2, 2, 3])", "other"},
+		{describe + `describe({"type": "circle", "r": 2})`, "circle"},
+		{describe + `describe({"type": "square"})`, "square"},
+		{describe + `describe({"kind": "square"})`, "other"},
+		{describe + "describe(0.0)", "zero"},
+		{describe + "describe(-0.0)", "zero"},
+		{describe + "describe(-1.0)", "minus one"},
+		{describe + "describe(0.5)", "other"},
+		{`match 1 { 1.0 => "float", 1 => "int" }`, "float"},
+		{`match 2.0 { 1 => "one", 2 => "two" }`, "two"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, testEval(tt.input), tt.expected)
+	}
+
+	integerTests := []struct {
+		input    string
+		expected int64
+	}{
+		{"match 5 { 1 => 10, n => n * 2 }", 10},
+		{"match 1 { 1 => 10, 1 => 20 }", 10},
+		{"match 2 { n if n > 5 => 1, n if n > 1 => 2, _ => 3 }", 2},
+		{"match [1, [2, 3]] { [x, [y, z]] => { let sum = x + y; sum + z } }", 6},
+		{"let f = fn(x) { match x { 1 => { return 10; }, _ => 20 } }; f(1) + f(2)", 30},
+	}
+
+	for _, tt := range integerTests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	testNullObject(t, testEval("match 5 { 1 => 10 }"))
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let r = ""; try { 1 + "a"; } catch (e) { r = e["message"]; }; r`, fmt.Sprintf("%s: INTEGER + STRING", TYPE_MISMATCH)},
+		{`let r = ""; try { range(0, 1, 0); } catch (e) { r = e["message"]; }; r`, "step of `range` must not be zero"},
+		{
+			`fn inner() { 1 + "a" }
+			fn outer() { inner() }
+			let r = "";
+			try { outer(); } catch (e) { r = e["stack"][0] + " " + e["stack"][1] + " " + e["stack"][2]; };
+			r`,
+			"inner outer <main>",
+		},
+		{`fn call(f) { f() } let r = ""; try { call(fn() { 1 + "a" }); } catch (e) { r = e["stack"][0] + " " + e["stack"][1]; }; r`, "<anonymous> call"},
+		{`let r = ""; try { 1 + "a"; } catch (e) { r = e.message; }; r`, fmt.Sprintf("%s: INTEGER + STRING", TYPE_MISMATCH)},
+		{`fn f() { 1 / 0 } let r = ""; try { f(); } catch (e) { r = e.stack[0] + " " + e.stack[1]; }; r`, "f <main>"},
+		{`let r = ""; try { r = "t"; } finally { r = r + "f"; }; r`, "tf"},
+		{`let r = ""; try { try { throw "a"; } finally { r = "f"; } } catch (e) { r = r + e; }; r`, "fa"},
+		{
+			`let r = "";
+			try {
+				try { throw "a"; } catch (e) { throw e + "b"; } finally { r = r + "f"; }
+			} catch (e) { r = r + e; };
+			r`,
+			"fab",
+		},
+		{`let log = ""; fn f() { try { log = log + "t"; return 1; } finally { log = log + "f"; } } f(); log`, "tf"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, testEval(tt.input), tt.expected)
+	}
+
+	integerTests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let r = 0; try { throw 5; } catch (e) { r = e; }; r", 5},
+		{"let r = 0; try { throw 1; } catch { r = 2; }; r", 2},
+		{"let r = 0; try { r = 1; } catch (e) { r = 2; }; r", 1},
+		{"fn boom() { throw 3 } let r = 0; try { r = 1 + boom(); } catch (e) { r = e; }; r + 1", 4},
+		{"fn f() { try { return 1; } finally { 2; } } f()", 1},
+		{"fn f() { try { return 1; } finally { return 2; } } f()", 2},
+		{"fn f() { try { throw 1; } catch (e) { return e + 1; } } f()", 2},
+		{`
+		let total = 0;
+		let i = 0;
+		while i < 5 {
+			i = i + 1;
+			try { if (i == 2) { continue; } if (i == 4) { break; } total = total + i; } finally { total = total + 10; }
+		}
+		total`, 44},
+		{"let r = 0; for x in [1, 2, 3] { try { throw x; } catch (e) { r = r + e; } } r", 6},
+		{"fn() { try { 1; } catch (e) { 2; } }()", 1},
+		{"fn() { try { throw 1; } catch (e) { 2; } }()", 2},
+		{"fn() { try { 1; } finally { 2; } }()", 1},
+		{"if true { try { throw 1; } catch { 3; } }", 3},
+		{`
+		let r = 0;
+		try {
+			try { throw 1; } finally {
+				try { try { throw 2; } finally { r = 10; } } catch (e) { r = r + e; }
+			}
+		} catch (e) { r = r * 10 + e; };
+		r`, 121},
+	}
+
+	for _, tt := range integerTests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	testNullObject(t, testEval(`let r = 0; try { 1 + "a"; } catch (e) { r = e["missing"]; }; r`))
+	testNullObject(t, testEval(`let r = 0; try { 1 + "a"; } catch (e) { r = e.missing; }; r`))
+	testNullObject(t, testEval(`fn() { try { let a = 1; } catch (e) { 2; } }()`))
+	testStringObject(t, testEval(`try { throw "boom"; } catch (e) { e; }`), "boom")
+}
+
+func TestWhileStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; while i < 10 { i = i + 1; } i", 10},
+		{"let i = 0; while false { i = i + 1; } i", 0},
+		{"let i = 0; while true { i = i + 1; if i == 5 { break; } } i", 5},
+		{`
+		let i = 0;
+		let sum = 0;
+		while i < 10 {
+			i = i + 1;
+			if i == 3 { continue; }
+			sum = sum + i;
+		}
+		sum;
+		`, 52},
+		{`
+		let find = fn(limit) {
+			let i = 0;
+			while true {
+				if i * i > limit { return i; }
+				i = i + 1;
+			}
+		};
+		find(50);
+		`, 8},
+		{"let i = 0; let s = 0; while i < 3 { i = i + 1; s = s + if true { continue } else { i } } s", 0},
+		{"let i = 0; let m = {}; while i < 4 { i = i + 1; m[if i % 2 == 0 { continue } else { i }] = i; } m[3]", 3},
+		{"let i = 0; while true { i = i + 1; let x = [i, if i == 3 { break } else { i }]; } i", 3},
+		{"let f = fn() { let x = 1 + if true { return 5 } else { 1 }; 10 }; f()", 5},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestForInStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for x in [1, 2, 3] { sum = sum + x; } sum", 6},
+		{"let sum = 0; for i, x in [10, 20, 30] { sum = sum + i; } sum", 3},
+		{`let keys = ""; for k in {"b": 1, "a": 2} { keys = keys + k; } keys`, "ab"},
+		{`let sum = 0; for k, v in {"b": 1, "a": 2} { sum = sum + v; } sum`, 3},
+		{`let out = ""; for c in "abc" { out = c + out; } out`, "cba"},
+		{"let sum = 0; for n in range(0, 5, 1) { sum = sum + n; } sum", 10},
+		{"let sum = 0; for n in range(10, 0, -3) { sum = sum + n; } sum", 22},
+		{"let sum = 0; for n in range(0, 10, 1) { if n == 2 { continue; } if n == 5 { break; } sum = sum + n; } sum", 8},
+		{"let first = fn(arr) { for x in arr { return x; } }; first([7, 8])", 7},
+		{"let sum = 0; for x in [1, 2, 3] { sum = sum + if x == 2 { continue } else { x } } sum", 4},
+		{"let sum = 0; for x in [1, 2, 3] { sum = sum + if x == 2 { break } else { x } } sum", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -325,6 +812,64 @@ func TestErrorHandling(t *testing.T) {
 	}{
 		{"foobar", fmt.Sprintf("%s: foobar", IDENTIFIER_NOT_FOUND)},
 		{`"str"-"str"`, fmt.Sprintf("%s: STRING - STRING", UNKNOWN_OPERATOR)},
+		{"foobar = 1", fmt.Sprintf("%s: foobar", IDENTIFIER_NOT_FOUND)},
+		{"len = 1", fmt.Sprintf("%s: len", CANNOT_ASSIGN_TO_BUILT_IN)},
+		{"const a = 1; a = 2", fmt.Sprintf("%s: a", CANNOT_ASSIGN_TO_CONSTANT)},
+		{"const a = 1; fn() { a = 2; }()", fmt.Sprintf("%s: a", CANNOT_ASSIGN_TO_CONSTANT)},
+		{"const a = 1; let a = 2;", fmt.Sprintf("%s: a", CANNOT_REDECLARE_CONSTANT)},
+		{"const a = 1; fn a() { }", fmt.Sprintf("%s: a", CANNOT_REDECLARE_CONSTANT)},
+		{"const a = 1; let [a] = [2];", fmt.Sprintf("%s: a", CANNOT_REDECLARE_CONSTANT)},
+		{"const a = 1; for a in [5, 6] {}", fmt.Sprintf("%s: a", CANNOT_REDECLARE_CONSTANT)},
+		{"const a = 1; for i, a in [5, 6] {}", fmt.Sprintf("%s: a", CANNOT_REDECLARE_CONSTANT)},
+		{"const a = 1; try { throw 5; } catch (a) {}", fmt.Sprintf("%s: a", CANNOT_REDECLARE_CONSTANT)},
+		{"break;", fmt.Sprintf("%s: break", OUTSIDE_OF_LOOP)},
+		{"for x in 1 { }", fmt.Sprintf("%s: INTEGER", NOT_ITERABLE)},
+		{"while true { fn() { continue; }(); }", fmt.Sprintf("%s: continue", OUTSIDE_OF_LOOP)},
+		{"yield 1;", fmt.Sprintf("%s: yield", OUTSIDE_OF_GENERATOR)},
+		{"next(fn*() { let f = fn() { yield 1; }; f(); }())", fmt.Sprintf("%s: yield", OUTSIDE_OF_GENERATOR)},
+		{"fn* f() { yield 1; } next(f()); next(1)", "argument to `next` must be GENERATOR, got INTEGER"},
+		{"fn* f() { yield 1 / 0; } for x in f() { }", "division by zero"},
+		{"join(spawn fn() { 1 / 0 })", "division by zero"},
+		{"join(spawn fn(x) { x }(1, 2))", "wrong number of arguments passed to <anonymous>, expected=1, got=2"},
+		{"join(1)", "argument to `join` must be TASK, got INTEGER"},
+		{"let ch = channel(); close(ch); send(ch, 1)", "send on closed channel"},
+		{"let ch = channel(); close(ch); close(ch)", "close of closed channel"},
+		{"channel(-1)", "capacity of `channel` must not be negative, got -1"},
+		{"recv(1)", "argument to `recv` must be CHANNEL, got INTEGER"},
+		{"select([channel(), 1])", "argument to `select` must be ARRAY of CHANNEL, got INTEGER at 1"},
+		{"select([])", "argument to `select` must not be empty"},
+		{"let a = [1]; a[1] = 2;", fmt.Sprintf("%s: 1, length is 1", INDEX_OUT_OF_BOUNDS)},
+		{"let a = [1]; a[-2] = 2;", fmt.Sprintf("%s: -2, length is 1", INDEX_OUT_OF_BOUNDS)},
+		{`let a = [1]; a["x"] = 2;`, fmt.Sprintf("%s, got STRING", ARRAY_INDEX_MUST_BE_INTEGER)},
+		{"let m = {}; m[[1]] = 2;", fmt.Sprintf("%s ARRAY", KEY_IS_NOT_HASHABLE)},
+		{`let s = "ab"; s[0] = "c";`, fmt.Sprintf("%s: STRING", INDEX_ASSIGNMENT_NOT_SUPPORTED)},
+		{"1 / 0", "division by zero"},
+		{"1 % 0", "division by zero"},
+		{"1 >> -1", "negative shift count"},
+		{"10 ** 100000", "result of exponentiation is larger than 65536 bits"},
+		{"1.5 & 1", fmt.Sprintf("%s: FLOAT & INTEGER", UNKNOWN_OPERATOR)},
+		{`~"a"`, fmt.Sprintf("%s: ~STRING", UNKNOWN_OPERATOR)},
+		{"let [a] = 1;", fmt.Sprintf("%s INTEGER", INDEX_OPERATOR_NOT_SUPPORTED)},
+		{"let [...rest] = {};", fmt.Sprintf("%s: HASH_MAP", SLICE_OPERATOR_NOT_SUPPORTED)},
+		{"1[0:1]", fmt.Sprintf("%s: INTEGER", SLICE_OPERATOR_NOT_SUPPORTED)},
+		{`[1, 2]["a":]`, fmt.Sprintf("%s, got STRING", SLICE_BOUND_MUST_BE_INTEGER)},
+		{`throw "boom";`, fmt.Sprintf("%s: boom", UNCAUGHT_EXCEPTION)},
+		{"try { throw 1; } catch (e) { throw e + 1; }", fmt.Sprintf("%s: 2", UNCAUGHT_EXCEPTION)},
+		{`try { 1 + "a"; } catch (e) { throw e; }`, fmt.Sprintf("%s: INTEGER + STRING", TYPE_MISMATCH)},
+		{"fn(a) { }();", "wrong number of arguments passed to <anonymous>, expected=1, got=0"},
+		{`fn log(level, msg = "") { } log(1, 2, 3);`, "wrong number of arguments passed to log, expected=1..2, got=3"},
+		{"fn log(level, ...rest) { } log();", "wrong number of arguments passed to log, expected=at least 1, got=0"},
+		{"fn f(a = b, b = 1) { a } f()", fmt.Sprintf("%s: b", DEFAULT_REFERS_TO_LATER_PARAM)},
+		{"let b = 5; fn f(a = b, b = 1) { a } f()", fmt.Sprintf("%s: b", DEFAULT_REFERS_TO_LATER_PARAM)},
+		{"fn f(a = a) { a } f()", fmt.Sprintf("%s: a", DEFAULT_REFERS_TO_LATER_PARAM)},
+		{"fn f(a = rest, ...rest) { a } f()", fmt.Sprintf("%s: rest", DEFAULT_REFERS_TO_LATER_PARAM)},
+		{`len("a", "b")`, "wrong number of arguments passed to len, expected=1, got=2"},
+		{"range(1, 2, 3, 4)", "wrong number of arguments passed to range, expected=1..3, got=4"},
+		{"struct Point { x, y } Point(1, 2).z", fmt.Sprintf("%s: Point.z", NO_SUCH_FIELD)},
+		{"struct Point { x, y } let p = Point(1, 2); p.z = 3;", fmt.Sprintf("%s: Point.z", NO_SUCH_FIELD)},
+		{"struct Point { x, y } Point(1)", "wrong number of arguments passed to Point, expected=2, got=1"},
+		{"let n = 1; n.x = 2;", fmt.Sprintf("%s: INTEGER", MEMBER_ACCESS_NOT_SUPPORTED)},
+		{`let p = {"f": fn(self) { 1 }}; p.f(1)`, "wrong number of arguments passed to <anonymous>, expected=1, got=2"},
 	}
 
 	for _, tt := range tests {
@@ -346,6 +891,33 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorStackTraces(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedStackTrace []string
+	}{
+		{`throw "boom";`, []string{"<main>"}},
+		{`fn f() { throw "boom"; } fn g() { f() } g();`, []string{"f", "g", "<main>"}},
+		{`fn f() { 1 + "a" } f();`, []string{"f", "<main>"}},
+		{`try { throw 1; } catch (e) { throw e + 1; }`, []string{"<main>"}},
+		{`fn f() { 1 + "a" } fn g() { try { f(); } catch (e) { throw e; } } g();`, []string{"f", "g", "<main>"}},
+		{`fn* g() { throw "boom"; } fn f() { next(g()) } f();`, []string{"g", "f", "<main>"}},
+		{`fn f() { throw "boom"; } join(spawn f())`, []string{"f", "<main>"}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("no error is returned for %q, got=%T(%+v)", tt.input, evaluated, evaluated)
+		}
+
+		if !slices.Equal(err.StackTrace, tt.expectedStackTrace) {
+			t.Errorf("wrong stack trace for %q. expected=%v, got=%v", tt.input, tt.expectedStackTrace, err.StackTrace)
+		}
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(a, b) { a + b; };"
 	expectedBody := "(a + b)"
@@ -392,6 +964,8 @@ func TestFunctionEval(t *testing.T) {
 		{"let identity = fn(x) { return x; }; identity(5);", 5},
 		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
 		{"fn(x) { x; }(5)", 5},
+		{"fn() { fn a() { b() + 1 } fn b() { 4 } a() }()", 5},
+		{"let r = 0; if true { fn a() { b() + 1 } fn b() { 4 } r = a(); } r", 5},
 	}
 
 	for _, tt := range tests {
@@ -399,6 +973,72 @@ func TestFunctionEval(t *testing.T) {
 	}
 }
 
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput interface{}
+	}{
+		{"fn add(a, b = 10) { a + b } add(1)", 11},
+		{"fn add(a, b = 10) { a + b } add(1, 2)", 3},
+		{"fn add(a, b = a * 2, c = a + b) { [a, b, c] } add(1)", []int64{1, 2, 3}},
+		{"fn add(a, b = a * 2, c = a + b) { [a, b, c] } add(1, 5)", []int64{1, 5, 6}},
+		{"let calls = 0; fn next() { calls = calls + 1; calls } fn f(x = next()) { x } f(); f(); f(7); f()", 3},
+		{"let base = 100; fn f() { let g = fn(x = base) { x }; g() } f()", 100},
+		{`fn log(level, msg = "", ...rest) { rest } log(1)`, []int64{}},
+		{`fn log(level, msg = "", ...rest) { rest } log(1, "a", 2, 3)`, []int64{2, 3}},
+		{"fn all(...items) { items } all(1, 2, 3)", []int64{1, 2, 3}},
+		{"let f = fn(a, ...rest) { fn() { rest } }; f(1, 2, 3)()", []int64{2, 3}},
+		{"let add = (a, b = 10) => a + b; add(1)", 11},
+		{"let add = (a, b = 10) => a + b; add(1, 2)", 3},
+		{"let all = (...items) => items; all(1, 2, 3)", []int64{1, 2, 3}},
+		{"let tail = (a, ...rest) => rest; tail(1, 2, 3)", []int64{2, 3}},
+		{"let total = 0; for i in range(4) { total = total + i; } total", 6},
+		{"let total = 0; for i in range(2, 5) { total = total + i; } total", 9},
+		{"let total = 0; for i in range(10, 0, -3) { total = total + i; } total", 22},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expectedOutput.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case []int64:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Fatalf("object is not array, got=%T (%+v)", evaluated, evaluated)
+			}
+			if len(array.Elements) != len(expected) {
+				t.Fatalf(
+					"array has wrong amount of elements, expected=%d got=%d",
+					len(expected), len(array.Elements),
+				)
+			}
+			for i, element := range array.Elements {
+				testIntegerObject(t, element, expected[i])
+			}
+		}
+	}
+}
+
+func TestArrowFunctionEval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let add = (x, y) => x + y; add(1, 2);", 3},
+		{"let inc = x => x + 1; inc(1);", 2},
+		{"(() => 5)();", 5},
+		{"let double = (x) => { let y = x * 2; y }; double(4);", 8},
+		{"let apply = fn(f, v) { f(v) }; apply(x => x * 10, 4);", 40},
+		{"let adder = a => b => a + b; adder(1)(2);", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello world!"`
 
@@ -425,6 +1065,25 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestInterpolatedString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"${1}"`, "1"},
+		{`"a ${1 + 2} b"`, "a 3 b"},
+		{`let name = "qrk"; "hello ${name}!"`, "hello qrk!"},
+		{`"${1.5} ${true} ${[1, 2]}"`, "1.5 true [1, 2]"},
+		{`let x = 2; "outer ${"inner ${x * 2}"}"`, "outer inner 4"},
+		{`"\${escaped}"`, "${escaped}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testStringObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestBuiltInFunctions(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -491,6 +1150,8 @@ func TestArraysIndecies(t *testing.T) {
 		{`[1,2][0];`, 1},
 		{`[1, 2, 3][-1];`, 3},
 		{`[1, 2, 3][4];`, nil},
+		{`[1, 2, 3][-3];`, 1},
+		{`[1, 2, 3][-10];`, nil},
 	}
 
 	for _, tt := range tests {
@@ -504,6 +1165,31 @@ func TestArraysIndecies(t *testing.T) {
 	}
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4, 5][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4, 5][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4, 5][-2:]", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][:-3]", "[1, 2]"},
+		{"[1, 2, 3][2:1]", "[]"},
+		{"[1, 2, 3][-10:10]", "[1, 2, 3]"},
+		{"let a = [1, 2]; let b = a[:]; b[0] = 5; a", "[1, 2]"},
+		{`"hello world"[0:5]`, "hello"},
+		{`"hello world"[-5:]`, "world"},
+		{`"héllo"[1:2]`, "é"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong value for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestHashLiteral(t *testing.T) {
 	input := `{"a": 42};`
 	expectedOutput := map[object.HashKey]int64{
@@ -552,3 +1238,258 @@ func TestHashMapIndecies(t *testing.T) {
 		}
 	}
 }
+
+// testEvalWithModules evaluates the input as the main file of a directory containing the modules
+func testEvalWithModules(t *testing.T, input string, modules map[string]string) object.Object {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range modules {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loader := module.NewLoader[*object.Module](filepath.Join(dir, "main.qrk"), filepath.Join(dir, "vendor"))
+
+	lexer := lexer.NewLexer(input)
+	p := parser.NewParser(lexer)
+	program := p.ParseProgram()
+
+	return Eval(program, object.NewModuleEnvironment(loader))
+}
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput interface{}
+	}{
+		{"struct Point { x, y } let p = Point(1, 2); p.x + p.y", 3},
+		{"struct Point { x, y } let p = Point(1, 2); p.x = 10; p.x", 10},
+		{"struct Point { x, y } let p = Point(1, 2); let q = p; q.y = 5; p.y", 5},
+		{"struct Point { x, y } struct Line { from, to } let l = Line(Point(1, 2), Point(3, 4)); l.from.y = 7; l.from.y", 7},
+		{"struct Point { x, y } fn norm(p) { p.x * p.x + p.y * p.y } norm(Point(3, 4))", 25},
+		{`fn make() { struct Pair { first, second } Pair(1, "a") } make().second`, "a"},
+		{"struct Point { x, y } Point(1, 2)", "Point { x: 1, y: 2 }"},
+		{"struct Point { x, y } Point", "struct Point { x, y }"},
+		{"fn mk() { Point(1, 2) } struct Point { x, y } mk().x", 1},
+		{"fn() { fn mk() { Pair(1, 2) } struct Pair { first, second } mk().second }()", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expectedOutput.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong value. expected=%q, got=%q", expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput interface{}
+	}{
+		{`let m = {"x": 1}; m.x`, 1},
+		{`let m = {"x": 1}; m.y`, nil},
+		{`let m = {}; m.y = 3; m["y"]`, 3},
+		{`let m = {"inner": {"v": 4}}; m.inner.v = 5; m.inner.v`, 5},
+		{`let p = {"name": "Ann", "greet": fn(self) { "hi, " + self.name }}; p.greet()`, "hi, Ann"},
+		{`let p = {"name": "Ann", "greet": fn(self, greeting = "hi") { greeting + ", " + self.name }}; p.greet("yo")`, "yo, Ann"},
+		{`let p = {"age": 1, "birthday": fn(self) { self.age = self.age + 1; }}; p.birthday(); p.birthday(); p.age`, 3},
+		{`let p = {"double": fn(x) { x * 2 }}; p.double(21)`, 42},
+		{`let p = {"len": len}; p.len("abc")`, 3},
+		{`let p = {"name": "Ann", "greet": fn(self) { self.name }}; p["greet"]({"name": "Bob"})`, "Bob"},
+		{`fn make() { {"n": 0, "inc": fn(self) { self.n = self.n + 1; self }} } make().inc().inc().n`, 2},
+		{`struct Counter { n, inc } let c = Counter(0, fn(self, by) { self.n = self.n + by; }); c.inc(5); c.n`, 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expectedOutput.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput interface{}
+	}{
+		{`fn* count() { yield 1; yield 2; } let g = count(); next(g); next(g)`, 2},
+		{`fn* count() { yield 1; yield 2; } let g = count(); next(g); next(g); next(g); next(g)`, nil},
+		{`fn* count(from, to = from + 2) { let i = from; while i <= to { yield i; i = i + 1; } } let sum = 0; for x in count(3) { sum = sum + x; } sum`, 12},
+		{`fn* letters() { yield "a"; yield "b"; } let keys = 0; for i, x in letters() { keys = keys + i; } keys`, 1},
+		{`fn* nat() { let n = 0; while true { yield n; n = n + 1; } } let sum = 0; for n in nat() { if n > 4 { break; } sum = sum + n; } sum`, 10},
+		{`fn* empty() { yield; } next(empty())`, nil},
+		{`fn* early() { yield 1; return 2; yield 3; } let g = early(); next(g); next(g)`, nil},
+		{`let g = fn*(...xs) { for x in xs { yield x * 2; } }; let out = ""; for x in g(1, 2, 3) { out = "${out}${x}"; } out`, "246"},
+		{`fn* inner() { yield 1; yield 2; } fn* outer() { for x in inner() { yield x * 10; } } let g = outer(); next(g) + next(g)`, 30},
+		{`let calls = 0; fn* lazy() { calls = calls + 1; yield calls; } let g = lazy(); calls`, 0},
+		{`fn* safe() { try { yield "a"; throw "x"; } catch (e) { yield e; } } let g = safe(); next(g) + next(g)`, "ax"},
+		{`fn* fails() { yield 1; throw "boom"; } let g = fails(); next(g); let caught = ""; try { next(g); } catch (e) { caught = e; } caught`, "boom"},
+		{`fn* fails() { throw "boom"; } let g = fails(); try { next(g) } catch (e) {} next(g)`, nil},
+		{`let holder = {}; fn* selfish() { yield next(holder.gen); } holder.gen = selfish(); let caught = ""; try { next(holder.gen); } catch (e) { caught = e["message"]; } caught`, "generator selfish is already running"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expectedOutput.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestTasksAndChannels(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput interface{}
+	}{
+		{`fn square(x) { x * x } let tasks = [spawn square(2), spawn square(3)]; join(tasks[0]) + join(tasks[1])`, 13},
+		{`join(spawn fn() { "done" })`, "done"},
+		{`join(spawn fn() { })`, nil},
+		{`join(spawn len("abc"))`, 3},
+		{`let counter = 1; let t = spawn fn() { counter = counter + 100; counter }; join(t) + counter`, 102},
+		{`let inc = fn() { let n = 0; fn() { n = n + 1; n } }(); join(spawn inc); inc()`, 1},
+		{`fn fib(n) { if n < 2 { return n; } fib(n - 1) + fib(n - 2) } join(spawn fib(15))`, 610},
+		{`let t = spawn fn() { throw "boom"; }; let caught = ""; try { join(t); } catch (e) { caught = e; } caught`, "boom"},
+		{`let ch = channel(); spawn fn() { send(ch, 42); }; recv(ch)`, 42},
+		{`let c = channel(); try { recv(c) } catch (e) { e["message"] }`, "deadlock: recv on channel with no senders"},
+		{`let c = channel(); try { send(c, 1) } catch (e) { e["message"] }`, "deadlock: send on channel with no receivers"},
+		{`try { select([channel(), channel(1)]) } catch (e) { e["message"] }`, "deadlock: select on channels with no senders"},
+		{`let c = channel(); let t = spawn fn() { recv(c) }; try { join(t) } catch (e) { e["message"][:9] }`, "deadlock:"},
+		{`let c = channel(); try { recv(c) } catch { 0 } spawn fn() { send(c, 5) }; recv(c)`, 5},
+		{`let ch = channel(); fn worker(id) { send(ch, id * 10); } for i in range(3) { spawn worker(i); } recv(ch) + recv(ch) + recv(ch)`, 30},
+		{`let ch = channel(2); send(ch, 1); send(ch, 2); close(ch); recv(ch) + recv(ch)`, 3},
+		{`let ch = channel(1); close(ch); recv(ch)`, nil},
+		{`let ch = channel(); let t = spawn fn() { let sum = 0; while true { let v = recv(ch); if !v { break; } sum = sum + v; } sum }; for i in range(5) { send(ch, i); } close(ch); join(t)`, 10},
+		{`let a = channel(); let b = channel(); spawn fn() { send(b, "b"); }; select([a, b])[0]`, 1},
+		{`let a = channel(); let b = channel(); spawn fn() { send(b, "b"); }; select([a, b])[1]`, "b"},
+		{`let a = channel(); close(a); select([a])[1]`, nil},
+		{`let h = {"n": 1}; join(spawn fn() { h["n"] = 2; }); h["n"]`, 1},
+		{`let h = {"n": 1}; join(spawn fn(m) { m["n"] = 2; }(h)); h["n"]`, 1},
+		{`let h = {"n": 1}; let fs = [fn() { h["n"] = 2; }]; join(spawn fn() { fs[0](); }); h["n"]`, 1},
+		{`let h = {"n": 1}; let t = spawn fn() { h["n"] = 2; h }; join(t)["n"]`, 2},
+		{`let a = [1]; let pair = [a, a]; join(spawn fn() { pair[0][0] = 5; pair[1][0] }())`, 5},
+		{`let ch = channel(1); let a = [1]; send(ch, a); a[0] = 2; recv(ch)[0]`, 1},
+		{`fn* gen() { yield 1; } let g = gen(); let caught = ""; try { join(spawn fn() { next(g) }); } catch (e) { caught = e["message"]; } caught`, "generator gen belongs to another task"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expectedOutput.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	modules := map[string]string{
+		"lib/strings.qrk": `
+			import "util.qrk" as util;
+			let calls = 0;
+			export fn shout(s) { calls = calls + 1; util.twice(s) + "!" }
+			export fn callsCount() { calls }
+			export let greeting = "hi";
+		`,
+		"lib/util.qrk":      `export fn twice(s) { s + s }`,
+		"lib/geo.qrk":       `export struct Point { x, y }`,
+		"lib/gen.qrk":       `export fn* evens(limit) { let i = 0; while i < limit { yield i; i = i + 2; } }`,
+		"vendor/answer.qrk": `export let answer = 42;`,
+		"a.qrk":             `import "b.qrk" as b; export let x = 1;`,
+		"b.qrk":             `import "a.qrk" as a; export let y = 2;`,
+		"failing.qrk":       `throw "failed";`,
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/strings.qrk" as s; s.shout("a")`, "aa!"},
+		{`import "lib/strings.qrk" as s; s.shout(s.greeting)`, "hihi!"},
+		{`import "lib/strings.qrk" as s; fn f() { s.shout } f()("x")`, "xx!"},
+		{`import "lib/geo.qrk" as geo; let p = geo.Point("a", "b"); p.x = "c"; p.x + p.y`, "cb"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, testEvalWithModules(t, tt.input, modules), tt.expected)
+	}
+
+	integerTests := []struct {
+		input    string
+		expected int64
+	}{
+		{`import "lib/strings.qrk" as s; let calls = 10; s.shout("a"); s.shout("b"); calls + s.callsCount()`, 12},
+		{`import "lib/strings.qrk" as s; import "lib/strings.qrk" as again; s.shout("a"); again.callsCount()`, 1},
+		{`import "answer.qrk" as a; a.answer`, 42},
+		{`import "lib/gen.qrk" as gen; let sum = 0; for e in gen.evens(7) { sum = sum + e; } sum`, 12},
+	}
+
+	for _, tt := range integerTests {
+		testIntegerObject(t, testEvalWithModules(t, tt.input, modules), tt.expected)
+	}
+
+	errorTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`import "lib/strings.qrk" as s; s.calls`, fmt.Sprintf("%s: s.calls", NO_SUCH_EXPORT)},
+		{`import "lib/strings.qrk" as s; s`, fmt.Sprintf("%s: s", MODULE_USED_AS_VALUE)},
+		{`import "lib/strings.qrk" as s; s = 1;`, fmt.Sprintf("%s: s", CANNOT_ASSIGN_TO_MODULE)},
+		{"let x = 1; x.y", fmt.Sprintf("%s: INTEGER", MEMBER_ACCESS_NOT_SUPPORTED)},
+		{`fn() { import "lib/util.qrk" as u; }()`, fmt.Sprintf("%s: import", NOT_AT_TOP_LEVEL)},
+		{`import "missing.qrk" as m;`, "module not found: missing.qrk"},
+		{`import "failing.qrk" as f;`, fmt.Sprintf("%s: failed", UNCAUGHT_EXCEPTION)},
+	}
+
+	for _, tt := range errorTests {
+		evaluated := testEvalWithModules(t, tt.input, modules)
+
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("no error is returned, got=%T(%+v)", evaluated, evaluated)
+		}
+
+		if err.Message != tt.expectedMessage {
+			t.Errorf("wrong error message, got=%s, expected=%s", err.Message, tt.expectedMessage)
+		}
+	}
+
+	evaluated := testEvalWithModules(t, `import "a.qrk" as a;`, modules)
+	err, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error is returned for import cycle, got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if !strings.HasPrefix(err.Message, "import cycle: ") || !strings.HasSuffix(err.Message, "a.qrk") {
+		t.Errorf("wrong error message for import cycle, got=%s", err.Message)
+	}
+}
@@ -0,0 +1,19 @@
+package lexer
+
+import "fmt"
+
+// Error points at the place of the input which couldn't be lexed
+type Error struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d", e.Message, e.Line, e.Column)
+}
+
+// errorAt reports the error at the line and column the lexer has tracked for the start of the malformed part
+func errorAt(line, column int, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Line: line, Column: column}
+}
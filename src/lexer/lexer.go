@@ -1,27 +1,57 @@
 package lexer
 
 import (
-	"errors"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/vdchnsk/qrk/src/token"
 	"github.com/vdchnsk/qrk/src/utils"
 )
 
+// \u{...} escapes hold code points up to 10FFFF
+const maxCodePointDigits = 6
+
 type Lexer struct {
 	input            string
 	position         int
 	currReadPosition int
 	currChar         byte
+
+	// interpolationBraces holds the amount of unclosed `{` for every `${` being lexed,
+	// so the `}` closing the interpolation can be told apart from the ones of the nested expression
+	interpolationBraces []int
+
+	line         int
+	lineStartsAt int
+	comments     []Comment
+}
+
+// Comment is trivia skipped while lexing, it is kept to be available for tooling like formatters
+type Comment struct {
+	Text    string // with delimiters, e.g. `// note` or `/* note */`
+	IsBlock bool
+	Line    int
+	Column  int
 }
 
 func NewLexer(input string) *Lexer {
-	l := &Lexer{input: input, currReadPosition: 0, position: 0}
+	l := &Lexer{input: input, currReadPosition: 0, position: 0, line: 1}
 	l.readChar()
 
 	return l
 }
 
+// Comments returns the comments skipped so far, in order of appearance
+func (l *Lexer) Comments() []Comment {
+	return l.comments
+}
+
 func (l *Lexer) readChar() {
+	if l.currChar == '\n' {
+		l.line++
+		l.lineStartsAt = l.currReadPosition
+	}
+
 	if l.currReadPosition >= len(l.input) {
 		l.currChar = 0
 	} else {
@@ -40,10 +70,31 @@ func (l *Lexer) peekChar() byte {
 	}
 }
 
+// peekCharAt looks offset chars past the next one
+func (l *Lexer) peekCharAt(offset int) byte {
+	position := l.currReadPosition + offset
+	if position >= len(l.input) {
+		return 0
+	}
+
+	return l.input[position]
+}
+
 func (l *Lexer) NextToken() (token.Token, error) {
-	var tok token.Token
+	if err := l.skipWhitespaceAndComments(); err != nil {
+		return token.Token{Type: token.EOF}, err
+	}
+
+	line, column := l.line, l.column()
+
+	tok, err := l.readToken()
+	tok.Line, tok.Column = line, column
+
+	return tok, err
+}
 
-	l.skipWhitespace()
+func (l *Lexer) readToken() (token.Token, error) {
+	var tok token.Token
 
 	switch l.currChar {
 	case '+':
@@ -52,19 +103,58 @@ func (l *Lexer) NextToken() (token.Token, error) {
 		tok = newToken(token.MINUS, l.currChar)
 	case '/':
 		tok = newToken(token.SLASH, l.currChar)
+	case '%':
+		tok = newToken(token.PERCENT, l.currChar)
+	case '^':
+		tok = newToken(token.BIT_XOR, l.currChar)
+	case '~':
+		tok = newToken(token.TILDE, l.currChar)
 	case '*':
-		tok = newToken(token.ASTERISK, l.currChar)
+		if l.peekChar() == '*' {
+			l.readChar()
+			tok = token.Token{Type: token.POWER, Literal: "**"}
+		} else {
+			tok = newToken(token.ASTERISK, l.currChar)
+		}
 	case '<':
-		tok = newToken(token.LT, l.currChar)
+		switch l.peekChar() {
+		case '=':
+			l.readChar()
+			tok = token.Token{Type: token.LT_EQ, Literal: "<="}
+		case '<':
+			l.readChar()
+			tok = token.Token{Type: token.SHIFT_LEFT, Literal: "<<"}
+		default:
+			tok = newToken(token.LT, l.currChar)
+		}
 	case '>':
-		tok = newToken(token.GT, l.currChar)
+		switch l.peekChar() {
+		case '=':
+			l.readChar()
+			tok = token.Token{Type: token.GT_EQ, Literal: ">="}
+		case '>':
+			l.readChar()
+			tok = token.Token{Type: token.SHIFT_RIGHT, Literal: ">>"}
+		default:
+			tok = newToken(token.GT, l.currChar)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.currChar)
 	case ')':
 		tok = newToken(token.RPAREN, l.currChar)
 	case '{':
+		if l.isInInterpolation() {
+			l.interpolationBraces[len(l.interpolationBraces)-1]++
+		}
 		tok = newToken(token.LBRACE, l.currChar)
 	case '}':
+		if l.isInInterpolation() && l.interpolationBraces[len(l.interpolationBraces)-1] == 0 {
+			l.interpolationBraces = l.interpolationBraces[:len(l.interpolationBraces)-1]
+			return l.readStringToken(token.INTERPOLATION_MIDDLE, token.INTERPOLATION_END)
+		}
+		if l.isInInterpolation() {
+			l.interpolationBraces[len(l.interpolationBraces)-1]--
+		}
 		tok = newToken(token.RBRACE, l.currChar)
 	case '[':
 		tok = newToken(token.LBRACKET, l.currChar)
@@ -107,7 +197,7 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			l.readChar()
 			tok = token.Token{Type: token.AND, Literal: string(currChar) + string(l.currChar)}
 		} else {
-			tok = newToken(token.ILLEGAL, l.currChar)
+			tok = newToken(token.BIT_AND, l.currChar)
 		}
 	case '|':
 		currChar := l.currChar
@@ -116,15 +206,18 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			l.readChar()
 			tok = token.Token{Type: token.OR, Literal: string(currChar) + string(l.currChar)}
 		} else {
-			tok = newToken(token.ILLEGAL, l.currChar)
+			tok = newToken(token.BIT_OR, l.currChar)
 		}
-	case '"':
-		strValue, err := l.readString()
-		if err != nil {
-			return token.Token{}, err
+	case '.':
+		if l.peekChar() == '.' && l.peekCharAt(1) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.DOT, l.currChar)
 		}
-		tok.Type = token.STRING
-		tok.Literal = strValue
+	case '"':
+		return l.readStringToken(token.INTERPOLATION_START, token.STRING)
 	default:
 		if isLetter(l.currChar) {
 			identifier := l.readIdentifier()
@@ -133,8 +226,7 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			tok.Literal = identifier
 			return tok, nil
 		} else if isDigit(l.currChar) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Literal, tok.Type = l.readNumber()
 			return tok, nil
 		} else {
 			tok = newToken(token.ILLEGAL, l.currChar)
@@ -158,34 +250,267 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-func (l *Lexer) readNumber() string {
+func (l *Lexer) skipWhitespaceAndComments() error {
+	for {
+		l.skipWhitespace()
+
+		if l.currChar != '/' {
+			return nil
+		}
+
+		switch l.peekChar() {
+		case '/':
+			l.readLineComment()
+		case '*':
+			if err := l.readBlockComment(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (l *Lexer) readLineComment() {
+	initialPosition := l.position
+	line, column := l.line, l.column()
+
+	for l.currChar != '\n' && l.currChar != 0 {
+		l.readChar()
+	}
+
+	l.comments = append(l.comments, Comment{
+		Text:   l.input[initialPosition:l.position],
+		Line:   line,
+		Column: column,
+	})
+}
+
+// readBlockComment reads a `/* */` comment, nested comments have to be closed as well, e.g. `/* a /* b */ c */`
+func (l *Lexer) readBlockComment() error {
+	initialPosition := l.position
+	line, column := l.line, l.column()
+	depth := 0
+
+	for {
+		switch {
+		case l.currChar == 0:
+			return errorAt(line, column, "no closing block comment symbol was found")
+		case l.currChar == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.currChar == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+		}
+
+		l.readChar()
+
+		if depth == 0 {
+			break
+		}
+	}
+
+	l.comments = append(l.comments, Comment{
+		Text:    l.input[initialPosition:l.position],
+		IsBlock: true,
+		Line:    line,
+		Column:  column,
+	})
+
+	return nil
+}
+
+func (l *Lexer) column() int {
+	return l.position - l.lineStartsAt + 1
+}
+
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	initialPosition := l.position
+	tokenType := token.TokenType(token.INT)
+
+	l.readDigits()
+
+	// `.` is a part of the number only when followed by a digit, e.g. `1.5`
+	if l.currChar == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+
+		l.readChar()
+		l.readDigits()
+	}
+
+	if (l.currChar == 'e' || l.currChar == 'E') && l.isExponentAhead() {
+		tokenType = token.FLOAT
+
+		l.readChar()
+		if l.currChar == '+' || l.currChar == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+
+	return l.input[initialPosition:l.position], tokenType
+}
 
+func (l *Lexer) readDigits() {
 	for isDigit(l.currChar) {
 		l.readChar()
 	}
+}
+
+// isExponentAhead checks that the current `e` is followed by digits, optionally signed, e.g. `e10`, `e-3`
+func (l *Lexer) isExponentAhead() bool {
+	next := l.peekChar()
+	if isDigit(next) {
+		return true
+	}
+
+	isSigned := next == '+' || next == '-'
+	afterSignPosition := l.currReadPosition + 1
+
+	return isSigned && afterSignPosition < len(l.input) && isDigit(l.input[afterSignPosition])
+}
+
+// readStringToken reads the string part starting at the current `"` or `}`.
+// The part is of interpolatedType when it is followed by `${`, and of closedType when it ends the string
+func (l *Lexer) readStringToken(interpolatedType, closedType token.TokenType) (token.Token, error) {
+	value, isInterpolated, err := l.readString()
+
+	tok := token.Token{Type: closedType, Literal: value}
+	if isInterpolated {
+		tok.Type = interpolatedType
+		l.interpolationBraces = append(l.interpolationBraces, 0)
+	}
 
-	return l.input[initialPosition:l.position]
+	l.readChar()
+
+	return tok, err
 }
 
-func (l *Lexer) readString() (string, error) {
-	initialPosition := l.position // "
+func (l *Lexer) isInInterpolation() bool {
+	return len(l.interpolationBraces) > 0
+}
+
+// readString reads a string literal up to the closing `"` or the next `${`, and decodes escape sequences in it.
+// An invalid escape sequence doesn't stop reading, so the whole literal is consumed and the first error found is returned
+// alongside the decoded value
+func (l *Lexer) readString() (value string, isInterpolated bool, err error) {
+	line, column := l.line, l.column() // " or }
+
+	var decoded strings.Builder
+	var firstErr error
 
 	for {
 		l.readChar()
 		if l.currChar == 0 {
-			return "", errors.New("no closing string symbol was found")
+			return decoded.String(), false, errorAt(line, column, "no closing string symbol was found")
 		}
 
 		if l.currChar == '"' {
 			break
 		}
+
+		if l.currChar == '$' && l.peekChar() == '{' {
+			l.readChar()
+			return decoded.String(), true, firstErr
+		}
+
+		if l.currChar != '\\' {
+			decoded.WriteByte(l.currChar)
+			continue
+		}
+
+		err := l.readEscapeSequence(&decoded)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return decoded.String(), false, firstErr
+}
+
+func (l *Lexer) readEscapeSequence(value *strings.Builder) error {
+	line, column := l.line, l.column() // backslash
+
+	if l.peekChar() == 0 {
+		return errorAt(line, column, "unfinished escape sequence")
+	}
+
+	l.readChar()
+
+	switch l.currChar {
+	case 'n':
+		value.WriteByte('\n')
+	case 't':
+		value.WriteByte('\t')
+	case 'r':
+		value.WriteByte('\r')
+	case '\\':
+		value.WriteByte('\\')
+	case '"':
+		value.WriteByte('"')
+	case '$':
+		value.WriteByte('$')
+	case 'x':
+		if !isHexDigit(l.peekChar()) || !isHexDigit(l.peekCharAt(1)) {
+			return errorAt(line, column, "\\x must be followed by exactly two hex digits")
+		}
+
+		l.readChar()
+		high := l.currChar
+		l.readChar()
+		low := l.currChar
+
+		value.WriteByte(hexValue(high)<<4 | hexValue(low))
+	case 'u':
+		codePoint, err := l.readUnicodeCodePoint(line, column)
+		if err != nil {
+			return err
+		}
+
+		value.WriteRune(codePoint)
+	default:
+		return errorAt(line, column, "invalid escape sequence \\%c", l.currChar)
+	}
+
+	return nil
+}
+
+// readUnicodeCodePoint reads the `{1F600}` part of a `\u{1F600}` escape sequence,
+// errors are reported at the line and column of the backslash
+func (l *Lexer) readUnicodeCodePoint(line, column int) (rune, error) {
+	if l.peekChar() != '{' {
+		return 0, errorAt(line, column, "\\u must be followed by a code point in braces, e.g. \\u{1F600}")
+	}
+	l.readChar()
+
+	var codePoint rune
+	digitsCount := 0
+
+	for isHexDigit(l.peekChar()) {
+		l.readChar()
+		codePoint = codePoint<<4 | rune(hexValue(l.currChar))
+		digitsCount++
+
+		if digitsCount > maxCodePointDigits {
+			return 0, errorAt(line, column, "code point in \\u{...} is too long")
+		}
+	}
+
+	if l.peekChar() != '}' {
+		return 0, errorAt(line, column, "\\u{...} must contain only hex digits and be closed with }")
 	}
+	l.readChar()
 
-	startValuePosition := initialPosition + 1
-	endValuePosition := l.position
+	if digitsCount == 0 {
+		return 0, errorAt(line, column, "\\u{...} must contain at least one hex digit")
+	}
 
-	return l.input[startValuePosition:endValuePosition], nil
+	if !utf8.ValidRune(codePoint) {
+		return 0, errorAt(line, column, "\\u{%X} is not a valid unicode code point", codePoint)
+	}
+
+	return codePoint, nil
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -199,6 +524,21 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[initialPosition:identEndPosition]
 }
 
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func hexValue(ch byte) byte {
+	switch {
+	case isDigit(ch):
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
+
 func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
@@ -16,7 +16,7 @@ func TestNextToken(t *testing.T) {
 		};
 
 		let result = add(five, ten);
-		!-/*5;
+		!-/ *5;
 		5 < 10 > 5;
 
 		if (5 < 10) {
@@ -30,6 +30,18 @@ func TestNextToken(t *testing.T) {
 		"foo";
 		"foo bar";
 		[1, 2][1];
+		while true { break; continue; }
+		for k, v in x {}
+		3.14 1e10 2.5e-3 1.e
+		% ** <= >= & | ^ ~ << >>
+		...rest
+		match
+		throw try catch finally
+		import "lib.qrk" as lib; export lib.x
+		struct
+		fn* yield
+		spawn
+		const
 	`
 
 	tests := []struct {
@@ -122,6 +134,60 @@ func TestNextToken(t *testing.T) {
 		{token.INT, "1"},
 		{token.RBRACKET, "]"},
 		{token.SEMICOLON, ";"},
+		{token.WHILE, "while"},
+		{token.TRUE, "true"},
+		{token.LBRACE, "{"},
+		{token.BREAK, "break"},
+		{token.SEMICOLON, ";"},
+		{token.CONTINUE, "continue"},
+		{token.SEMICOLON, ";"},
+		{token.RBRACE, "}"},
+		{token.FOR, "for"},
+		{token.IDENT, "k"},
+		{token.COMMA, ","},
+		{token.IDENT, "v"},
+		{token.IN, "in"},
+		{token.IDENT, "x"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e10"},
+		{token.FLOAT, "2.5e-3"},
+		{token.INT, "1"},
+		{token.DOT, "."},
+		{token.IDENT, "e"},
+		{token.PERCENT, "%"},
+		{token.POWER, "**"},
+		{token.LT_EQ, "<="},
+		{token.GT_EQ, ">="},
+		{token.BIT_AND, "&"},
+		{token.BIT_OR, "|"},
+		{token.BIT_XOR, "^"},
+		{token.TILDE, "~"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.MATCH, "match"},
+		{token.THROW, "throw"},
+		{token.TRY, "try"},
+		{token.CATCH, "catch"},
+		{token.FINALLY, "finally"},
+		{token.IMPORT, "import"},
+		{token.STRING, "lib.qrk"},
+		{token.AS, "as"},
+		{token.IDENT, "lib"},
+		{token.SEMICOLON, ";"},
+		{token.EXPORT, "export"},
+		{token.IDENT, "lib"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.STRUCT, "struct"},
+		{token.FUNCTION, "fn"},
+		{token.ASTERISK, "*"},
+		{token.YIELD, "yield"},
+		{token.SPAWN, "spawn"},
+		{token.CONST, "const"},
 		{token.EOF, ""},
 	}
 
@@ -146,3 +212,249 @@ func TestNextToken(t *testing.T) {
 	}
 
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "const x = 1;\n  x = \"two\";"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"const", 1, 1},
+		{"x", 1, 7},
+		{"=", 1, 9},
+		{"1", 1, 11},
+		{";", 1, 12},
+		{"x", 2, 3},
+		{"=", 2, 5},
+		{"two", 2, 7},
+		{";", 2, 12},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok, _ := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf(
+				"tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLiteral, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column,
+			)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 1; // trailing comment
+/* block /* nested */ still comment */ x / 2;
+/**/ x
+// comment at the end`
+
+	expectedTokens := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range expectedTokens {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected lexing error: %s", i, err)
+		}
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	expectedComments := []Comment{
+		{Text: "// leading comment", Line: 1, Column: 1},
+		{Text: "// trailing comment", Line: 2, Column: 12},
+		{Text: "/* block /* nested */ still comment */", IsBlock: true, Line: 3, Column: 1},
+		{Text: "/**/", IsBlock: true, Line: 4, Column: 1},
+		{Text: "// comment at the end", Line: 5, Column: 1},
+	}
+
+	comments := l.Comments()
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong amount of comments. expected=%d, got=%d", len(expectedComments), len(comments))
+	}
+
+	for i, expected := range expectedComments {
+		if comments[i] != expected {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, expected, comments[i])
+		}
+	}
+}
+
+func TestUnclosedBlockComment(t *testing.T) {
+	l := NewLexer("x /* a /* b */ c")
+
+	l.NextToken()
+	tok, err := l.NextToken()
+	if err == nil {
+		t.Fatalf("expected lexing error, got none")
+	}
+
+	expectedError := "no closing block comment symbol was found at line 1, column 3"
+	if err.Error() != expectedError {
+		t.Errorf("wrong error. expected=%q, got=%q", expectedError, err.Error())
+	}
+
+	if tok.Type != token.EOF {
+		t.Errorf("tokentype wrong. expected=%q, got=%q", token.EOF, tok.Type)
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"a\tb\r"`, "a\tb\r"},
+		{`"back\\slash"`, `back\slash`},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"\x41\x7a"`, "Az"},
+		{`"\u{48}\u{1F600}"`, "H😀"},
+		{`"\u{10FFFF}"`, "\U0010FFFF"},
+	}
+
+	for _, tt := range tests {
+		l := NewLexer(tt.input)
+
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("unexpected lexing error for %s: %s", tt.input, err)
+		}
+
+		if tok.Type != token.STRING {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", token.STRING, tok.Type)
+		}
+
+		if tok.Literal != tt.expected {
+			t.Errorf("literal wrong. expected=%q, got=%q", tt.expected, tok.Literal)
+		}
+	}
+}
+
+func TestInterpolatedStringTokens(t *testing.T) {
+	input := `"a ${x + {"k": 1}["k"]} b ${"c${y}"}"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INTERPOLATION_START, "a "},
+		{token.IDENT, "x"},
+		{token.PLUS, "+"},
+		{token.LBRACE, "{"},
+		{token.STRING, "k"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "k"},
+		{token.RBRACKET, "]"},
+		{token.INTERPOLATION_MIDDLE, " b "},
+		{token.INTERPOLATION_START, "c"},
+		{token.IDENT, "y"},
+		{token.INTERPOLATION_END, ""},
+		{token.INTERPOLATION_END, ""},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected lexing error: %s", i, err)
+		}
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringEscapeErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`"\q"`, `invalid escape sequence \q at line 1, column 2`},
+		{"\n  \"ab\\x4\"", `\x must be followed by exactly two hex digits at line 2, column 6`},
+		{`"\u41"`, `\u must be followed by a code point in braces, e.g. \u{1F600} at line 1, column 2`},
+		{`"\u{}"`, `\u{...} must contain at least one hex digit at line 1, column 2`},
+		{`"\u{41"`, `\u{...} must contain only hex digits and be closed with } at line 1, column 2`},
+		{`"\u{1234567}"`, `code point in \u{...} is too long at line 1, column 2`},
+		{`"\u{D800}"`, `\u{D800} is not a valid unicode code point at line 1, column 2`},
+		{`"abc`, `no closing string symbol was found at line 1, column 1`},
+		{"\n\n  \"abc", `no closing string symbol was found at line 3, column 3`},
+		{"\"a\nb\\q\"", `invalid escape sequence \q at line 2, column 2`},
+		{"\n  /* a /* b */", `no closing block comment symbol was found at line 2, column 3`},
+	}
+
+	for _, tt := range tests {
+		l := NewLexer(tt.input)
+
+		_, err := l.NextToken()
+		if err == nil {
+			t.Errorf("expected lexing error for %s, got none", tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, err.Error())
+		}
+	}
+}
+
+func TestLexingContinuesAfterInvalidEscape(t *testing.T) {
+	l := NewLexer(`"a\qb" 5`)
+
+	tok, err := l.NextToken()
+	if err == nil {
+		t.Fatalf("expected lexing error, got none")
+	}
+	if tok.Type != token.STRING || tok.Literal != "ab" {
+		t.Fatalf("wrong token. expected STRING %q, got %s %q", "ab", tok.Type, tok.Literal)
+	}
+
+	tok, err = l.NextToken()
+	if err != nil {
+		t.Fatalf("unexpected lexing error: %s", err)
+	}
+	if tok.Type != token.INT || tok.Literal != "5" {
+		t.Fatalf("wrong token. expected INT %q, got %s %q", "5", tok.Type, tok.Literal)
+	}
+}
@@ -0,0 +1,161 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vdchnsk/qrk/src/ast"
+	"github.com/vdchnsk/qrk/src/fs"
+	"github.com/vdchnsk/qrk/src/lexer"
+	"github.com/vdchnsk/qrk/src/parser"
+)
+
+// SearchPathEnvVar lists directories imports are looked up in, when they aren't found next to the importing file
+const SearchPathEnvVar = "QRK_PATH"
+
+// Loader finds and parses imported files, every module is loaded once and shared between its importers.
+// T is whatever the backend makes of a module, e.g. compiled exports or an evaluated environment
+type Loader[T any] struct {
+	SearchPaths []string
+
+	modules map[string]T
+	// absolute paths of the files being loaded, starting with the main file, the innermost import last
+	loading []string
+}
+
+// NewLoader creates a loader for the program in the main file,
+// mainPath is empty when the program doesn't come from a file, then imports are relative to the working directory
+func NewLoader[T any](mainPath string, searchPaths ...string) *Loader[T] {
+	loader := &Loader[T]{
+		SearchPaths: searchPaths,
+		modules:     make(map[string]T),
+	}
+
+	if mainPath != "" {
+		absPath, err := filepath.Abs(mainPath)
+		if err == nil {
+			loader.loading = []string{absPath}
+		}
+	}
+
+	return loader
+}
+
+// SearchPathsFromEnv returns the directories listed in QRK_PATH
+func SearchPathsFromEnv() []string {
+	return filepath.SplitList(os.Getenv(SearchPathEnvVar))
+}
+
+// Load resolves the path imported by the file being loaded and builds the module with `build`, unless it's already loaded.
+// Modules imported by the module are loaded while it's being built
+func (l *Loader[T]) Load(path string, build func(path string, program *ast.Program) (T, error)) (T, error) {
+	var noModule T
+
+	absPath, err := l.Resolve(path, l.importerDir())
+	if err != nil {
+		return noModule, err
+	}
+
+	if module, ok := l.modules[absPath]; ok {
+		return module, nil
+	}
+
+	if err := l.enter(absPath); err != nil {
+		return noModule, err
+	}
+	defer l.leave()
+
+	program, err := parse(absPath)
+	if err != nil {
+		return noModule, err
+	}
+
+	module, err := build(absPath, program)
+	if err != nil {
+		return noModule, err
+	}
+
+	l.modules[absPath] = module
+
+	return module, nil
+}
+
+// Resolve looks the imported path up relative to the importing file's directory first, then in the search paths
+func (l *Loader[T]) Resolve(path, importerDir string) (string, error) {
+	candidates := []string{path}
+
+	if !filepath.IsAbs(path) {
+		candidates = []string{filepath.Join(importerDir, path)}
+
+		for _, searchPath := range l.SearchPaths {
+			candidates = append(candidates, filepath.Join(searchPath, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if fs.CanRunFile(candidate) {
+			return filepath.Abs(candidate)
+		}
+	}
+
+	return "", fmt.Errorf("module not found: %s", path)
+}
+
+func (l *Loader[T]) importerDir() string {
+	if len(l.loading) == 0 {
+		return "."
+	}
+
+	return filepath.Dir(l.loading[len(l.loading)-1])
+}
+
+func (l *Loader[T]) enter(path string) error {
+	for i, loading := range l.loading {
+		if loading == path {
+			cycle := append(append([]string{}, l.loading[i:]...), path)
+			return fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	l.loading = append(l.loading, path)
+
+	return nil
+}
+
+func (l *Loader[T]) leave() {
+	l.loading = l.loading[:len(l.loading)-1]
+}
+
+func parse(path string) (*ast.Program, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := parser.NewParser(lexer.NewLexer(string(data)))
+	program := parser.ParseProgram()
+
+	if len(parser.Errors()) != 0 {
+		return nil, fmt.Errorf("syntax error in module %s: %s", path, strings.Join(parser.Errors(), "; "))
+	}
+
+	return program, nil
+}
+
+// ExportedNames returns the names of the bindings declared with `export` at the top level of the module
+func ExportedNames(program *ast.Program) []string {
+	names := []string{}
+
+	for _, statement := range program.Statements {
+		export, ok := statement.(*ast.ExportStatement)
+		if !ok {
+			continue
+		}
+
+		names = append(names, export.Name())
+	}
+
+	return names
+}
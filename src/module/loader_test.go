@@ -0,0 +1,195 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vdchnsk/qrk/src/ast"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// loadNested builds a module by loading its own imports, the result is the path of the module
+func loadNested(loader *Loader[string], imports map[string][]string) func(string, *ast.Program) (string, error) {
+	var build func(string, *ast.Program) (string, error)
+
+	build = func(path string, program *ast.Program) (string, error) {
+		for _, imported := range imports[filepath.Base(path)] {
+			if _, err := loader.Load(imported, build); err != nil {
+				return "", err
+			}
+		}
+
+		return path, nil
+	}
+
+	return build
+}
+
+func TestResolve(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.qrk":        "",
+		"lib/strings.qrk": "",
+		"vendor/http.qrk": "",
+		"vendor/x.txt":    "",
+	})
+
+	loader := NewLoader[string](filepath.Join(dir, "main.qrk"), filepath.Join(dir, "vendor"))
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"lib/strings.qrk", filepath.Join(dir, "lib/strings.qrk")},
+		{"./lib/../lib/strings.qrk", filepath.Join(dir, "lib/strings.qrk")},
+		{"http.qrk", filepath.Join(dir, "vendor/http.qrk")},
+		{filepath.Join(dir, "main.qrk"), filepath.Join(dir, "main.qrk")},
+	}
+
+	for _, tt := range tests {
+		resolved, err := loader.Resolve(tt.path, dir)
+		if err != nil {
+			t.Fatalf("unexpected error resolving %q: %s", tt.path, err)
+		}
+
+		if resolved != tt.expected {
+			t.Errorf("wrong resolved path. expected=%q, got=%q", tt.expected, resolved)
+		}
+	}
+
+	for _, path := range []string{"missing.qrk", "x.txt", "lib"} {
+		_, err := loader.Resolve(path, dir)
+		if err == nil {
+			t.Fatalf("expected error resolving %q, got none", path)
+		}
+
+		expected := "module not found: " + path
+		if err.Error() != expected {
+			t.Errorf("wrong error. expected=%q, got=%q", expected, err.Error())
+		}
+	}
+}
+
+func TestLoadRelativeToImporter(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.qrk":      `import "lib/a.qrk" as a;`,
+		"lib/a.qrk":     `import "b.qrk" as b;`,
+		"lib/b.qrk":     "",
+		"b.qrk":         "",
+		"lib/other.qrk": "",
+	})
+
+	loader := NewLoader[string](filepath.Join(dir, "main.qrk"))
+	build := loadNested(loader, map[string][]string{"a.qrk": {"b.qrk"}})
+
+	if _, err := loader.Load("lib/a.qrk", build); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, ok := loader.modules[filepath.Join(dir, "lib/b.qrk")]; !ok {
+		t.Errorf("b.qrk imported by lib/a.qrk is not resolved relative to lib/")
+	}
+
+	if _, ok := loader.modules[filepath.Join(dir, "b.qrk")]; ok {
+		t.Errorf("b.qrk imported by lib/a.qrk is resolved relative to the main file")
+	}
+}
+
+func TestLoadCachesModules(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.qrk": "",
+		"lib.qrk":  "export let x = 1;",
+	})
+
+	loader := NewLoader[int](filepath.Join(dir, "main.qrk"))
+
+	builds := 0
+	build := func(path string, program *ast.Program) (int, error) {
+		builds++
+
+		names := ExportedNames(program)
+		if len(names) != 1 || names[0] != "x" {
+			t.Errorf("wrong exported names. expected=[x], got=%v", names)
+		}
+
+		return builds, nil
+	}
+
+	for _, path := range []string{"lib.qrk", "./lib.qrk", filepath.Join(dir, "lib.qrk")} {
+		module, err := loader.Load(path, build)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if module != 1 {
+			t.Errorf("module %q is built again", path)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.qrk":   `import "a.qrk" as a;`,
+		"a.qrk":      `import "b.qrk" as b;`,
+		"b.qrk":      `import "main.qrk" as m;`,
+		"self.qrk":   `import "self.qrk" as s;`,
+		"broken.qrk": "let = 1;",
+	})
+
+	main := filepath.Join(dir, "main.qrk")
+	a := filepath.Join(dir, "a.qrk")
+	b := filepath.Join(dir, "b.qrk")
+	self := filepath.Join(dir, "self.qrk")
+	broken := filepath.Join(dir, "broken.qrk")
+
+	imports := map[string][]string{
+		"a.qrk":    {"b.qrk"},
+		"b.qrk":    {"main.qrk"},
+		"self.qrk": {"self.qrk"},
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"a.qrk", "import cycle: " + main + " -> " + a + " -> " + b + " -> " + main},
+		{"self.qrk", "import cycle: " + self + " -> " + self},
+		{"broken.qrk", "syntax error in module " + broken + ": expected next token to be IDENT, got = instead; no prefix parse function for = found"},
+	}
+
+	for _, tt := range tests {
+		loader := NewLoader[string](main)
+
+		_, err := loader.Load(tt.path, loadNested(loader, imports))
+		if err == nil {
+			t.Fatalf("expected error loading %q, got none", tt.path)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, err.Error())
+		}
+
+		if len(loader.loading) != 1 {
+			t.Errorf("loader is left loading %v", loader.loading)
+		}
+	}
+}
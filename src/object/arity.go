@@ -0,0 +1,54 @@
+package object
+
+import (
+	"fmt"
+
+	"github.com/vdchnsk/qrk/src/ast"
+)
+
+// VariadicArity is the maximum amount of arguments of functions accepting any amount of them
+const VariadicArity = -1
+
+// Arity is the range of amounts of arguments a function can be called with
+type Arity struct {
+	Min int
+	Max int
+}
+
+func ExactArity(paramsCount int) Arity {
+	return Arity{Min: paramsCount, Max: paramsCount}
+}
+
+// FuncArity is the arity of the function declared by the literal, parameters with default values are optional
+func FuncArity(node *ast.FuncLiteral) Arity {
+	arity := Arity{Min: node.RequiredParamsCount(), Max: len(node.Parameters)}
+
+	if node.Rest != nil {
+		arity.Max = VariadicArity
+	}
+
+	return arity
+}
+
+func (a Arity) Accepts(argsCount int) bool {
+	return argsCount >= a.Min && (a.Max == VariadicArity || argsCount <= a.Max)
+}
+
+func (a Arity) String() string {
+	switch {
+	case a.Max == VariadicArity:
+		return fmt.Sprintf("at least %d", a.Min)
+	case a.Min == a.Max:
+		return fmt.Sprintf("%d", a.Min)
+	default:
+		return fmt.Sprintf("%d..%d", a.Min, a.Max)
+	}
+}
+
+// WrongArgumentsCountMessage describes the call of the function with the amount of arguments its arity doesn't accept
+func WrongArgumentsCountMessage(function string, arity Arity, argsCount int) string {
+	return fmt.Sprintf(
+		"wrong number of arguments passed to %s, expected=%s, got=%d",
+		FrameName(function), arity, argsCount,
+	)
+}
@@ -0,0 +1,153 @@
+package object
+
+// Channel passes values between tasks, a send waits until the value is received,
+// unless there is room for it in the buffer of the channel
+type Channel struct {
+	capacity int
+	buffer   []Object
+	// the values left in the buffer of a closed channel can still be received
+	closed bool
+
+	// tasks waiting to receive, and tasks waiting to send while the buffer is full, in the order they started waiting
+	receivers []queuedWaiter
+	senders   []queuedWaiter
+}
+
+func NewChannel(capacity int) *Channel {
+	return &Channel{capacity: capacity}
+}
+
+func (ch *Channel) Type() ObjectType { return CHANNEL_OBJ }
+func (ch *Channel) Inspect() string  { return "<channel>" }
+
+// Send waits until the value is received or buffered, ok is false once the channel is closed.
+// It fails with a deadlock error if no other task is left to receive the value
+func (ch *Channel) Send(value Object) (ok bool, err *Error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	if ch.closed {
+		return false, nil
+	}
+
+	if receiver, ok := popWaiter(&ch.receivers); ok {
+		receiver.wakeUp(value, true, receiver.index)
+		return true, nil
+	}
+
+	if len(ch.buffer) < ch.capacity {
+		ch.buffer = append(ch.buffer, value)
+		return true, nil
+	}
+
+	sender := newWaiter()
+	sender.sending = value
+	ch.senders = append(ch.senders, queuedWaiter{waiter: sender})
+
+	if err := sender.park("send on channel with no receivers"); err != nil {
+		ch.senders = removeWaiter(ch.senders, sender)
+		return false, err
+	}
+
+	return sender.ok, nil
+}
+
+// Recv waits for the next value, ok is false once the channel is closed and its buffer is drained.
+// It fails with a deadlock error if no other task is left to send a value
+func (ch *Channel) Recv() (value Object, ok bool, err *Error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	if value, ok, ready := ch.tryRecv(); ready {
+		return value, ok, nil
+	}
+
+	receiver := newWaiter()
+	ch.receivers = append(ch.receivers, queuedWaiter{waiter: receiver})
+
+	if err := receiver.park("recv on channel with no senders"); err != nil {
+		ch.receivers = removeWaiter(ch.receivers, receiver)
+		return nil, false, err
+	}
+
+	return receiver.value, receiver.ok, nil
+}
+
+// tryRecv receives without waiting, ready is false if the receiver would have to wait
+func (ch *Channel) tryRecv() (value Object, ok bool, ready bool) {
+	if len(ch.buffer) > 0 {
+		value = ch.buffer[0]
+		ch.buffer = ch.buffer[1:]
+
+		// the buffer has room again for the value of the first waiting sender
+		if sender, ok := popWaiter(&ch.senders); ok {
+			ch.buffer = append(ch.buffer, sender.sending)
+			sender.wakeUp(nil, true, 0)
+		}
+
+		return value, true, true
+	}
+
+	if sender, ok := popWaiter(&ch.senders); ok {
+		sender.wakeUp(nil, true, 0)
+		return sender.sending, true, true
+	}
+
+	if ch.closed {
+		return nil, false, true
+	}
+
+	return nil, false, false
+}
+
+// Close wakes up the tasks waiting on the channel, it fails if the channel is already closed
+func (ch *Channel) Close() bool {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	if ch.closed {
+		return false
+	}
+
+	ch.closed = true
+
+	for receiver, ok := popWaiter(&ch.receivers); ok; receiver, ok = popWaiter(&ch.receivers) {
+		receiver.wakeUp(nil, false, receiver.index)
+	}
+	for sender, ok := popWaiter(&ch.senders); ok; sender, ok = popWaiter(&ch.senders) {
+		sender.wakeUp(nil, false, 0)
+	}
+
+	return true
+}
+
+// Select waits for the first of the channels to have a value, or to be closed,
+// ok is false if the chosen channel is closed and its buffer is drained.
+// It fails with a deadlock error if no other task is left to send to any of the channels
+func Select(channels []*Channel) (chosen int, value Object, ok bool, err *Error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	for i, ch := range channels {
+		if value, ok, ready := ch.tryRecv(); ready {
+			return i, value, ok, nil
+		}
+	}
+
+	receiver := newWaiter()
+	for i, ch := range channels {
+		ch.receivers = append(ch.receivers, queuedWaiter{waiter: receiver, index: i})
+	}
+
+	err = receiver.park("select on channels with no senders")
+
+	for _, ch := range channels {
+		ch.receivers = removeWaiter(ch.receivers, receiver)
+	}
+
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	return receiver.chosen, receiver.value, receiver.ok, nil
+}
@@ -0,0 +1,134 @@
+package object
+
+import "maps"
+
+// Copier copies the values handed over to another task, so that tasks share no mutable values
+// and channels stay the only way for them to communicate. Values reachable from several of the copied
+// values are copied once, so the copies keep referring to each other like the originals do
+type Copier struct {
+	objects map[Object]Object
+	envs    map[*Environment]*Environment
+}
+
+func NewCopier() *Copier {
+	return &Copier{
+		objects: map[Object]Object{},
+		envs:    map[*Environment]*Environment{},
+	}
+}
+
+// Copy copies the arrays, hashmaps and structs reachable from the value, functions are rebound to copies
+// of the environments and free variables they have captured. Values which are never mutated,
+// or which are safe to use from several tasks, like channels, are shared
+func (c *Copier) Copy(obj Object) Object {
+	if copied, ok := c.objects[obj]; ok {
+		return copied
+	}
+
+	switch obj := obj.(type) {
+	case *Array:
+		copied := &Array{Elements: make([]Object, len(obj.Elements))}
+		c.objects[obj] = copied
+
+		for i, element := range obj.Elements {
+			copied.Elements[i] = c.Copy(element)
+		}
+		return copied
+
+	case *HashMap:
+		copied := &HashMap{Pairs: make(map[HashKey]HashPair, len(obj.Pairs))}
+		c.objects[obj] = copied
+
+		for hashKey, pair := range obj.Pairs {
+			copied.Pairs[hashKey] = HashPair{Key: pair.Key, Value: c.Copy(pair.Value)}
+		}
+		return copied
+
+	case *Struct:
+		copied := &Struct{StructType: obj.StructType, Values: make([]Object, len(obj.Values))}
+		c.objects[obj] = copied
+
+		for i, value := range obj.Values {
+			copied.Values[i] = c.Copy(value)
+		}
+		return copied
+
+	case *Function:
+		copied := *obj
+		c.objects[obj] = &copied
+
+		copied.Env = c.CopyEnv(obj.Env)
+		return &copied
+
+	case *Closure:
+		copied := &Closure{Fn: obj.Fn, Free: make([]*Cell, len(obj.Free))}
+		c.objects[obj] = copied
+
+		for i, free := range obj.Free {
+			copied.Free[i] = c.Copy(free).(*Cell)
+		}
+		return copied
+
+	case *Cell:
+		copied := &Cell{}
+		c.objects[obj] = copied
+
+		copied.Value = c.Copy(obj.Value)
+		return copied
+
+	case *Module:
+		copied := *obj
+		c.objects[obj] = &copied
+
+		copied.Env = c.CopyEnv(obj.Env)
+		return &copied
+
+	case *Generator:
+		// the body of a generator runs in the task which has created it, other tasks can't resume it
+		copied := NewGenerator(obj.Name, func() (Object, bool, *Error) {
+			return nil, false, &Error{Message: "generator " + FrameName(obj.Name) + " belongs to another task"}
+		})
+		copied.done = obj.done
+		c.objects[obj] = copied
+		return copied
+
+	default:
+		return obj
+	}
+}
+
+// CopyError copies the value thrown with the error, the rest of the error is never mutated
+func (c *Copier) CopyError(err *Error) *Error {
+	if err == nil || err.Value == nil {
+		return err
+	}
+
+	copied := *err
+	copied.Value = c.Copy(err.Value)
+
+	return &copied
+}
+
+// CopyEnv copies the environment and the ones enclosing it, along with the values bound in them
+func (c *Copier) CopyEnv(env *Environment) *Environment {
+	if env == nil {
+		return nil
+	}
+
+	if copied, ok := c.envs[env]; ok {
+		return copied
+	}
+
+	copied := *env
+	c.envs[env] = &copied
+
+	copied.outer = c.CopyEnv(env.outer)
+	copied.consts = maps.Clone(env.consts)
+	copied.store = make(map[string]Object, len(env.store))
+
+	for ident, value := range env.store {
+		copied.store[ident] = c.Copy(value)
+	}
+
+	return &copied
+}
@@ -0,0 +1,47 @@
+package object
+
+const (
+	MainFrameName      = "<main>"
+	AnonymousFrameName = "<anonymous>"
+)
+
+// FrameName is the name a function is shown with in stack traces
+func FrameName(function string) string {
+	if function == "" {
+		return AnonymousFrameName
+	}
+
+	return function
+}
+
+// Exception is a runtime error caught by `catch`, it exposes its message and stack trace
+// as the "message" and "stack" keys, e.g. `e["message"]` or `e.message`
+type Exception struct {
+	Message    string
+	StackTrace []string
+}
+
+func (ex *Exception) Type() ObjectType { return EXCEPTION_OBJ }
+func (ex *Exception) Inspect() string  { return "Exception: " + ex.Message }
+
+// Field looks up the field of the exception by its key
+func (ex *Exception) Field(key Object) (Object, bool) {
+	str, ok := key.(*String)
+	if !ok {
+		return nil, false
+	}
+
+	switch str.Value {
+	case "message":
+		return &String{Value: ex.Message}, true
+	case "stack":
+		frames := make([]Object, len(ex.StackTrace))
+		for i, frame := range ex.StackTrace {
+			frames[i] = &String{Value: frame}
+		}
+
+		return &Array{Elements: frames}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,156 @@
+package object
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Generator is created by calling a generator function declared with `fn*`,
+// its body runs up to the next `yield` every time the generator is resumed
+type Generator struct {
+	Name string
+
+	// runs the body up to the next yield, provided by the backend executing the body
+	resume func() (value Object, ok bool, err *Error)
+
+	index   int64
+	running bool
+	done    bool
+	err     *Error
+}
+
+// NewGenerator creates the generator of the function, the body is run by resume,
+// which reports the yielded value, or that the body has finished
+func NewGenerator(name string, resume func() (Object, bool, *Error)) *Generator {
+	return &Generator{Name: name, resume: resume}
+}
+
+func (g *Generator) Type() ObjectType { return GENERATOR_OBJ }
+func (g *Generator) Inspect() string  { return "<generator " + FrameName(g.Name) + ">" }
+
+// Resume runs the body up to the next yield, ok is false once the body has finished, or has failed with err
+func (g *Generator) Resume() (value Object, ok bool, err *Error) {
+	if g.done {
+		return nil, false, nil
+	}
+
+	if g.running {
+		return nil, false, &Error{Message: "generator " + FrameName(g.Name) + " is already running"}
+	}
+
+	g.running = true
+	value, ok, err = g.resume()
+	g.running = false
+
+	if !ok || err != nil {
+		g.done = true
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+// Next makes generators iterable, the index of the yielded value is its key
+func (g *Generator) Next() (Object, Object, bool) {
+	value, ok, err := g.Resume()
+	if err != nil {
+		g.err = err
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	key := &Integer{Value: g.index}
+	g.index++
+
+	return key, value, true
+}
+
+func (g *Generator) Err() *Error {
+	return g.err
+}
+
+// Coroutine runs the body of a generator function in the evaluator on its own goroutine,
+// the goroutine is parked at every `yield` until the generator is resumed again,
+// or until the coroutine is abandoned, which makes the goroutine exit without running the rest of the body
+type Coroutine struct {
+	body    func() *Error
+	started bool
+
+	resume      chan struct{}
+	steps       chan coroutineStep
+	abandon     chan struct{}
+	abandonOnce sync.Once
+}
+
+type coroutineStep struct {
+	value Object
+	done  bool
+	err   *Error
+	// panic of the body, raised again on the goroutine resuming the generator
+	panicked any
+}
+
+func NewCoroutine(body func() *Error) *Coroutine {
+	return &Coroutine{
+		body:    body,
+		resume:  make(chan struct{}),
+		steps:   make(chan coroutineStep),
+		abandon: make(chan struct{}),
+	}
+}
+
+// NewCoroutineGenerator creates the generator resuming the coroutine, the coroutine of a generator
+// which is dropped before its body finishes is abandoned once the generator is garbage collected
+func NewCoroutineGenerator(name string, co *Coroutine) *Generator {
+	generator := NewGenerator(name, co.Resume)
+	runtime.AddCleanup(generator, (*Coroutine).Abandon, co)
+
+	return generator
+}
+
+// Resume runs the body until it yields or finishes, it fits NewGenerator
+func (co *Coroutine) Resume() (Object, bool, *Error) {
+	if co.started {
+		co.resume <- struct{}{}
+	} else {
+		co.started = true
+		go co.run()
+	}
+
+	step := <-co.steps
+	if step.panicked != nil {
+		panic(step.panicked)
+	}
+
+	return step.value, !step.done, step.err
+}
+
+// Abandon lets the goroutine parked at `yield` exit, the coroutine must not be resumed afterwards
+func (co *Coroutine) Abandon() {
+	co.abandonOnce.Do(func() { close(co.abandon) })
+}
+
+func (co *Coroutine) run() {
+	defer func() {
+		// runtime.Goexit of an abandoned coroutine isn't a panic, recover reports nil for it
+		if recovered := recover(); recovered != nil {
+			co.steps <- coroutineStep{done: true, panicked: recovered}
+		}
+	}()
+
+	err := co.body()
+	co.steps <- coroutineStep{done: true, err: err}
+}
+
+// Yield hands the value over to the code resuming the generator, and waits for the next resumption
+func (co *Coroutine) Yield(value Object) {
+	co.steps <- coroutineStep{value: value}
+
+	select {
+	case <-co.resume:
+	case <-co.abandon:
+		runtime.Goexit()
+	}
+}
@@ -0,0 +1,297 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Integer arithmetic is done on int64 while it fits, results which overflow are promoted to BigInteger,
+// and BigInteger results which fit into int64 are demoted back to Integer
+
+var (
+	ErrDivisionByZero     = errors.New("division by zero")
+	ErrNegativeShiftCount = errors.New("negative shift count")
+	ErrShiftCountTooLarge = fmt.Errorf("shift count is greater than %d", maxShiftCount)
+	ErrPowResultTooLarge  = fmt.Errorf("result of exponentiation is larger than %d bits", maxPowBits)
+)
+
+// maxShiftCount guards against accidentally allocating huge BigIntegers, e.g. `1 << 1000000000000`
+const maxShiftCount = 1 << 16
+
+// maxPowBits does the same for exponentiation, e.g. `10 ** 1000000000000`
+const maxPowBits = maxShiftCount
+
+// NewBigInteger wraps value into the smallest integral object it fits in
+func NewBigInteger(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+
+	return &BigInteger{Value: value}
+}
+
+func IsIntegral(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInteger:
+		return true
+	default:
+		return false
+	}
+}
+
+func ToBigInt(obj Object) *big.Int {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value)
+	case *BigInteger:
+		return obj.Value
+	default:
+		return nil
+	}
+}
+
+func AddIntegers(left, right Object) Object {
+	leftInt, rightInt, ok := bothInt64(left, right)
+	if ok {
+		result := leftInt + rightInt
+
+		overflowed := (leftInt^result)&(rightInt^result) < 0
+		if !overflowed {
+			return &Integer{Value: result}
+		}
+	}
+
+	return NewBigInteger(new(big.Int).Add(ToBigInt(left), ToBigInt(right)))
+}
+
+func SubIntegers(left, right Object) Object {
+	leftInt, rightInt, ok := bothInt64(left, right)
+	if ok {
+		result := leftInt - rightInt
+
+		overflowed := (leftInt^rightInt)&(leftInt^result) < 0
+		if !overflowed {
+			return &Integer{Value: result}
+		}
+	}
+
+	return NewBigInteger(new(big.Int).Sub(ToBigInt(left), ToBigInt(right)))
+}
+
+func MulIntegers(left, right Object) Object {
+	leftInt, rightInt, ok := bothInt64(left, right)
+	if ok {
+		if leftInt == 0 || rightInt == 0 {
+			return &Integer{Value: 0}
+		}
+
+		result := leftInt * rightInt
+
+		overflowed := result/rightInt != leftInt ||
+			(leftInt == -1 && rightInt == math.MinInt64) ||
+			(rightInt == -1 && leftInt == math.MinInt64)
+		if !overflowed {
+			return &Integer{Value: result}
+		}
+	}
+
+	return NewBigInteger(new(big.Int).Mul(ToBigInt(left), ToBigInt(right)))
+}
+
+// DivIntegers truncates towards zero, the same way int64 division does
+func DivIntegers(left, right Object) (Object, error) {
+	if ToBigInt(right).Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+
+	leftInt, rightInt, ok := bothInt64(left, right)
+	if ok {
+		overflowed := leftInt == math.MinInt64 && rightInt == -1
+		if !overflowed {
+			return &Integer{Value: leftInt / rightInt}, nil
+		}
+	}
+
+	return NewBigInteger(new(big.Int).Quo(ToBigInt(left), ToBigInt(right))), nil
+}
+
+// ModIntegers takes the sign of the dividend, the same way int64 remainder does
+func ModIntegers(left, right Object) (Object, error) {
+	if ToBigInt(right).Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+
+	leftInt, rightInt, ok := bothInt64(left, right)
+	if ok {
+		if rightInt == -1 {
+			return &Integer{Value: 0}, nil
+		}
+
+		return &Integer{Value: leftInt % rightInt}, nil
+	}
+
+	return NewBigInteger(new(big.Int).Rem(ToBigInt(left), ToBigInt(right))), nil
+}
+
+// PowIntegers results in a Float for negative exponents, e.g. `2 ** -1` is 0.5
+func PowIntegers(base, exponent Object) (Object, error) {
+	exponentInt := ToBigInt(exponent)
+
+	if exponentInt.Sign() < 0 {
+		return &Float{Value: math.Pow(IntegerToFloat(base), IntegerToFloat(exponent))}, nil
+	}
+
+	baseInt := ToBigInt(base)
+	if powBits(baseInt, exponentInt) > maxPowBits {
+		return nil, ErrPowResultTooLarge
+	}
+
+	return NewBigInteger(new(big.Int).Exp(baseInt, exponentInt, nil)), nil
+}
+
+// powBits estimates the bit length of the power without computing it
+func powBits(base, exponent *big.Int) float64 {
+	// 0, 1 and -1 stay that small whatever the exponent is
+	if base.CmpAbs(big.NewInt(1)) <= 0 {
+		return 0
+	}
+
+	baseFloat, _ := new(big.Float).SetInt(base).Float64()
+	exponentFloat, _ := new(big.Float).SetInt(exponent).Float64()
+
+	return exponentFloat * math.Log2(math.Abs(baseFloat))
+}
+
+func BitAndIntegers(left, right Object) Object {
+	leftInt, rightInt, ok := bothInt64(left, right)
+	if ok {
+		return &Integer{Value: leftInt & rightInt}
+	}
+
+	return NewBigInteger(new(big.Int).And(ToBigInt(left), ToBigInt(right)))
+}
+
+func BitOrIntegers(left, right Object) Object {
+	leftInt, rightInt, ok := bothInt64(left, right)
+	if ok {
+		return &Integer{Value: leftInt | rightInt}
+	}
+
+	return NewBigInteger(new(big.Int).Or(ToBigInt(left), ToBigInt(right)))
+}
+
+func BitXorIntegers(left, right Object) Object {
+	leftInt, rightInt, ok := bothInt64(left, right)
+	if ok {
+		return &Integer{Value: leftInt ^ rightInt}
+	}
+
+	return NewBigInteger(new(big.Int).Xor(ToBigInt(left), ToBigInt(right)))
+}
+
+func BitNotInteger(obj Object) Object {
+	if integer, ok := obj.(*Integer); ok {
+		return &Integer{Value: ^integer.Value}
+	}
+
+	return NewBigInteger(new(big.Int).Not(ToBigInt(obj)))
+}
+
+// ShiftLeftIntegers promotes to BigInteger instead of dropping the bits shifted out of int64
+func ShiftLeftIntegers(value, count Object) (Object, error) {
+	shift, err := shiftCount(count)
+	if err != nil {
+		return nil, err
+	}
+
+	integer, ok := value.(*Integer)
+	if ok && shift < 63 {
+		result := integer.Value << shift
+
+		overflowed := result>>shift != integer.Value
+		if !overflowed {
+			return &Integer{Value: result}, nil
+		}
+	}
+
+	return NewBigInteger(new(big.Int).Lsh(ToBigInt(value), shift)), nil
+}
+
+// ShiftRightIntegers is an arithmetic shift, so negative values stay negative
+func ShiftRightIntegers(value, count Object) (Object, error) {
+	shift, err := shiftCount(count)
+	if err != nil {
+		return nil, err
+	}
+
+	if integer, ok := value.(*Integer); ok {
+		return &Integer{Value: integer.Value >> shift}, nil
+	}
+
+	return NewBigInteger(new(big.Int).Rsh(ToBigInt(value), shift)), nil
+}
+
+func shiftCount(count Object) (uint, error) {
+	integer, ok := count.(*Integer)
+	if !ok || integer.Value > maxShiftCount {
+		return 0, ErrShiftCountTooLarge
+	}
+
+	if integer.Value < 0 {
+		return 0, ErrNegativeShiftCount
+	}
+
+	return uint(integer.Value), nil
+}
+
+func NegateInteger(obj Object) Object {
+	integer, ok := obj.(*Integer)
+	if ok && integer.Value != math.MinInt64 {
+		return &Integer{Value: -integer.Value}
+	}
+
+	return NewBigInteger(new(big.Int).Neg(ToBigInt(obj)))
+}
+
+// CompareIntegers returns -1, 0 or +1 depending on whether left is less than, equal to or greater than right
+func CompareIntegers(left, right Object) int {
+	leftInt, rightInt, ok := bothInt64(left, right)
+	if !ok {
+		return ToBigInt(left).Cmp(ToBigInt(right))
+	}
+
+	switch {
+	case leftInt < rightInt:
+		return -1
+	case leftInt > rightInt:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IntegerToFloat converts an integral object to float64, rounding to the nearest representable value
+func IntegerToFloat(obj Object) float64 {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value)
+	case *BigInteger:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	default:
+		return 0
+	}
+}
+
+func bothInt64(left, right Object) (int64, int64, bool) {
+	leftInt, leftOk := left.(*Integer)
+	rightInt, rightOk := right.(*Integer)
+
+	if !leftOk || !rightOk {
+		return 0, 0, false
+	}
+
+	return leftInt.Value, rightInt.Value, true
+}
@@ -0,0 +1,172 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Iterator walks over the elements of an iterable object,
+// every step produces a key (index for sequences) and a value
+type Iterator interface {
+	Object
+	Next() (key Object, value Object, ok bool)
+}
+
+// FallibleIterator is an iterator whose steps can fail, e.g. the body of a generator can throw,
+// Err reports the failure the iteration stopped at
+type FallibleIterator interface {
+	Iterator
+	Err() *Error
+}
+
+// NewIterator creates an iterator for the given object, if the object is iterable
+func NewIterator(obj Object) (Iterator, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		return &ArrayIterator{array: obj}, true
+	case *String:
+		return &StringIterator{chars: []rune(obj.Value)}, true
+	case *HashMap:
+		return newHashMapIterator(obj), true
+	case *Range:
+		return &RangeIterator{rng: obj, current: obj.Start}, true
+	case Iterator:
+		return obj, true
+	default:
+		return nil, false
+	}
+}
+
+// SingleBinding picks the value bound by the single variable form of `for x in ...`,
+// hashmaps bind their keys, while every other iterable binds its elements
+func SingleBinding(iterator Iterator, key, value Object) Object {
+	if _, ok := iterator.(*HashMapIterator); ok {
+		return key
+	}
+
+	return value
+}
+
+type ArrayIterator struct {
+	array *Array
+	index int
+}
+
+func (ai *ArrayIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (ai *ArrayIterator) Inspect() string  { return fmt.Sprintf("ArrayIterator[%p]", ai) }
+func (ai *ArrayIterator) Next() (Object, Object, bool) {
+	if ai.index >= len(ai.array.Elements) {
+		return nil, nil, false
+	}
+
+	key := &Integer{Value: int64(ai.index)}
+	value := ai.array.Elements[ai.index]
+	ai.index++
+
+	return key, value, true
+}
+
+type StringIterator struct {
+	chars []rune
+	index int
+}
+
+func (si *StringIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (si *StringIterator) Inspect() string  { return fmt.Sprintf("StringIterator[%p]", si) }
+func (si *StringIterator) Next() (Object, Object, bool) {
+	if si.index >= len(si.chars) {
+		return nil, nil, false
+	}
+
+	key := &Integer{Value: int64(si.index)}
+	value := &String{Value: string(si.chars[si.index])}
+	si.index++
+
+	return key, value, true
+}
+
+type HashMapIterator struct {
+	pairs []HashPair
+	index int
+}
+
+// hashmap is snapshotted and sorted by keys, so the iteration order is stable between runs
+func newHashMapIterator(hashMap *HashMap) *HashMapIterator {
+	pairs := make([]HashPair, 0, len(hashMap.Pairs))
+	for _, pair := range hashMap.Pairs {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return lessKey(pairs[i].Key, pairs[j].Key)
+	})
+
+	return &HashMapIterator{pairs: pairs}
+}
+
+func lessKey(left, right Object) bool {
+	if left.Type() != right.Type() {
+		return left.Type() < right.Type()
+	}
+
+	leftInt, isLeftInt := left.(*Integer)
+	rightInt, isRightInt := right.(*Integer)
+	if isLeftInt && isRightInt {
+		return leftInt.Value < rightInt.Value
+	}
+
+	return left.Inspect() < right.Inspect()
+}
+
+func (hi *HashMapIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (hi *HashMapIterator) Inspect() string  { return fmt.Sprintf("HashMapIterator[%p]", hi) }
+func (hi *HashMapIterator) Next() (Object, Object, bool) {
+	if hi.index >= len(hi.pairs) {
+		return nil, nil, false
+	}
+
+	pair := hi.pairs[hi.index]
+	hi.index++
+
+	return pair.Key, pair.Value, true
+}
+
+// Range is a lazy sequence of integers, elements are produced only while iterating
+type Range struct {
+	Start int64
+	End   int64
+	Step  int64
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+type RangeIterator struct {
+	rng     *Range
+	current int64
+	index   int64
+	// set once the next element doesn't fit into int64, so it would be past the end anyway
+	overflowed bool
+}
+
+func (ri *RangeIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (ri *RangeIterator) Inspect() string  { return fmt.Sprintf("RangeIterator[%p]", ri) }
+func (ri *RangeIterator) Next() (Object, Object, bool) {
+	isExhausted := (ri.rng.Step > 0 && ri.current >= ri.rng.End) || (ri.rng.Step < 0 && ri.current <= ri.rng.End)
+	if isExhausted || ri.overflowed {
+		return nil, nil, false
+	}
+
+	key := &Integer{Value: ri.index}
+	value := &Integer{Value: ri.current}
+
+	ri.overflowed = (ri.rng.Step > 0 && ri.current > math.MaxInt64-ri.rng.Step) ||
+		(ri.rng.Step < 0 && ri.current < math.MinInt64-ri.rng.Step)
+	ri.current += ri.rng.Step
+	ri.index++
+
+	return key, value, true
+}
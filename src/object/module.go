@@ -0,0 +1,46 @@
+package object
+
+import (
+	"github.com/vdchnsk/qrk/src/module"
+	"github.com/vdchnsk/qrk/src/utils"
+)
+
+// Module is the namespace bound by an import, it exposes the exported bindings of the module's top-level environment
+type Module struct {
+	Path    string
+	Env     *Environment
+	Exports []string
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "<module " + m.Path + ">" }
+
+// Export looks up the current value of the exported binding
+func (m *Module) Export(name string) (Object, bool) {
+	if !utils.Contains(m.Exports, name) {
+		return nil, false
+	}
+
+	return m.Env.Get(name)
+}
+
+// NewModuleEnvironment creates the top-level environment of a file, the file's imports are loaded with the loader
+func NewModuleEnvironment(loader *module.Loader[*Module]) *Environment {
+	env := NewEnvironment()
+	env.loader = loader
+
+	return env
+}
+
+// ModuleLoader returns the loader of the file the environment belongs to
+func (env *Environment) ModuleLoader() *module.Loader[*Module] {
+	for env.outer != nil {
+		env = env.outer
+	}
+
+	if env.loader == nil {
+		env.loader = module.NewLoader[*Module]("")
+	}
+
+	return env.loader
+}
@@ -4,26 +4,46 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
 
 	"github.com/vdchnsk/qrk/src/ast"
 	"github.com/vdchnsk/qrk/src/code"
+	"github.com/vdchnsk/qrk/src/module"
 )
 
 type ObjectType string
 
 const (
 	INTEGER_OBJ       = "INTEGER"
+	BIG_INTEGER_OBJ   = "BIG_INTEGER"
+	FLOAT_OBJ         = "FLOAT"
 	BOOLEAN_OBJ       = "BOOLEAN"
 	NULL_OBJ          = "NULL"
 	RETURN_OBJ        = "RETURN"
+	BREAK_OBJ         = "BREAK"
+	CONTINUE_OBJ      = "CONTINUE"
 	ERROR_OBJ         = "ERROR"
 	FUNC_OBJ          = "FUNCTION"
 	COMPILED_FUNC_OBJ = "COMPILED_FUNCTION"
+	CLOSURE_OBJ       = "CLOSURE"
+	CELL_OBJ          = "CELL"
+	GOTO_TABLE_OBJ    = "GOTO_TABLE"
 	BUILT_IN_OBJ      = "BUILT_IN"
 	STRING_OBJ        = "STRING"
 	ARRAY_OBJ         = "ARRAY"
 	HASH_MAP_OBJ      = "HASH_MAP"
+	RANGE_OBJ         = "RANGE"
+	ITERATOR_OBJ      = "ITERATOR"
+	EXCEPTION_OBJ     = "EXCEPTION"
+	MODULE_OBJ        = "MODULE"
+	STRUCT_TYPE_OBJ   = "STRUCT_TYPE"
+	STRUCT_OBJ        = "STRUCT"
+	GENERATOR_OBJ     = "GENERATOR"
+	CHANNEL_OBJ       = "CHANNEL"
+	TASK_OBJ          = "TASK"
 )
 
 type Object interface {
@@ -31,13 +51,27 @@ type Object interface {
 	Inspect() string
 }
 
+// Error is a failure propagating through the evaluator, either a runtime error or a value thrown by `throw`
 type Error struct {
-	Message string
+	Message    string
+	Value      Object   // the thrown value, nil for runtime errors
+	StackTrace []string // functions being called when the error happened, innermost first
 }
 
 type Environment struct {
 	store map[string]Object
 	outer *Environment
+	// identifiers of the store bound by const
+	consts map[string]bool
+
+	// set for environments of function calls only
+	caller   *Environment
+	function string
+	// set for environments of generator function calls only
+	coroutine *Coroutine
+
+	// set for top-level environments of files only
+	loader *module.Loader[*Module]
 }
 
 type Hashable interface {
@@ -48,6 +82,38 @@ type HashKey struct {
 	Value int64
 }
 
+// SamePatternKey tells whether both objects have equal pattern keys,
+// it's how literal patterns are matched, so unlike `==` it never fails on mismatched types
+func SamePatternKey(a, b Object) bool {
+	keyA, ok := PatternKey(a)
+	if !ok {
+		return false
+	}
+
+	keyB, ok := PatternKey(b)
+	if !ok {
+		return false
+	}
+
+	return keyA == keyB
+}
+
+// PatternKey is the key literal patterns are matched by: the hash key of the value, except for floats holding
+// an integral value, which have the key of the same integer, so that `1` matches `1.0` just like they're equal with `==`
+func PatternKey(obj Object) (HashKey, bool) {
+	if float, ok := obj.(*Float); ok && !math.IsInf(float.Value, 0) && float.Value == math.Trunc(float.Value) {
+		integer, _ := big.NewFloat(float.Value).Int(nil)
+		obj = NewBigInteger(integer)
+	}
+
+	hashable, ok := obj.(Hashable)
+	if !ok {
+		return HashKey{}, false
+	}
+
+	return hashable.HashKey(), true
+}
+
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
 	return &Environment{store: s, outer: nil}
@@ -60,6 +126,53 @@ func NewEnclosedEnv(outer *Environment) *Environment {
 	return env
 }
 
+// NewCallEnv creates the environment of a call of the function, made from the caller environment
+func NewCallEnv(outer, caller *Environment, function string) *Environment {
+	env := NewEnclosedEnv(outer)
+	env.caller = caller
+	env.function = function
+
+	return env
+}
+
+// NewGeneratorEnv creates the environment of a call of the generator function, its body is run as the coroutine
+func NewGeneratorEnv(outer, caller *Environment, function string, body func(env *Environment) *Error) *Environment {
+	env := NewCallEnv(outer, caller, function)
+	env.coroutine = NewCoroutine(func() *Error { return body(env) })
+
+	return env
+}
+
+// Coroutine returns the coroutine of the generator function being called, nil outside of generator functions
+func (env *Environment) Coroutine() *Coroutine {
+	for env != nil {
+		if env.caller != nil {
+			return env.coroutine
+		}
+
+		env = env.outer
+	}
+
+	return nil
+}
+
+// StackTrace lists the functions being called while the environment is active, innermost first
+func (env *Environment) StackTrace() []string {
+	trace := []string{}
+
+	for env != nil {
+		if env.caller != nil {
+			trace = append(trace, FrameName(env.function))
+			env = env.caller
+			continue
+		}
+
+		env = env.outer
+	}
+
+	return append(trace, MainFrameName)
+}
+
 func (env *Environment) Get(ident string) (Object, bool) {
 	val, ok := env.store[ident]
 	if !ok && env.outer != nil {
@@ -73,6 +186,47 @@ func (env *Environment) Put(ident string, val Object) Object {
 	return val
 }
 
+// PutConst binds the value like Put, but the binding can't be assigned to afterwards
+func (env *Environment) PutConst(ident string, val Object) Object {
+	if env.consts == nil {
+		env.consts = make(map[string]bool)
+	}
+	env.consts[ident] = true
+
+	return env.Put(ident, val)
+}
+
+// HasConst tells whether the identifier is bound by const in the environment, not in the enclosing ones
+func (env *Environment) HasConst(ident string) bool {
+	return env.consts[ident]
+}
+
+// IsConst tells whether the binding the identifier refers to is bound by const
+func (env *Environment) IsConst(ident string) bool {
+	for env != nil {
+		if _, ok := env.store[ident]; ok {
+			return env.consts[ident]
+		}
+
+		env = env.outer
+	}
+
+	return false
+}
+
+// Set overrides an existing binding in the environment it was defined in
+func (env *Environment) Set(ident string, val Object) (Object, bool) {
+	if _, ok := env.store[ident]; ok {
+		return env.Put(ident, val), true
+	}
+
+	if env.outer == nil {
+		return nil, false
+	}
+
+	return env.outer.Set(ident, val)
+}
+
 func (err *Error) Type() ObjectType { return ERROR_OBJ }
 func (err *Error) Inspect() string  { return "ERROR:" + err.Message }
 
@@ -86,6 +240,39 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: INTEGER_OBJ, Value: i.Value}
 }
 
+// BigInteger holds integers which don't fit into int64, see NewBigInteger
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (bi *BigInteger) Type() ObjectType { return BIG_INTEGER_OBJ }
+func (bi *BigInteger) Inspect() string  { return bi.Value.String() }
+func (bi *BigInteger) HashKey() HashKey {
+	hash := fnv.New64a()
+	hash.Write([]byte(bi.Value.String()))
+
+	return HashKey{Type: BIG_INTEGER_OBJ, Value: int64(hash.Sum64())}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	formatted := strconv.FormatFloat(f.Value, 'g', -1, 64)
+
+	// keep floats distinguishable from integers when printed, e.g. `2.0` instead of `2`
+	if !strings.ContainsAny(formatted, ".eIN") {
+		formatted += ".0"
+	}
+
+	return formatted
+}
+func (f *Float) HashKey() HashKey {
+	return HashKey{Type: FLOAT_OBJ, Value: int64(math.Float64bits(f.Value))}
+}
+
 type String struct {
 	Value string
 }
@@ -128,10 +315,28 @@ type ReturnWrapper struct {
 func (rw *ReturnWrapper) Type() ObjectType { return RETURN_OBJ }
 func (rw *ReturnWrapper) Inspect() string  { return fmt.Sprintf("%d", rw.Value) }
 
+// Break and Continue are signals that unwind evaluation up to the closest enclosing loop
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // default values of the parameters, nil for the required ones
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string
+	Arity      Arity
+	TakesSelf  bool // the first parameter is `self`, method calls pass the receiver to it
+	// declared with `fn*`, calling it creates a generator instead of running the body
+	IsGenerator bool
 }
 
 func (fn *Function) Type() ObjectType { return FUNC_OBJ }
@@ -139,8 +344,15 @@ func (fn *Function) Inspect() string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, p := range fn.Parameters {
-		params = append(params, p.String())
+	for i, p := range fn.Parameters {
+		if i < len(fn.Defaults) && fn.Defaults[i] != nil {
+			params = append(params, p.String()+" = "+fn.Defaults[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if fn.Rest != nil {
+		params = append(params, "..."+fn.Rest.String())
 	}
 
 	out.WriteString("fn")
@@ -156,7 +368,11 @@ func (fn *Function) Inspect() string {
 type CompiledFunction struct {
 	Instructions code.Instructions
 	LocalsCount  int
-	ParamsCount  int
+	ParamsCount  int // amount of the parameters, the rest parameter excluded
+	Arity        Arity
+	Name         string
+	TakesSelf    bool // the first parameter is `self`, method calls pass the receiver to it
+	IsGenerator  bool
 }
 
 func (cfn *CompiledFunction) Type() ObjectType { return COMPILED_FUNC_OBJ }
@@ -164,10 +380,54 @@ func (cfn *CompiledFunction) Inspect() string {
 	return fmt.Sprintf("CompiledFunction[%p]: %s", cfn, cfn.Instructions)
 }
 
+// Closure is a compiled function bundled with the cells of the free variables
+// it captured from its enclosing scopes
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []*Cell
+}
+
+func (c *Closure) Type() ObjectType { return CLOSURE_OBJ }
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
+// Cell holds a variable captured by closures, the scope declaring the variable and the closures
+// capturing it share the cell, so they see each other's assignments
+type Cell struct {
+	Value Object
+}
+
+func (c *Cell) Type() ObjectType { return CELL_OBJ }
+func (c *Cell) Inspect() string  { return c.Value.Inspect() }
+
+// TakesSelf tells whether a method call passes the receiver to the function as its first argument
+func TakesSelf(fn Object) bool {
+	switch fn := fn.(type) {
+	case *Function:
+		return fn.TakesSelf
+	case *Closure:
+		return fn.Fn.TakesSelf
+	default:
+		return false
+	}
+}
+
+// GotoTable is a jump table of a match expression, it maps the pattern keys of literal patterns
+// to the instruction positions of their arms
+type GotoTable struct {
+	Targets map[HashKey]int
+}
+
+func (gt *GotoTable) Type() ObjectType { return GOTO_TABLE_OBJ }
+func (gt *GotoTable) Inspect() string {
+	return fmt.Sprintf("GotoTable[%p]", gt)
+}
+
 type BuiltInFunction struct {
-	Name        string
-	ParamsCount int
-	Fn          func(args ...Object) Object
+	Name  string
+	Arity Arity
+	Fn    func(args ...Object) Object
 }
 
 func (fn *BuiltInFunction) Type() ObjectType { return BUILT_IN_OBJ }
@@ -195,6 +455,17 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+// Position resolves the index of an element, negative index counts from the end, e.g. -1 is the last element.
+// ok is false when the index is out of bounds
+func (a *Array) Position(index int64) (position int64, ok bool) {
+	length := int64(len(a.Elements))
+	if index < 0 {
+		index += length
+	}
+
+	return index, index >= 0 && index < length
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
@@ -1,6 +1,12 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestStringHashKey(t *testing.T) {
 	input1 := &String{Value: "Hello"}
@@ -42,6 +48,87 @@ func TestIntegerHashKey(t *testing.T) {
 	}
 }
 
+func TestIntegerOverflowPromotion(t *testing.T) {
+	maxInt := &Integer{Value: math.MaxInt64}
+	minInt := &Integer{Value: math.MinInt64}
+	one := &Integer{Value: 1}
+
+	divided, _ := DivIntegers(minInt, &Integer{Value: -1})
+	shifted, _ := ShiftLeftIntegers(maxInt, one)
+	powered, _ := PowIntegers(&Integer{Value: 2}, &Integer{Value: 64})
+
+	tests := []struct {
+		result   Object
+		expected string
+	}{
+		{AddIntegers(maxInt, one), "9223372036854775808"},
+		{SubIntegers(minInt, one), "-9223372036854775809"},
+		{MulIntegers(maxInt, &Integer{Value: 2}), "18446744073709551614"},
+		{MulIntegers(minInt, &Integer{Value: -1}), "9223372036854775808"},
+		{divided, "9223372036854775808"},
+		{NegateInteger(minInt), "9223372036854775808"},
+		{powered, "18446744073709551616"},
+		{shifted, "18446744073709551614"},
+	}
+
+	for _, tt := range tests {
+		result, ok := tt.result.(*BigInteger)
+		if !ok {
+			t.Errorf("result is not BigInteger. got=%T (%+v)", tt.result, tt.result)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("wrong result. expected=%s, got=%s", tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestBigIntegerDemotion(t *testing.T) {
+	promoted := AddIntegers(&Integer{Value: math.MaxInt64}, &Integer{Value: 1})
+
+	result, ok := SubIntegers(promoted, &Integer{Value: 1}).(*Integer)
+	if !ok {
+		t.Fatalf("result is not Integer. got=%T", result)
+	}
+	if result.Value != math.MaxInt64 {
+		t.Errorf("wrong result. expected=%d, got=%d", int64(math.MaxInt64), result.Value)
+	}
+}
+
+func TestBigIntegerHashKey(t *testing.T) {
+	input1 := NewBigInteger(new(big.Int).Lsh(big.NewInt(1), 70)).(*BigInteger)
+	input2 := NewBigInteger(new(big.Int).Lsh(big.NewInt(1), 70)).(*BigInteger)
+	input3 := NewBigInteger(new(big.Int).Neg(input1.Value)).(*BigInteger)
+
+	if input1.HashKey() != input2.HashKey() {
+		t.Errorf("hash keys of equal big integers %s and %s don't match", input1.Value, input2.Value)
+	}
+
+	if input1.HashKey() == input3.HashKey() {
+		t.Errorf("hash keys of different big integers match %s, %s", input1.Value, input3.Value)
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-3, "-3.0"},
+		{1e21, "1e+21"},
+		{0.1, "0.1"},
+	}
+
+	for _, tt := range tests {
+		float := &Float{Value: tt.value}
+		if float.Inspect() != tt.expected {
+			t.Errorf("float.Inspect() wrong. expected=%q, got=%q", tt.expected, float.Inspect())
+		}
+	}
+}
+
 func TestBooleanHashKey(t *testing.T) {
 	input1 := &Boolean{Value: true}
 	input2 := &Boolean{Value: true}
@@ -61,3 +148,334 @@ func TestBooleanHashKey(t *testing.T) {
 		)
 	}
 }
+
+func TestPatternKey(t *testing.T) {
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, &Float{Value: 1}, true},
+		{&Integer{Value: 0}, &Float{Value: math.Copysign(0, -1)}, true},
+		{&Integer{Value: 1}, &Float{Value: 1.5}, false},
+		{NewBigInteger(new(big.Int).Lsh(big.NewInt(1), 70)), &Float{Value: math.Ldexp(1, 70)}, true},
+		{&Float{Value: math.Inf(1)}, &Float{Value: math.Inf(1)}, true},
+		{&Integer{Value: 1}, &String{Value: "1"}, false},
+		{&Integer{Value: 1}, &Array{}, false},
+	}
+
+	for _, tt := range tests {
+		if SamePatternKey(tt.a, tt.b) != tt.expected {
+			t.Errorf("wrong match of %s and %s, expected=%t", tt.a.Inspect(), tt.b.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestRangeIterator(t *testing.T) {
+	tests := []struct {
+		rng      *Range
+		expected []int64
+	}{
+		{&Range{Start: 0, End: 3, Step: 1}, []int64{0, 1, 2}},
+		{&Range{Start: 0, End: 5, Step: 2}, []int64{0, 2, 4}},
+		{&Range{Start: 3, End: 0, Step: -1}, []int64{3, 2, 1}},
+		{&Range{Start: 3, End: 3, Step: 1}, []int64{}},
+		{&Range{Start: math.MaxInt64 - 1, End: math.MaxInt64, Step: 2}, []int64{math.MaxInt64 - 1}},
+		{&Range{Start: math.MinInt64 + 1, End: math.MinInt64, Step: -3}, []int64{math.MinInt64 + 1}},
+	}
+
+	for _, tt := range tests {
+		iterator, ok := NewIterator(tt.rng)
+		if !ok {
+			t.Fatalf("range is not iterable")
+		}
+
+		for i, expected := range tt.expected {
+			key, value, ok := iterator.Next()
+			if !ok {
+				t.Fatalf("%s exhausted too early at %d", tt.rng.Inspect(), i)
+			}
+
+			if key.(*Integer).Value != int64(i) {
+				t.Errorf("wrong key. expected=%d, got=%s", i, key.Inspect())
+			}
+
+			if value.(*Integer).Value != expected {
+				t.Errorf("wrong value. expected=%d, got=%s", expected, value.Inspect())
+			}
+		}
+
+		if _, _, ok := iterator.Next(); ok {
+			t.Errorf("%s was expected to be exhausted", tt.rng.Inspect())
+		}
+	}
+}
+
+func TestArity(t *testing.T) {
+	tests := []struct {
+		arity          Arity
+		expectedString string
+		accepted       []int
+		rejected       []int
+	}{
+		{ExactArity(0), "0", []int{0}, []int{1}},
+		{ExactArity(2), "2", []int{2}, []int{1, 3}},
+		{Arity{Min: 1, Max: 3}, "1..3", []int{1, 2, 3}, []int{0, 4}},
+		{Arity{Min: 1, Max: VariadicArity}, "at least 1", []int{1, 2, 100}, []int{0}},
+	}
+
+	for _, tt := range tests {
+		if tt.arity.String() != tt.expectedString {
+			t.Errorf("wrong arity string. expected=%q, got=%q", tt.expectedString, tt.arity.String())
+		}
+
+		for _, argsCount := range tt.accepted {
+			if !tt.arity.Accepts(argsCount) {
+				t.Errorf("arity %s doesn't accept %d arguments", tt.arity, argsCount)
+			}
+		}
+
+		for _, argsCount := range tt.rejected {
+			if tt.arity.Accepts(argsCount) {
+				t.Errorf("arity %s accepts %d arguments", tt.arity, argsCount)
+			}
+		}
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	steps := []Object{&Integer{Value: 10}, &Integer{Value: 20}}
+	failure := &Error{Message: "boom"}
+
+	resumes := 0
+	generator := NewGenerator("gen", func() (Object, bool, *Error) {
+		resumes++
+		if resumes > len(steps) {
+			return nil, false, failure
+		}
+		return steps[resumes-1], true, nil
+	})
+
+	for i, expected := range steps {
+		key, value, ok := generator.Next()
+		if !ok {
+			t.Fatalf("generator exhausted too early at %d", i)
+		}
+
+		if key.(*Integer).Value != int64(i) {
+			t.Errorf("wrong key. expected=%d, got=%s", i, key.Inspect())
+		}
+
+		if value != expected {
+			t.Errorf("wrong value. expected=%s, got=%s", expected.Inspect(), value.Inspect())
+		}
+	}
+
+	if _, _, ok := generator.Next(); ok {
+		t.Fatalf("generator was expected to be exhausted")
+	}
+
+	if generator.Err() != failure {
+		t.Errorf("wrong error. expected=%v, got=%v", failure, generator.Err())
+	}
+
+	if _, ok, err := generator.Resume(); ok || err != nil {
+		t.Errorf("finished generator is resumed again")
+	}
+
+	if resumes != len(steps)+1 {
+		t.Errorf("wrong amount of resumptions. expected=%d, got=%d", len(steps)+1, resumes)
+	}
+}
+
+func TestCoroutine(t *testing.T) {
+	exited := make(chan struct{})
+
+	var co *Coroutine
+	co = NewCoroutine(func() *Error {
+		defer close(exited)
+
+		for i := int64(0); ; i++ {
+			co.Yield(&Integer{Value: i})
+		}
+	})
+
+	for i := int64(0); i < 2; i++ {
+		value, ok, err := co.Resume()
+		if !ok || err != nil {
+			t.Fatalf("coroutine finished too early at %d", i)
+		}
+
+		if value.(*Integer).Value != i {
+			t.Errorf("wrong value. expected=%d, got=%s", i, value.Inspect())
+		}
+	}
+
+	co.Abandon()
+
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatalf("goroutine of the abandoned coroutine didn't exit")
+	}
+}
+
+func TestCoroutinePanic(t *testing.T) {
+	co := NewCoroutine(func() *Error {
+		panic("boom")
+	})
+
+	defer func() {
+		if recovered := recover(); recovered != "boom" {
+			t.Errorf("wrong panic. expected=%q, got=%v", "boom", recovered)
+		}
+	}()
+
+	co.Resume()
+	t.Fatalf("panic of the body wasn't raised by Resume")
+}
+
+func TestChannel(t *testing.T) {
+	ch := NewChannel(2)
+	one, two := &Integer{Value: 1}, &Integer{Value: 2}
+
+	sentOne, _ := ch.Send(one)
+	sentTwo, _ := ch.Send(two)
+	if !sentOne || !sentTwo {
+		t.Fatalf("sends to the buffer of the channel failed")
+	}
+
+	if !ch.Close() {
+		t.Fatalf("channel was already closed")
+	}
+
+	if ch.Close() {
+		t.Errorf("closed channel is closed again")
+	}
+
+	if sent, _ := ch.Send(one); sent {
+		t.Errorf("send to closed channel succeeded")
+	}
+
+	for _, expected := range []Object{one, two} {
+		value, ok, _ := ch.Recv()
+		if !ok || value != expected {
+			t.Errorf("wrong received value. expected=%s, got=%v", expected.Inspect(), value)
+		}
+	}
+
+	if _, ok, _ := ch.Recv(); ok {
+		t.Errorf("closed channel was expected to be drained")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	idle, busy := NewChannel(0), NewChannel(1)
+	value := &String{Value: "v"}
+	busy.Send(value)
+
+	chosen, received, ok, _ := Select([]*Channel{idle, busy})
+	if chosen != 1 || !ok || received != value {
+		t.Errorf("wrong selected channel. expected=1 with %s, got=%d with %v", value.Inspect(), chosen, received)
+	}
+
+	idle.Close()
+
+	chosen, _, ok, _ = Select([]*Channel{idle, NewChannel(0)})
+	if chosen != 0 || ok {
+		t.Errorf("closed channel was expected to be selected, got=%d", chosen)
+	}
+}
+
+func TestDeadlock(t *testing.T) {
+	ch := NewChannel(0)
+
+	if _, _, err := ch.Recv(); err == nil || err.Message != "deadlock: recv on channel with no senders" {
+		t.Errorf("wrong recv error. got=%v", err)
+	}
+
+	if _, err := ch.Send(&Integer{Value: 1}); err == nil || err.Message != "deadlock: send on channel with no receivers" {
+		t.Errorf("wrong send error. got=%v", err)
+	}
+
+	if _, _, _, err := Select([]*Channel{ch, NewChannel(1)}); err == nil {
+		t.Errorf("select on channels with no senders was expected to fail")
+	}
+
+	waiting := NewTask("wait", func() (Object, *Error) {
+		_, _, err := NewChannel(0).Recv()
+		return nil, err
+	})
+	// either the task or the joining code is the last one to start waiting, and fails
+	if _, err := waiting.Join(); err == nil || !strings.HasPrefix(err.Message, "deadlock: ") {
+		t.Errorf("wrong join error. got=%v", err)
+	}
+
+	// the channel is still usable once a task is around to receive
+	received := NewTask("recv", func() (Object, *Error) {
+		value, _, err := ch.Recv()
+		return value, err
+	})
+	if _, err := ch.Send(&Integer{Value: 2}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if result, err := received.Join(); err != nil || result.(*Integer).Value != 2 {
+		t.Errorf("wrong received value. got=%v, %v", result, err)
+	}
+}
+
+func TestTaskJoin(t *testing.T) {
+	task := NewTask("work", func() (Object, *Error) {
+		return &Integer{Value: 7}, nil
+	})
+
+	result, err := task.Join()
+	if err != nil || result.(*Integer).Value != 7 {
+		t.Errorf("wrong task result. got=%v, %v", result, err)
+	}
+
+	failure := &Error{Message: "failed"}
+	failing := NewTask("", func() (Object, *Error) { return nil, failure })
+
+	if _, err := failing.Join(); err != failure {
+		t.Errorf("wrong task error. expected=%v, got=%v", failure, err)
+	}
+
+	if failing.Inspect() != "<task <anonymous>>" {
+		t.Errorf("wrong task inspect. got=%q", failing.Inspect())
+	}
+}
+
+func TestCopier(t *testing.T) {
+	shared := &Array{Elements: []Object{&Integer{Value: 1}}}
+	hashMap := &HashMap{Pairs: map[HashKey]HashPair{}}
+	key := &String{Value: "self"}
+	hashMap.Pairs[key.HashKey()] = HashPair{Key: key, Value: hashMap}
+
+	env := NewEnvironment()
+	env.Put("shared", shared)
+	function := &Function{Env: env}
+	channel := NewChannel(0)
+
+	copier := NewCopier()
+	copied := copier.Copy(&Array{Elements: []Object{shared, shared, hashMap, function, channel}}).(*Array)
+
+	first, second := copied.Elements[0].(*Array), copied.Elements[1].(*Array)
+	if first == shared || first != second {
+		t.Errorf("array reachable twice must be copied once, got=%p and %p", first, second)
+	}
+
+	copiedMap := copied.Elements[2].(*HashMap)
+	if copiedMap == hashMap || copiedMap.Pairs[key.HashKey()].Value != copiedMap {
+		t.Errorf("hashmap referring to itself must refer to its copy")
+	}
+
+	copiedFunction := copied.Elements[3].(*Function)
+	bound, _ := copiedFunction.Env.Get("shared")
+	if copiedFunction.Env == env || bound != first {
+		t.Errorf("function must be rebound to the copy of its environment")
+	}
+
+	if copied.Elements[4] != channel {
+		t.Errorf("channel must be shared, got=%v", copied.Elements[4])
+	}
+}
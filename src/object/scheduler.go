@@ -0,0 +1,87 @@
+package object
+
+import (
+	"slices"
+	"sync"
+)
+
+// scheduler counts the tasks which are able to make progress, tasks waiting on a channel, or for another task
+// to finish, aren't counted. Once every task waits, none of them can ever be woken up, so the task which is about
+// to wait fails with a deadlock error, instead of parking its goroutine forever.
+// The mutex also guards the state of all channels and tasks, so that waking a task up and counting it
+// as running again happen at once
+var scheduler = struct {
+	mu sync.Mutex
+	// the main task of the program is running from the start
+	running int
+}{running: 1}
+
+// waiter is a task parked until another task wakes it up with the outcome of the operation it waits for
+type waiter struct {
+	wake  chan struct{}
+	woken bool
+
+	// value handed over to the receiver, set for the waiters sending to a channel
+	sending Object
+
+	// outcome of the operation
+	value Object
+	ok    bool
+	// index of the channel which has woken up the waiter of select
+	chosen int
+}
+
+// queuedWaiter is the waiter in the queue of a channel, a waiter of select is queued on every channel with its index
+type queuedWaiter struct {
+	*waiter
+	index int
+}
+
+func newWaiter() *waiter {
+	return &waiter{wake: make(chan struct{})}
+}
+
+// park waits until the waiter is woken up, scheduler.mu is released meanwhile. If no other task is running,
+// the deadlock error is returned right away, the waiter has to be removed from the queues it was put into
+func (w *waiter) park(deadlock string) *Error {
+	scheduler.running--
+	if scheduler.running == 0 {
+		scheduler.running++
+		w.woken = true
+
+		return &Error{Message: "deadlock: " + deadlock}
+	}
+
+	scheduler.mu.Unlock()
+	<-w.wake
+	scheduler.mu.Lock()
+
+	return nil
+}
+
+// wakeUp hands the outcome over to the waiter, the waiter of select is woken up by the first of its channels only
+func (w *waiter) wakeUp(value Object, ok bool, chosen int) {
+	w.woken = true
+	w.value, w.ok, w.chosen = value, ok, chosen
+
+	scheduler.running++
+	close(w.wake)
+}
+
+// popWaiter removes the first waiter of the queue which is still waiting
+func popWaiter(queue *[]queuedWaiter) (queuedWaiter, bool) {
+	for len(*queue) > 0 {
+		queued := (*queue)[0]
+		*queue = (*queue)[1:]
+
+		if !queued.woken {
+			return queued, true
+		}
+	}
+
+	return queuedWaiter{}, false
+}
+
+func removeWaiter(queue []queuedWaiter, w *waiter) []queuedWaiter {
+	return slices.DeleteFunc(queue, func(queued queuedWaiter) bool { return queued.waiter == w })
+}
@@ -0,0 +1,75 @@
+package object
+
+import "strings"
+
+// StructType is declared by `struct Point { x, y }`, calling it constructs an instance,
+// the arguments are the values of the fields in declaration order
+type StructType struct {
+	Name   string
+	Fields []string
+}
+
+func (st *StructType) Type() ObjectType { return STRUCT_TYPE_OBJ }
+func (st *StructType) Inspect() string {
+	return "struct " + st.Name + " { " + strings.Join(st.Fields, ", ") + " }"
+}
+
+func (st *StructType) Arity() Arity {
+	return ExactArity(len(st.Fields))
+}
+
+// New constructs an instance of the struct, there must be a value for every field
+func (st *StructType) New(values []Object) *Struct {
+	fields := make([]Object, len(values))
+	copy(fields, values)
+
+	return &Struct{StructType: st, Values: fields}
+}
+
+func (st *StructType) fieldIndex(name string) int {
+	for i, field := range st.Fields {
+		if field == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Struct is an instance of a struct type, unlike hashmaps it can't gain new fields
+type Struct struct {
+	StructType *StructType
+	Values     []Object
+}
+
+func (s *Struct) Type() ObjectType { return STRUCT_OBJ }
+func (s *Struct) Inspect() string {
+	fields := make([]string, len(s.Values))
+	for i, value := range s.Values {
+		fields[i] = s.StructType.Fields[i] + ": " + value.Inspect()
+	}
+
+	return s.StructType.Name + " { " + strings.Join(fields, ", ") + " }"
+}
+
+// Field looks up the value of the field by its name
+func (s *Struct) Field(name string) (Object, bool) {
+	i := s.StructType.fieldIndex(name)
+	if i == -1 {
+		return nil, false
+	}
+
+	return s.Values[i], true
+}
+
+// SetField replaces the value of the field, it fails if the struct has no such field
+func (s *Struct) SetField(name string, value Object) bool {
+	i := s.StructType.fieldIndex(name)
+	if i == -1 {
+		return false
+	}
+
+	s.Values[i] = value
+
+	return true
+}
@@ -0,0 +1,59 @@
+package object
+
+// Task is the join handle of a function spawned with `spawn`, the function runs on a goroutine of its own
+type Task struct {
+	Name string
+
+	done    bool
+	joiners []queuedWaiter
+	result  Object
+	err     *Error
+}
+
+// NewTask starts running the function on a new goroutine, run is provided by the backend executing the function
+func NewTask(name string, run func() (Object, *Error)) *Task {
+	task := &Task{Name: name}
+
+	scheduler.mu.Lock()
+	scheduler.running++
+	scheduler.mu.Unlock()
+
+	go func() {
+		result, err := run()
+
+		scheduler.mu.Lock()
+		defer scheduler.mu.Unlock()
+
+		task.result, task.err = result, err
+		task.done = true
+		scheduler.running--
+
+		for joiner, ok := popWaiter(&task.joiners); ok; joiner, ok = popWaiter(&task.joiners) {
+			joiner.wakeUp(nil, true, 0)
+		}
+	}()
+
+	return task
+}
+
+func (t *Task) Type() ObjectType { return TASK_OBJ }
+func (t *Task) Inspect() string  { return "<task " + FrameName(t.Name) + ">" }
+
+// Join waits for the function to finish, and reports its result, or the error it has failed with.
+// It fails with a deadlock error if every other task is waiting as well, so the function never finishes
+func (t *Task) Join() (Object, *Error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	if !t.done {
+		joiner := newWaiter()
+		t.joiners = append(t.joiners, queuedWaiter{waiter: joiner})
+
+		if err := joiner.park("join of task that never finishes"); err != nil {
+			t.joiners = removeWaiter(t.joiners, joiner)
+			return nil, err
+		}
+	}
+
+	return t.result, t.err
+}
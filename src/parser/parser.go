@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"strconv"
 
 	"github.com/vdchnsk/qrk/src/ast"
@@ -13,11 +15,19 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	LAMBDA
+	LOGICAL_OR
+	LOGICAL_AND
 	EQUALS
 	LESSGREATER
+	BITWISE_OR
+	BITWISE_XOR
+	BITWISE_AND
+	SHIFT
 	SUM
 	PRODUCT
 	PREFIX
+	EXPONENT // binds tighter than prefix operators, so -2 ** 2 is -(2 ** 2)
 	CALL
 	INDEX
 )
@@ -60,14 +70,19 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.INTERPOLATION_START, p.parseInterpolatedString)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.SPAWN, p.parseSpawnExpression)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashMapLiteral)
 
@@ -76,6 +91,15 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
@@ -84,23 +108,41 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.ARROW, p.parseArrowFunction)
 
 	return p
 }
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.AND:      EQUALS,
-	token.OR:       EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.EQ:          EQUALS,
+	token.NOT_EQ:      EQUALS,
+	token.AND:         LOGICAL_AND,
+	token.OR:          LOGICAL_OR,
+	token.LT:          LESSGREATER,
+	token.GT:          LESSGREATER,
+	token.LT_EQ:       LESSGREATER,
+	token.GT_EQ:       LESSGREATER,
+	token.BIT_OR:      BITWISE_OR,
+	token.BIT_XOR:     BITWISE_XOR,
+	token.BIT_AND:     BITWISE_AND,
+	token.SHIFT_LEFT:  SHIFT,
+	token.SHIFT_RIGHT: SHIFT,
+	token.PLUS:        SUM,
+	token.MINUS:       SUM,
+	token.SLASH:       PRODUCT,
+	token.ASTERISK:    PRODUCT,
+	token.PERCENT:     PRODUCT,
+	token.POWER:       EXPONENT,
+	token.LPAREN:      CALL,
+	token.LBRACKET:    INDEX,
+	token.DOT:         INDEX,
+	token.ARROW:       LAMBDA,
+}
+
+// rightAssociative operators group from the right, e.g. 2 ** 3 ** 2 is 2 ** (3 ** 2)
+var rightAssociative = map[token.TokenType]bool{
+	token.POWER: true,
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -147,7 +189,6 @@ func (p *Parser) NextToken() {
 	nextToken, err := p.lexer.NextToken()
 	if err != nil {
 		p.errors = append(p.errors, err.Error())
-		return
 	}
 	p.peekToken = nextToken
 }
@@ -206,6 +247,19 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	return prefExp
 }
 
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	expression := &ast.SpawnExpression{Token: p.currToken}
+
+	p.NextToken()
+
+	expression.Value = p.parseExpression(PREFIX)
+	if expression.Value == nil {
+		return nil
+	}
+
+	return expression
+}
+
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	infExp := &ast.InfixExpression{
 		Token:    p.currToken,
@@ -214,6 +268,10 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.currPrecedence()
+	if rightAssociative[p.currToken.Type] {
+		precedence--
+	}
+
 	p.NextToken()
 	infExp.Right = p.parseExpression(precedence)
 
@@ -224,6 +282,9 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	intLit := &ast.IntegerLiteral{Token: p.currToken}
 
 	value, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return p.parseBigIntegerLiteral()
+	}
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.currToken.Literal)
 		p.errors = append(p.errors, msg)
@@ -235,16 +296,89 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return intLit
 }
 
+func (p *Parser) parseBigIntegerLiteral() ast.Expression {
+	value, ok := new(big.Int).SetString(p.currToken.Literal, 0)
+	if !ok {
+		msg := fmt.Sprintf("could not parse %q as integer", p.currToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return &ast.BigIntegerLiteral{Token: p.currToken, Value: value}
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	floatLit := &ast.FloatLiteral{Token: p.currToken}
+
+	value, err := strconv.ParseFloat(p.currToken.Literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.currToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	floatLit.Value = value
+
+	return floatLit
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
 }
 
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	interpolated := &ast.InterpolatedString{
+		Token:    p.currToken,
+		Segments: []string{p.currToken.Literal},
+	}
+
+	for !p.currTokenIs(token.INTERPOLATION_END) {
+		p.NextToken()
+
+		expression := p.parseExpression(LOWEST)
+		if expression == nil {
+			return nil
+		}
+		interpolated.Expressions = append(interpolated.Expressions, expression)
+
+		if p.peekTokenIs(token.INTERPOLATION_MIDDLE) {
+			p.NextToken()
+		} else if !p.expectPeek(token.INTERPOLATION_END) {
+			return nil
+		}
+
+		interpolated.Segments = append(interpolated.Segments, p.currToken.Literal)
+	}
+
+	return interpolated
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.currToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.YIELD:
+		return p.parseYieldStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForInStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
 	case token.IDENT:
 		if p.peekTokenIs(token.ASSIGN) {
 			return p.parseAssign()
@@ -255,11 +389,19 @@ func (p *Parser) parseStatement() ast.Statement {
 	}
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+func (p *Parser) parseExpressionStatement() ast.Statement {
 	statement := &ast.ExpressionStatement{Token: p.currToken}
 
 	statement.Value = p.parseExpression(LOWEST)
 
+	if index, ok := statement.Value.(*ast.IndexExpression); ok && p.peekTokenIs(token.ASSIGN) {
+		return p.parseIndexAssign(index)
+	}
+
+	if member, ok := statement.Value.(*ast.MemberExpression); ok && p.peekTokenIs(token.ASSIGN) {
+		return p.parseMemberAssign(member)
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.NextToken()
 	}
@@ -290,7 +432,12 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
+// parseLetStatement parses both let and const statements, only let statements can destructure the value
+func (p *Parser) parseLetStatement() ast.Statement {
+	if p.currTokenIs(token.LET) && (p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE)) {
+		return p.parseLetDestructuringStatement()
+	}
+
 	statement := &ast.LetStatement{Token: p.currToken}
 
 	if !p.expectPeek(token.IDENT) {
@@ -306,6 +453,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	p.NextToken()
 	statement.Value = p.parseExpression(LOWEST)
 
+	if funcLit, ok := statement.Value.(*ast.FuncLiteral); ok && funcLit.Name == "" {
+		funcLit.Name = statement.Identifier.Value
+	}
+
 	for p.peekTokenIs(token.SEMICOLON) {
 		p.NextToken()
 	}
@@ -313,6 +464,183 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return statement
 }
 
+func (p *Parser) parseLetDestructuringStatement() ast.Statement {
+	statement := &ast.LetDestructuringStatement{Token: p.currToken}
+
+	p.NextToken()
+	pattern := p.parsePattern(false)
+	if pattern == nil {
+		return nil
+	}
+	statement.Pattern = pattern
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.NextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
+// parsePattern parses the pattern starting at the current token,
+// literal patterns are refutable, so they are only allowed when the pattern is matched rather than bound
+func (p *Parser) parsePattern(allowLiterals bool) ast.Pattern {
+	switch p.currToken.Type {
+	case token.IDENT:
+		if p.currToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.currToken}
+		}
+		return &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	case token.LBRACKET:
+		return p.parseArrayPattern(allowLiterals)
+	case token.LBRACE:
+		return p.parseHashMapPattern(allowLiterals)
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.MINUS:
+		if !allowLiterals {
+			msg := fmt.Sprintf("literal pattern %s is only allowed in match arms", p.currToken.Literal)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		return p.parseLiteralPattern()
+	default:
+		msg := fmt.Sprintf("unexpected %s in pattern", p.currToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+}
+
+func (p *Parser) parseLiteralPattern() ast.Pattern {
+	pattern := &ast.LiteralPattern{Token: p.currToken}
+
+	if !p.currTokenIs(token.MINUS) {
+		pattern.Value = p.parseLiteralPatternValue()
+		if pattern.Value == nil {
+			return nil
+		}
+		return pattern
+	}
+
+	negation := &ast.PrefixExpression{Token: p.currToken, Operator: p.currToken.Literal}
+
+	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+		msg := fmt.Sprintf("expected number after - in pattern, got %s instead", p.peekToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	p.NextToken()
+
+	negation.Right = p.parseLiteralPatternValue()
+	if negation.Right == nil {
+		return nil
+	}
+	pattern.Value = negation
+
+	return pattern
+}
+
+func (p *Parser) parseLiteralPatternValue() ast.Expression {
+	switch p.currToken.Type {
+	case token.INT:
+		return p.parseIntegerLiteral()
+	case token.FLOAT:
+		return p.parseFloatLiteral()
+	case token.STRING:
+		return p.parseStringLiteral()
+	default:
+		return p.parseBoolean()
+	}
+}
+
+func (p *Parser) parseArrayPattern(allowLiterals bool) ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.currToken, Elements: []ast.Pattern{}}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.NextToken()
+
+		if p.currTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+			break
+		}
+
+		element := p.parsePattern(allowLiterals)
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.NextToken()
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseHashMapPattern(allowLiterals bool) ast.Pattern {
+	pattern := &ast.HashMapPattern{Token: p.currToken, Keys: []ast.Expression{}, Values: []ast.Pattern{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.NextToken()
+
+		switch p.currToken.Type {
+		case token.IDENT:
+			// `{name}` is a shorthand for `{"name": name}`
+			key := &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+			pattern.Keys = append(pattern.Keys, key)
+			pattern.Values = append(pattern.Values, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+
+		case token.STRING, token.INT, token.TRUE, token.FALSE:
+			key := p.parseLiteralPatternValue()
+			if key == nil {
+				return nil
+			}
+
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.NextToken()
+
+			value := p.parsePattern(allowLiterals)
+			if value == nil {
+				return nil
+			}
+
+			pattern.Keys = append(pattern.Keys, key)
+			pattern.Values = append(pattern.Values, value)
+
+		default:
+			msg := fmt.Sprintf("unexpected %s in hashmap pattern", p.currToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.NextToken()
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return pattern
+}
+
 func (p *Parser) parseAssign() *ast.AssignStatement {
 	ident, ok := p.parseIdentifier().(*ast.Identifier)
 	if !ok {
@@ -335,6 +663,36 @@ func (p *Parser) parseAssign() *ast.AssignStatement {
 	return statement
 }
 
+func (p *Parser) parseIndexAssign(target *ast.IndexExpression) *ast.IndexAssignStatement {
+	p.NextToken()
+
+	statement := &ast.IndexAssignStatement{Token: p.currToken, Target: target}
+
+	p.NextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseMemberAssign(target *ast.MemberExpression) *ast.MemberAssignStatement {
+	p.NextToken()
+
+	statement := &ast.MemberAssignStatement{Token: p.currToken, Target: target}
+
+	p.NextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	statement := &ast.ReturnStatement{Token: p.currToken}
 
@@ -349,6 +707,273 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return statement
 }
 
+func (p *Parser) parseYieldStatement() *ast.YieldStatement {
+	statement := &ast.YieldStatement{Token: p.currToken}
+
+	if !p.peekTokenIs(token.SEMICOLON) && !p.peekTokenIs(token.RBRACE) {
+		p.NextToken()
+		statement.Value = p.parseExpression(LOWEST)
+	}
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	statement := &ast.WhileStatement{Token: p.currToken}
+
+	p.NextToken()
+
+	statement.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	statement.Body = p.parseBlockStatement()
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseForInStatement() *ast.ForInStatement {
+	statement := &ast.ForInStatement{Token: p.currToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	statement.Value = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.NextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		statement.Key = statement.Value
+		statement.Value = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.NextToken()
+
+	statement.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	statement.Body = p.parseBlockStatement()
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	statement := &ast.BreakStatement{Token: p.currToken}
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	statement := &ast.ContinueStatement{Token: p.currToken}
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseThrowStatement() ast.Statement {
+	statement := &ast.ThrowStatement{Token: p.currToken}
+
+	p.NextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseTryStatement() ast.Statement {
+	statement := &ast.TryStatement{Token: p.currToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	statement.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.NextToken()
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.NextToken()
+
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			statement.CatchParam = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		statement.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.NextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		statement.Finally = p.parseBlockStatement()
+	}
+
+	if statement.Catch == nil && statement.Finally == nil {
+		p.errors = append(p.errors, "try must be followed by catch or finally")
+		return nil
+	}
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseImportStatement() ast.Statement {
+	statement := &ast.ImportStatement{Token: p.currToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	statement.Path = &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.AS) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	statement.Alias = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseExportStatement() ast.Statement {
+	statement := &ast.ExportStatement{Token: p.currToken}
+
+	p.NextToken()
+
+	switch {
+	case (p.currTokenIs(token.LET) || p.currTokenIs(token.CONST)) && p.peekTokenIs(token.IDENT):
+		declaration := p.parseLetStatement()
+		if declaration == nil {
+			return nil
+		}
+		statement.Statement = declaration
+
+	case p.currTokenIs(token.STRUCT):
+		declaration := p.parseStructStatement()
+		if declaration == nil {
+			return nil
+		}
+		statement.Statement = declaration
+
+	case p.currTokenIs(token.FUNCTION) && (p.peekTokenIs(token.IDENT) || p.peekTokenIs(token.ASTERISK)):
+		declaration, ok := p.parseExpressionStatement().(*ast.ExpressionStatement)
+		if !ok {
+			return nil
+		}
+
+		if function, ok := declaration.Value.(*ast.FuncLiteral); !ok || function.Name == "" {
+			p.errors = append(p.errors, fmt.Sprintf("cannot export expression %s", declaration.Value))
+			return nil
+		}
+		statement.Statement = declaration
+
+	default:
+		p.errors = append(p.errors, fmt.Sprintf("only let, const, struct and named fn declarations can be exported, got %s instead", p.currToken.Type))
+		return nil
+	}
+
+	return statement
+}
+
+func (p *Parser) parseStructStatement() ast.Statement {
+	statement := &ast.StructStatement{Token: p.currToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	statement.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	declared := map[string]bool{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		field := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		if declared[field.Value] {
+			p.errors = append(p.errors, fmt.Sprintf("duplicate field %s in struct %s", field.Value, statement.Name.Value))
+			return nil
+		}
+		declared[field.Value] = true
+		statement.Fields = append(statement.Fields, field)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return statement
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{
 		Token: p.currToken,
@@ -357,10 +982,30 @@ func (p *Parser) parseBoolean() ast.Expression {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	// `() => ...`, `(...rest) => ...`
+	if p.peekTokenIs(token.RPAREN) || p.peekTokenIs(token.ELLIPSIS) {
+		funcLit := &ast.FuncLiteral{}
+		if !p.ParseFuncParams(funcLit) {
+			return nil
+		}
+
+		return p.parseArrowFunctionFromParams(funcLit)
+	}
+
 	p.NextToken()
 
+	// `(a, b) => ...`, `(a = 1) => ...`, neither of them is a valid grouped expression
+	if p.currTokenIs(token.IDENT) && (p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.ASSIGN)) {
+		return p.parseArrowFunctionParams()
+	}
+
 	expression := p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.COMMA) {
+		p.arrowFunctionParamError(expression)
+		return nil
+	}
+
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
@@ -368,6 +1013,83 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	return expression
 }
 
+// parseArrowFunctionParams parses the parameters of the arrow function the same way as the ones of `fn`,
+// the current token is the first parameter
+func (p *Parser) parseArrowFunctionParams() ast.Expression {
+	funcLit := &ast.FuncLiteral{
+		Parameters: []*ast.Identifier{},
+		Defaults:   []ast.Expression{},
+	}
+
+	if !p.parseFuncParam(funcLit) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.NextToken()
+
+		if !p.parseFuncParamList(funcLit) {
+			return nil
+		}
+	} else if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return p.parseArrowFunctionFromParams(funcLit)
+}
+
+func (p *Parser) parseArrowFunctionFromParams(funcLit *ast.FuncLiteral) ast.Expression {
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	return p.parseArrowFunctionBody(funcLit)
+}
+
+// parseArrowFunction parses the single parameter form `x => ...`,
+// as well as `(x) => ...`, since the grouped expression is parsed before `=>` is seen
+func (p *Parser) parseArrowFunction(left ast.Expression) ast.Expression {
+	param, ok := left.(*ast.Identifier)
+	if !ok {
+		p.arrowFunctionParamError(left)
+		return nil
+	}
+
+	return p.parseArrowFunctionBody(&ast.FuncLiteral{
+		Parameters: []*ast.Identifier{param},
+		Defaults:   []ast.Expression{nil},
+	})
+}
+
+// parseArrowFunctionBody expects `=>` to be the current token,
+// expression body is wrapped into a block, so it's implicitly returned just like the last expression of a block
+func (p *Parser) parseArrowFunctionBody(funcLit *ast.FuncLiteral) ast.Expression {
+	funcLit.Token = p.currToken
+
+	if p.peekTokenIs(token.LBRACE) {
+		p.NextToken()
+		funcLit.Body = p.parseBlockStatement()
+		return funcLit
+	}
+
+	p.NextToken()
+
+	bodyStatement := &ast.ExpressionStatement{Token: p.currToken}
+	bodyStatement.Value = p.parseExpression(LOWEST)
+
+	funcLit.Body = &ast.BlockStatement{
+		Token:      funcLit.Token,
+		Statements: []ast.Statement{bodyStatement},
+	}
+
+	return funcLit
+}
+
+func (p *Parser) arrowFunctionParamError(param ast.Expression) {
+	msg := fmt.Sprintf("arrow function parameters must be identifiers, got %s", param)
+	p.errors = append(p.errors, msg)
+}
+
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{Token: p.currToken}
 
@@ -394,6 +1116,79 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.currToken, Arms: []*ast.MatchArm{}}
+
+	p.NextToken()
+
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.NextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.NextToken()
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return expression
+}
+
+// parseMatchArm parses `pattern [if guard] => body`, the body is parsed the same way as the body of an arrow function
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.currToken}
+
+	arm.Pattern = p.parsePattern(true)
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.IF) {
+		p.NextToken()
+		p.NextToken()
+
+		// `=>` has LAMBDA precedence, parsing the guard with it stops right before the arrow
+		arm.Guard = p.parseExpression(LAMBDA)
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.LBRACE) {
+		p.NextToken()
+		arm.Body = p.parseBlockStatement()
+		return arm
+	}
+
+	p.NextToken()
+
+	bodyStatement := &ast.ExpressionStatement{Token: p.currToken}
+	bodyStatement.Value = p.parseExpression(LOWEST)
+
+	arm.Body = &ast.BlockStatement{
+		Token:      arm.Token,
+		Statements: []ast.Statement{bodyStatement},
+	}
+
+	return arm
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	blockStatement := &ast.BlockStatement{Token: p.currToken}
 	blockStatement.Statements = []ast.Statement{}
@@ -416,17 +1211,25 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		Token: p.currToken,
 	}
 
+	if p.peekTokenIs(token.ASTERISK) {
+		p.NextToken()
+		funcLit.IsGenerator = true
+	}
+
 	if p.peekTokenIs(token.IDENT) {
 		p.NextToken()
 
 		funcIdentifier := p.parseIdentifier().(*ast.Identifier)
 		funcLit.Identifier = funcIdentifier
+		funcLit.Name = funcIdentifier.Value
 	}
 
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	funcLit.Parameters = p.ParseFuncParams()
+	if !p.ParseFuncParams(funcLit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -436,37 +1239,71 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return funcLit
 }
 
-func (p *Parser) ParseFuncParams() []*ast.Identifier {
-	params := []*ast.Identifier{}
+// ParseFuncParams parses the parameters of the function literal: required parameters,
+// parameters with default values, e.g. msg = "", and the rest parameter, e.g. ...rest
+func (p *Parser) ParseFuncParams(funcLit *ast.FuncLiteral) bool {
+	funcLit.Parameters = []*ast.Identifier{}
+	funcLit.Defaults = []ast.Expression{}
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.NextToken()
-		return params
+		return true
 	}
 
-	p.NextToken()
+	return p.parseFuncParamList(funcLit)
+}
 
-	parameter := &ast.Identifier{
-		Token: p.currToken,
-		Value: p.currToken.Literal,
+// parseFuncParamList parses the parameters starting with the next token, up to the closing paren
+func (p *Parser) parseFuncParamList(funcLit *ast.FuncLiteral) bool {
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.NextToken()
+
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			funcLit.Rest = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+			// rest parameter must be the last one, so the closing paren is expected right after it
+			break
+		}
+
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		if !p.parseFuncParam(funcLit) {
+			return false
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.NextToken()
 	}
-	params = append(params, parameter)
 
-	for p.peekTokenIs(token.COMMA) {
+	return p.expectPeek(token.RPAREN)
+}
+
+// parseFuncParam parses the parameter which is the current token, along with its default value if there is one
+func (p *Parser) parseFuncParam(funcLit *ast.FuncLiteral) bool {
+	parameter := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	var defaultValue ast.Expression
+	if p.peekTokenIs(token.ASSIGN) {
 		p.NextToken()
 		p.NextToken()
-		parameter := &ast.Identifier{
-			Token: p.currToken,
-			Value: p.currToken.Literal,
-		}
-		params = append(params, parameter)
-	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+		defaultValue = p.parseExpression(LOWEST)
+	} else if len(funcLit.Defaults) > 0 && funcLit.Defaults[len(funcLit.Defaults)-1] != nil {
+		msg := fmt.Sprintf("required parameter %s follows a parameter with a default value", parameter)
+		p.errors = append(p.errors, msg)
+		return false
 	}
 
-	return params
+	funcLit.Parameters = append(funcLit.Parameters, parameter)
+	funcLit.Defaults = append(funcLit.Defaults, defaultValue)
+
+	return true
 }
 
 func (p *Parser) parseCallExpression(fn ast.Expression) ast.Expression {
@@ -536,12 +1373,52 @@ func (p *Parser) parseHashMapLiteral() ast.Expression {
 	return hashMap
 }
 
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	expression := &ast.MemberExpression{Token: p.currToken, Object: left}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	expression.Member = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	return expression
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	expression := &ast.IndexExpression{Token: p.currToken, Left: left}
+	bracket := p.currToken
+
+	p.NextToken()
+
+	if p.currTokenIs(token.COLON) {
+		return p.parseSliceExpression(bracket, left, nil)
+	}
+
+	index := p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.COLON) {
+		p.NextToken()
+		return p.parseSliceExpression(bracket, left, index)
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return &ast.IndexExpression{Token: bracket, Left: left, Index: index}
+}
+
+// parseSliceExpression parses the rest of the slice after the colon, either of the bounds can be omitted
+func (p *Parser) parseSliceExpression(bracket token.Token, left, start ast.Expression) ast.Expression {
+	expression := &ast.SliceExpression{Token: bracket, Left: left, Start: start}
+
+	if p.peekTokenIs(token.RBRACKET) {
+		p.NextToken()
+		return expression
+	}
 
 	p.NextToken()
 
-	expression.Index = p.parseExpression(LOWEST)
+	expression.End = p.parseExpression(LOWEST)
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
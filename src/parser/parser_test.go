@@ -47,6 +47,51 @@ func TestLetStatement(t *testing.T) {
 	}
 }
 
+func TestConstStatement(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedIdentifier string
+		expectedValue      interface{}
+		expectedString     string
+	}{
+		{"const x = 5;", "x", 5, "const x = 5;"},
+		{"const LIMIT = limit;", "LIMIT", "limit", "const LIMIT = limit;"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("statement is not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+
+		if !statement.IsConst() {
+			t.Errorf("statement is not const")
+		}
+
+		if statement.Identifier.Value != tt.expectedIdentifier {
+			t.Errorf("statement.Identifier.Value not '%s'. got=%s", tt.expectedIdentifier, statement.Identifier.Value)
+		}
+
+		if !testLiteralExpression(t, statement.Value, tt.expectedValue) {
+			return
+		}
+
+		if statement.String() != tt.expectedString {
+			t.Errorf("wrong string. expected=%q, got=%q", tt.expectedString, statement.String())
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, statement ast.Statement, identifier string) bool {
 	if statement.TokenLiteral() != "let" {
 		t.Errorf(
@@ -220,6 +265,62 @@ func TestIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerExpression(t *testing.T) {
+	input := "99999999999999999999;"
+
+	lexer := lexer.NewLexer(input)
+	parser := NewParser(lexer)
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement")
+	}
+
+	bigInt, ok := statement.Value.(*ast.BigIntegerLiteral)
+	if !ok {
+		t.Fatalf("statement.Value is not *ast.BigIntegerLiteral. got=%T", statement.Value)
+	}
+	if bigInt.Value.String() != "99999999999999999999" {
+		t.Errorf("bigInt.Value not %s. got=%s", "99999999999999999999", bigInt.Value)
+	}
+}
+
+func TestFloatExpression(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedValue float64
+	}{
+		{"3.14;", 3.14},
+		{"1e10;", 1e10},
+		{"2.5e-3;", 2.5e-3},
+		{"1E+2;", 100},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement")
+		}
+
+		float, ok := statement.Value.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("statement.Value is not *ast.FloatLiteral. got=%T", statement.Value)
+		}
+		if float.Value != tt.expectedValue {
+			t.Errorf("float.Value not %g. got=%g", tt.expectedValue, float.Value)
+		}
+	}
+}
+
 func TestStringExpression(t *testing.T) {
 	input := `"Linus Torvalds"`
 	expectedOutput := "Linus Torvalds"
@@ -261,6 +362,125 @@ func TestStringExpression(t *testing.T) {
 	}
 }
 
+func TestStringExpressionRoundTrip(t *testing.T) {
+	inputs := []string{
+		`"plain"`,
+		`"quote \" and backslash \\"`,
+		`"line\nbreak\ttab"`,
+		`"\u{1F600} \u{200B} \x00"`,
+	}
+
+	for _, input := range inputs {
+		original := parseStringLiteral(t, input)
+		reparsed := parseStringLiteral(t, original.String())
+
+		if original.Value != reparsed.Value {
+			t.Errorf("value changed after round trip. expected=%q, got=%q", original.Value, reparsed.Value)
+		}
+	}
+}
+
+func parseStringLiteral(t *testing.T, input string) *ast.StringLiteral {
+	t.Helper()
+
+	lexer := lexer.NewLexer(input)
+	parser := NewParser(lexer)
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement")
+	}
+
+	literal, ok := statement.Value.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("statement.Value is not *ast.StringLiteral. got=%T", statement.Value)
+	}
+
+	return literal
+}
+
+func TestInterpolatedString(t *testing.T) {
+	input := `"hello ${name}, you are ${age + 1}!"`
+
+	lexer := lexer.NewLexer(input)
+	parser := NewParser(lexer)
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement")
+	}
+
+	interpolated, ok := statement.Value.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("statement.Value is not *ast.InterpolatedString. got=%T", statement.Value)
+	}
+
+	expectedSegments := []string{"hello ", ", you are ", "!"}
+	if len(interpolated.Segments) != len(expectedSegments) {
+		t.Fatalf("wrong amount of segments. expected=%d, got=%d", len(expectedSegments), len(interpolated.Segments))
+	}
+	for i, segment := range expectedSegments {
+		if interpolated.Segments[i] != segment {
+			t.Errorf("wrong segment %d. expected=%q, got=%q", i, segment, interpolated.Segments[i])
+		}
+	}
+
+	if len(interpolated.Expressions) != 2 {
+		t.Fatalf("wrong amount of expressions. expected=2, got=%d", len(interpolated.Expressions))
+	}
+	testIdentifier(t, interpolated.Expressions[0], "name")
+	testInfixExpression(t, interpolated.Expressions[1], "age", "+", 1)
+
+	if interpolated.String() != `"hello ${name}, you are ${(age + 1)}!"` {
+		t.Errorf("wrong string representation. got=%s", interpolated.String())
+	}
+}
+
+func TestInterpolatedStringNesting(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"${ {"a": {}} }"`, `"${{"a":{}}}"`},
+		{`"a ${"b ${c}"} d"`, `"a ${"b ${c}"} d"`},
+		{`"\${not} ${x}"`, `"\${not} ${x}"`},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. expected=%s, got=%s", tt.expected, program.String())
+		}
+	}
+}
+
+func TestInvalidEscapeParserError(t *testing.T) {
+	lexer := lexer.NewLexer(`let s = "a\qb"; s`)
+	parser := NewParser(lexer)
+	parser.ParseProgram()
+
+	errors := parser.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected exactly 1 error, got=%d (%v)", len(errors), errors)
+	}
+
+	expected := `invalid escape sequence \q at line 1, column 11`
+	if errors[0] != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, errors[0])
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -271,6 +491,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 		{"-42;", "-", 42},
 		{"!true", "!", true},
 		{"!false", "!", false},
+		{"~5;", "~", 5},
 	}
 
 	for _, tt := range prefixTests {
@@ -459,6 +680,15 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"5 ** 5;", 5, "**", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 		{"true == true", true, "==", true},
 		{"true && false", true, "&&", false},
 		{"true || true", true, "||", true},
@@ -610,6 +840,70 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"getArray(b * c, e)[1 + 1] + d",
 			"((getArray((b * c), e)[(1 + 1)] + d)",
 		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"2 ** -1",
+			"(2 ** (-1))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b << c + d",
+			"(a & (b << (c + d)))",
+		},
+		{
+			"a << b >> c",
+			"((a << b) >> c)",
+		},
+		{
+			"a | b == c",
+			"((a | b) == c)",
+		},
+		{
+			"~a & b",
+			"((~a) & b)",
+		},
+		{
+			"a < b | c",
+			"(a < (b | c))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a == b && c != d",
+			"((a == b) && (c != d))",
+		},
+		{
+			"a || b || c",
+			"((a || b) || c)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -743,6 +1037,108 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestWhileStatement(t *testing.T) {
+	input := `while x < y { break; continue; }`
+
+	lexer := lexer.NewLexer(input)
+	parser := NewParser(lexer)
+
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"program.Statements length=%d, expected=%d",
+			len(program.Statements), 1,
+		)
+	}
+
+	statement, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf(
+			"program.Statements[0] is not ast.WhileStatement, got=%T",
+			program.Statements[0],
+		)
+	}
+
+	if !testInfixExpression(t, statement.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(statement.Body.Statements) != 2 {
+		t.Fatalf(
+			"wrong amount of body statements got=%d",
+			len(statement.Body.Statements),
+		)
+	}
+
+	if _, ok := statement.Body.Statements[0].(*ast.BreakStatement); !ok {
+		t.Fatalf(
+			"body.Statements[0] is not ast.BreakStatement, got=%T",
+			statement.Body.Statements[0],
+		)
+	}
+
+	if _, ok := statement.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Fatalf(
+			"body.Statements[1] is not ast.ContinueStatement, got=%T",
+			statement.Body.Statements[1],
+		)
+	}
+}
+
+func TestForInStatement(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedKey   string
+		expectedValue string
+		expectedBody  string
+	}{
+		{"for x in items { x; }", "", "x", "x"},
+		{"for k, v in items { v; }", "k", "v", "v"},
+		{"for x in range(0, 10, 1) { }", "", "x", ""},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf(
+				"program.Statements length=%d, expected=%d",
+				len(program.Statements), 1,
+			)
+		}
+
+		statement, ok := program.Statements[0].(*ast.ForInStatement)
+		if !ok {
+			t.Fatalf(
+				"program.Statements[0] is not ast.ForInStatement, got=%T",
+				program.Statements[0],
+			)
+		}
+
+		if tt.expectedKey == "" && statement.Key != nil {
+			t.Fatalf("expected no key binding, got=%s", statement.Key)
+		}
+
+		if tt.expectedKey != "" && !testIdentifier(t, statement.Key, tt.expectedKey) {
+			return
+		}
+
+		if !testIdentifier(t, statement.Value, tt.expectedValue) {
+			return
+		}
+
+		if statement.Body.String() != tt.expectedBody {
+			t.Errorf("wrong body. expected=%q, got=%q", tt.expectedBody, statement.Body.String())
+		}
+	}
+}
+
 func TestFuncLiteral(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -802,14 +1198,18 @@ func TestFuncLiteral(t *testing.T) {
 	testInfixExpression(t, functionBodyStatement.Value, "x", "+", "y")
 }
 
-func TestFuncParams(t *testing.T) {
+func TestArrowFuncLiteral(t *testing.T) {
 	tests := []struct {
 		input          string
-		expectedOutput []string
+		expectedParams []string
+		expectedBody   string
 	}{
-		{"fn() { }", []string{}},
-		{"fn(x) { x; }", []string{"x"}},
-		{"fn(x, y) { x + y; }", []string{"x", "y"}},
+		{"() => 1", []string{}, "1"},
+		{"x => x + 1", []string{"x"}, "(x + 1)"},
+		{"(x) => x", []string{"x"}, "x"},
+		{"(x, y) => x * y", []string{"x", "y"}, "(x * y)"},
+		{"(x, y) => { let z = x; z + y }", []string{"x", "y"}, "let z = x;(z + y)"},
+		{"x => y => x + y", []string{"x"}, "fn (y)(x + y)"},
 	}
 
 	for _, tt := range tests {
@@ -840,20 +1240,184 @@ func TestFuncParams(t *testing.T) {
 				statement.Value,
 			)
 		}
-		funcParams := function.Parameters
 
-		if len(funcParams) != len(tt.expectedOutput) {
+		if len(function.Parameters) != len(tt.expectedParams) {
 			t.Fatalf(
-				"wrong number of parameters, expectedd=%d, got=%d,",
-				len(tt.expectedOutput),
-				len(funcParams),
+				"wrong number of parameters, expected=%d, got=%d",
+				len(tt.expectedParams),
+				len(function.Parameters),
 			)
 		}
 
-		for index := range funcParams {
-			testLiteralExpression(t, funcParams[index], tt.expectedOutput[index])
+		for index := range function.Parameters {
+			testLiteralExpression(t, function.Parameters[index], tt.expectedParams[index])
 		}
-	}
+
+		if function.Body.String() != tt.expectedBody {
+			t.Errorf("wrong body. expected=%q, got=%q", tt.expectedBody, function.Body.String())
+		}
+	}
+}
+
+func TestArrowFuncLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"(x, 1) => x", "expected next token to be IDENT, got INT instead"},
+		{"(1, x) => x", "arrow function parameters must be identifiers, got 1"},
+		{"(x + 1) => x", "arrow function parameters must be identifiers, got (x + 1)"},
+		{"(x, y) + 1", "expected next token to be =>, got + instead"},
+		{"(a = 1, b) => a", "required parameter b follows a parameter with a default value"},
+		{"(...xs, a) => a", "expected next token to be ), got , instead"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong parser error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestFuncParams(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput []string
+	}{
+		{"fn() { }", []string{}},
+		{"fn(x) { x; }", []string{"x"}},
+		{"fn(x, y) { x + y; }", []string{"x", "y"}},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf(
+				"program.Body does not contain %d statements, got %d\n",
+				1, len(program.Statements),
+			)
+		}
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf(
+				"program.Statements[0] is not ast.ExpressionStatement, got=%T",
+				program.Statements[0],
+			)
+		}
+
+		function, ok := statement.Value.(*ast.FuncLiteral)
+		if !ok {
+			t.Fatalf(
+				"function is not ast.FuncLiteral, got=%T",
+				statement.Value,
+			)
+		}
+		funcParams := function.Parameters
+
+		if len(funcParams) != len(tt.expectedOutput) {
+			t.Fatalf(
+				"wrong number of parameters, expectedd=%d, got=%d,",
+				len(tt.expectedOutput),
+				len(funcParams),
+			)
+		}
+
+		for index := range funcParams {
+			testLiteralExpression(t, funcParams[index], tt.expectedOutput[index])
+		}
+	}
+}
+
+func TestFuncDefaultAndRestParams(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedString   string
+		expectedRequired int
+		expectedRest     string
+	}{
+		{"fn(a, b = 1) { }", "fn (a,b = 1)", 1, ""},
+		{"fn(a = 1, b = a * 2) { }", "fn (a = 1,b = (a * 2))", 0, ""},
+		{"fn(...rest) { }", "fn (...rest)", 0, "rest"},
+		{"fn(a, b = [], ...rest) { }", "fn (a,b = [],...rest)", 1, "rest"},
+		{"(a, b = 1) => a + b", "fn (a,b = 1)(a + b)", 1, ""},
+		{"(a = 1) => a", "fn (a = 1)a", 0, ""},
+		{"(...xs) => xs", "fn (...xs)xs", 0, "xs"},
+		{"(a, ...xs) => xs", "fn (a,...xs)xs", 1, "xs"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := statement.Value.(*ast.FuncLiteral)
+		if !ok {
+			t.Fatalf("function is not ast.FuncLiteral, got=%T", statement.Value)
+		}
+
+		if function.String() != tt.expectedString {
+			t.Errorf("wrong function. expected=%q, got=%q", tt.expectedString, function.String())
+		}
+
+		if function.RequiredParamsCount() != tt.expectedRequired {
+			t.Errorf(
+				"wrong number of required parameters, expected=%d, got=%d",
+				tt.expectedRequired, function.RequiredParamsCount(),
+			)
+		}
+
+		if tt.expectedRest == "" {
+			if function.Rest != nil {
+				t.Errorf("function.Rest is not nil, got=%s", function.Rest)
+			}
+			continue
+		}
+
+		testIdentifier(t, function.Rest, tt.expectedRest)
+	}
+}
+
+func TestFuncParamsErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"fn(a = 1, b) { }", "required parameter b follows a parameter with a default value"},
+		{"fn(...rest, a) { }", "expected next token to be ), got , instead"},
+		{"fn(...rest = []) { }", "expected next token to be ), got = instead"},
+		{"fn(a = ) { }", "no prefix parse function for ) found"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong parser error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
 }
 
 func TestCallExpression(t *testing.T) {
@@ -1029,68 +1593,778 @@ func TestIndexExpression(t *testing.T) {
 	}
 }
 
-func TestHashMapLiteral(t *testing.T) {
-	input := `{ "check": 1, "69": 42 }`
-	expectedOutput := map[string]int64{
-		"check": 1,
-		"69":    42,
+func TestSliceExpression(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedStart interface{}
+		expectedEnd   interface{}
+		expected      string
+	}{
+		{"arr[1:3]", 1, 3, "(arr[1:3])"},
+		{"arr[:n]", nil, "n", "(arr[:n])"},
+		{"arr[-2:]", nil, nil, "(arr[(-2):])"},
+		{"arr[:]", nil, nil, "(arr[:])"},
+		{"arr[i + 1:len(arr)]", nil, nil, "(arr[(i + 1):len(arr)])"},
 	}
 
-	lexer := lexer.NewLexer(input)
-	parser := NewParser(lexer)
-	program := parser.ParseProgram()
-	checkParserErrors(t, parser)
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
 
-	statement := program.Statements[0].(*ast.ExpressionStatement)
-	hashMap, ok := statement.Value.(*ast.HashMapLiteral)
-	if !ok {
-		t.Errorf(
-			"provided statement is not HashMapLiteral, got=%T",
-			statement,
-		)
+		statement, _ := program.Statements[0].(*ast.ExpressionStatement)
+		sliceExp, ok := statement.Value.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("expression is not *ast.SliceExpression, received=%T", statement.Value)
+		}
+
+		if !testIdentifier(t, sliceExp.Left, "arr") {
+			return
+		}
+
+		if tt.expectedStart != nil && !testLiteralExpression(t, sliceExp.Start, tt.expectedStart) {
+			return
+		}
+
+		if tt.expectedEnd != nil && !testLiteralExpression(t, sliceExp.End, tt.expectedEnd) {
+			return
+		}
+
+		if sliceExp.String() != tt.expected {
+			t.Errorf("wrong string. expected=%q, got=%q", tt.expected, sliceExp.String())
+		}
 	}
-	amountOfPairs := len(hashMap.Pairs)
+}
 
-	if amountOfPairs != 2 {
-		t.Errorf(
-			"wrong amount of has map pairs, got=%d",
-			amountOfPairs,
-		)
+func TestSliceExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"arr[1:2:3]", "expected next token to be ], got : instead"},
+		{"arr[1 2]", "expected next token to be ], got INT instead"},
+		{"arr[:", "no prefix parse function for EOF found"},
 	}
 
-	for key, value := range hashMap.Pairs {
-		strLiteral, ok := key.(*ast.StringLiteral)
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestIndexAssignStatement(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedLeft  string
+		expectedIndex interface{}
+		expectedValue interface{}
+	}{
+		{"arr[0] = 1;", "arr", 0, 1},
+		{"person[key] = age", "person", "key", "age"},
+		{"grid[1][2] = true;", "(grid[1]", 2, true},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong amount of statements, expected 1, got=%d", len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.IndexAssignStatement)
 		if !ok {
-			t.Errorf("key is not string")
+			t.Fatalf("program.Statements[0] is not *ast.IndexAssignStatement. got=%T", program.Statements[0])
+		}
+
+		if statement.Target.Left.String() != tt.expectedLeft {
+			t.Errorf("wrong target. expected=%s, got=%s", tt.expectedLeft, statement.Target.Left.String())
 		}
 
-		expectedValue := expectedOutput[strLiteral.String()]
+		if !testLiteralExpression(t, statement.Target.Index, tt.expectedIndex) {
+			return
+		}
 
-		testIntegerLiteral(t, value, expectedValue)
+		if !testLiteralExpression(t, statement.Value, tt.expectedValue) {
+			return
+		}
 	}
 }
 
-func TestEmptyHashMap(t *testing.T) {
-	input := `{}`
+func TestLetDestructuringStatement(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedPattern string
+		expectedValue   string
+	}{
+		{"let [a, b] = arr;", "[a, b]", "arr"},
+		{"let [head, ...tail] = [1, 2, 3];", "[head, ...tail]", "[1, 2, 3]"},
+		{"let [...all] = arr", "[...all]", "arr"},
+		{"let [x, [y, z]] = pairs;", "[x, [y, z]]", "pairs"},
+		{"let [] = arr;", "[]", "arr"},
+		{"let {name, age} = person;", "{name, age}", "person"},
+		{"let [first, {name}] = people;", "[first, {name}]", "people"},
+		{`let {"name": n, "tags": [first, _]} = person;`, `{"name": n, "tags": [first, _]}`, "person"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong amount of statements, expected 1, got=%d", len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.LetDestructuringStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.LetDestructuringStatement. got=%T", program.Statements[0])
+		}
+
+		if statement.Pattern.String() != tt.expectedPattern {
+			t.Errorf("wrong pattern. expected=%s, got=%s", tt.expectedPattern, statement.Pattern.String())
+		}
+
+		if statement.Value.String() != tt.expectedValue {
+			t.Errorf("wrong value. expected=%s, got=%s", tt.expectedValue, statement.Value.String())
+		}
+	}
+}
+
+func TestLetDestructuringStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let [a, ...rest, b] = arr;", "expected next token to be ], got , instead"},
+		{"let [a, 1] = arr;", "literal pattern 1 is only allowed in match arms"},
+		{"let {name, [age]} = person;", "unexpected [ in hashmap pattern"},
+		{`let {"name"} = person;`, "expected next token to be :, got } instead"},
+		{"let [...[a]] = arr;", "expected next token to be IDENT, got [ instead"},
+		{"const [a] = arr;", "expected next token to be IDENT, got [ instead"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match value {
+		1 => "one",
+		-2.5 => "negative",
+		[a, ...rest] if a > 0 => { a },
+		{"type": t, "ok": true} => t,
+		x => x => x + 1,
+		_ => null,
+	}`
 
 	lexer := lexer.NewLexer(input)
 	parser := NewParser(lexer)
 	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
 
-	statement := program.Statements[0].(*ast.ExpressionStatement)
-	hashMap, ok := statement.Value.(*ast.HashMapLiteral)
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has wrong amount of statements, expected 1, got=%d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
 	if !ok {
-		t.Errorf(
-			"provided statement is not HashMapLiteral, got=%T",
-			statement,
-		)
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement. got=%T", program.Statements[0])
 	}
-	amountOfPairs := len(hashMap.Pairs)
 
-	if amountOfPairs != 0 {
-		t.Errorf(
-			"wrong amount of has map pairs, got=%d",
-			amountOfPairs,
-		)
+	match, ok := statement.Value.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("statement.Value is not *ast.MatchExpression. got=%T", statement.Value)
+	}
+
+	if !testIdentifier(t, match.Subject, "value") {
+		return
+	}
+
+	expectedArms := []struct {
+		pattern string
+		guard   string
+		body    string
+	}{
+		{"1", "", `"one"`},
+		{"(-2.5)", "", `"negative"`},
+		{"[a, ...rest]", "(a > 0)", "a"},
+		{`{"type": t, "ok": true}`, "", "t"},
+		{"x", "", "fn (x)(x + 1)"},
+		{"_", "", "null"},
+	}
+
+	if len(match.Arms) != len(expectedArms) {
+		t.Fatalf("wrong amount of arms, expected %d, got=%d", len(expectedArms), len(match.Arms))
+	}
+
+	for i, expected := range expectedArms {
+		arm := match.Arms[i]
+
+		if arm.Pattern.String() != expected.pattern {
+			t.Errorf("arm %d has wrong pattern. expected=%s, got=%s", i, expected.pattern, arm.Pattern.String())
+		}
+
+		guard := ""
+		if arm.Guard != nil {
+			guard = arm.Guard.String()
+		}
+		if guard != expected.guard {
+			t.Errorf("arm %d has wrong guard. expected=%s, got=%s", i, expected.guard, guard)
+		}
+
+		if arm.Body.String() != expected.body {
+			t.Errorf("arm %d has wrong body. expected=%s, got=%s", i, expected.body, arm.Body.String())
+		}
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"match x { 1 }", "expected next token to be =>, got } instead"},
+		{"match x { 1 => 2 3 => 4 }", "expected next token to be }, got INT instead"},
+		{"match x { fn => 1 }", "unexpected FUNCTION in pattern"},
+		{`match x { - "a" => 1 }`, "expected number after - in pattern, got STRING instead"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestHashMapLiteral(t *testing.T) {
+	input := `{ "check": 1, "69": 42 }`
+	expectedOutput := map[string]int64{
+		"check": 1,
+		"69":    42,
+	}
+
+	lexer := lexer.NewLexer(input)
+	parser := NewParser(lexer)
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	hashMap, ok := statement.Value.(*ast.HashMapLiteral)
+	if !ok {
+		t.Errorf(
+			"provided statement is not HashMapLiteral, got=%T",
+			statement,
+		)
+	}
+	amountOfPairs := len(hashMap.Pairs)
+
+	if amountOfPairs != 2 {
+		t.Errorf(
+			"wrong amount of has map pairs, got=%d",
+			amountOfPairs,
+		)
+	}
+
+	for key, value := range hashMap.Pairs {
+		strLiteral, ok := key.(*ast.StringLiteral)
+		if !ok {
+			t.Errorf("key is not string")
+		}
+
+		expectedValue := expectedOutput[strLiteral.Value]
+
+		testIntegerLiteral(t, value, expectedValue)
+	}
+}
+
+func TestEmptyHashMap(t *testing.T) {
+	input := `{}`
+
+	lexer := lexer.NewLexer(input)
+	parser := NewParser(lexer)
+	program := parser.ParseProgram()
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	hashMap, ok := statement.Value.(*ast.HashMapLiteral)
+	if !ok {
+		t.Errorf(
+			"provided statement is not HashMapLiteral, got=%T",
+			statement,
+		)
+	}
+	amountOfPairs := len(hashMap.Pairs)
+
+	if amountOfPairs != 0 {
+		t.Errorf(
+			"wrong amount of has map pairs, got=%d",
+			amountOfPairs,
+		)
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	input := `throw error;`
+
+	lexer := lexer.NewLexer(input)
+	parser := NewParser(lexer)
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has wrong amount of statements, expected 1, got=%d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, statement.Value, "error") {
+		return
+	}
+}
+
+func TestGeneratorFunction(t *testing.T) {
+	input := `fn* count(n) { yield n + 1; yield; }`
+
+	lexer := lexer.NewLexer(input)
+	parser := NewParser(lexer)
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has wrong amount of statements, expected 1, got=%d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	function, ok := statement.Value.(*ast.FuncLiteral)
+	if !ok {
+		t.Fatalf("statement.Value is not *ast.FuncLiteral. got=%T", statement.Value)
+	}
+
+	if !function.IsGenerator {
+		t.Errorf("function is not a generator")
+	}
+
+	if function.String() != "fn* count(n)yield (n + 1);yield;" {
+		t.Errorf("wrong function string. got=%q", function.String())
+	}
+
+	if len(function.Body.Statements) != 2 {
+		t.Fatalf("function body has wrong amount of statements, expected 2, got=%d", len(function.Body.Statements))
+	}
+
+	yieldValue, ok := function.Body.Statements[0].(*ast.YieldStatement)
+	if !ok {
+		t.Fatalf("function.Body.Statements[0] is not *ast.YieldStatement. got=%T", function.Body.Statements[0])
+	}
+
+	if !testInfixExpression(t, yieldValue.Value, "n", "+", 1) {
+		return
+	}
+
+	yieldNothing, ok := function.Body.Statements[1].(*ast.YieldStatement)
+	if !ok {
+		t.Fatalf("function.Body.Statements[1] is not *ast.YieldStatement. got=%T", function.Body.Statements[1])
+	}
+
+	if yieldNothing.Value != nil {
+		t.Errorf("yield without value has value %s", yieldNothing.Value)
+	}
+}
+
+func TestSpawnExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"spawn work(1, 2)", "spawn work(1, 2)"},
+		{"spawn fn() { 1 }", "spawn fn ()1"},
+		{"let t = spawn pool.run(job);", "let t = spawn pool.run(job);"},
+		{"spawn work() + 1", "(spawn work() + 1)"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedParam string
+		hasCatch      bool
+		hasFinally    bool
+		expected      string
+	}{
+		{"try { x } catch (e) { e };", "e", true, false, "try x catch (e) e"},
+		{"try { x } catch { y }", "", true, false, "try x catch y"},
+		{"try { x } finally { y }", "", false, true, "try x finally y"},
+		{"try { x } catch (e) { e } finally { y }", "e", true, true, "try x catch (e) e finally y"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong amount of statements, expected 1, got=%d", len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.TryStatement. got=%T", program.Statements[0])
+		}
+
+		param := ""
+		if statement.CatchParam != nil {
+			param = statement.CatchParam.Value
+		}
+		if param != tt.expectedParam {
+			t.Errorf("wrong catch param. expected=%q, got=%q", tt.expectedParam, param)
+		}
+
+		if (statement.Catch != nil) != tt.hasCatch {
+			t.Errorf("wrong catch block presence. expected=%t", tt.hasCatch)
+		}
+
+		if (statement.Finally != nil) != tt.hasFinally {
+			t.Errorf("wrong finally block presence. expected=%t", tt.hasFinally)
+		}
+
+		if statement.String() != tt.expected {
+			t.Errorf("wrong statement. expected=%q, got=%q", tt.expected, statement.String())
+		}
+	}
+}
+
+func TestTryStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"try { x }", "try must be followed by catch or finally"},
+		{"try { x } catch (1) { y }", "expected next token to be IDENT, got INT instead"},
+		{"try { x } catch e { y }", "expected next token to be {, got IDENT instead"},
+		{"try x catch { y }", "expected next token to be {, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestFunctionNames(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedName string
+	}{
+		{"fn add(a, b) { a + b }", "add"},
+		{"let add = fn(a, b) { a + b };", "add"},
+		{"fn(a, b) { a + b }", ""},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		var function *ast.FuncLiteral
+		switch statement := program.Statements[0].(type) {
+		case *ast.LetStatement:
+			function = statement.Value.(*ast.FuncLiteral)
+		case *ast.ExpressionStatement:
+			function = statement.Value.(*ast.FuncLiteral)
+		}
+
+		if function.Name != tt.expectedName {
+			t.Errorf("wrong function name. expected=%q, got=%q", tt.expectedName, function.Name)
+		}
+	}
+}
+
+func TestImportStatement(t *testing.T) {
+	input := `import "lib/strings.qrk" as s;`
+
+	lexer := lexer.NewLexer(input)
+	parser := NewParser(lexer)
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has wrong amount of statements, expected 1, got=%d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ImportStatement. got=%T", program.Statements[0])
+	}
+
+	if statement.Path.Value != "lib/strings.qrk" {
+		t.Errorf("wrong import path. expected=%q, got=%q", "lib/strings.qrk", statement.Path.Value)
+	}
+
+	if !testIdentifier(t, statement.Alias, "s") {
+		return
+	}
+}
+
+func TestExportStatement(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedName string
+	}{
+		{"export let answer = 42;", "answer"},
+		{"export const LIMIT = 10;", "LIMIT"},
+		{"export fn shout(s) { s }", "shout"},
+		{"export struct Point { x, y }", "Point"},
+		{"export fn* evens(n) { yield n; }", "evens"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong amount of statements, expected 1, got=%d", len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.ExportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ExportStatement. got=%T", program.Statements[0])
+		}
+
+		if statement.Name() != tt.expectedName {
+			t.Errorf("wrong exported name. expected=%q, got=%q", tt.expectedName, statement.Name())
+		}
+	}
+}
+
+func TestImportExportErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"import lib as l;", "expected next token to be STRING, got IDENT instead"},
+		{`import "lib.qrk";`, "expected next token to be AS, got ; instead"},
+		{`import "lib.qrk" as "l";`, "expected next token to be IDENT, got STRING instead"},
+		{"export 1;", "only let, const, struct and named fn declarations can be exported, got INT instead"},
+		{"export fn(x) { x };", "only let, const, struct and named fn declarations can be exported, got FUNCTION instead"},
+		{"export let [a] = arr;", "only let, const, struct and named fn declarations can be exported, got LET instead"},
+		{"export const [a] = arr;", "only let, const, struct and named fn declarations can be exported, got CONST instead"},
+		{"export fn f() { 1 } + 1;", "cannot export expression (fn f()1 + 1)"},
+		{"export fn*(n) { yield n; }", "cannot export expression fn* (n)yield n;"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestMemberExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"s.shout", "s.shout"},
+		{`s.shout("a")`, `s.shout("a")`},
+		{"a.b.c", "a.b.c"},
+		{"s.list[0]", "(s.list[0]"},
+		{"-s.x * 2", "((-s.x) * 2)"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestStructStatement(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedName   string
+		expectedFields []string
+		expectedString string
+	}{
+		{"struct Point { x, y }", "Point", []string{"x", "y"}, "struct Point { x, y }"},
+		{"struct Point { x, y, };", "Point", []string{"x", "y"}, "struct Point { x, y }"},
+		{"struct Unit {}", "Unit", []string{}, "struct Unit {  }"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong amount of statements, expected 1, got=%d", len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.StructStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.StructStatement. got=%T", program.Statements[0])
+		}
+
+		if !testIdentifier(t, statement.Name, tt.expectedName) {
+			return
+		}
+
+		if len(statement.Fields) != len(tt.expectedFields) {
+			t.Fatalf(
+				"wrong number of fields, expected=%d, got=%d",
+				len(tt.expectedFields), len(statement.Fields),
+			)
+		}
+
+		for i, field := range statement.Fields {
+			testIdentifier(t, field, tt.expectedFields[i])
+		}
+
+		if statement.String() != tt.expectedString {
+			t.Errorf("wrong struct. expected=%q, got=%q", tt.expectedString, statement.String())
+		}
+	}
+}
+
+func TestStructStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"struct { x }", "expected next token to be IDENT, got { instead"},
+		{"struct Point x, y", "expected next token to be {, got IDENT instead"},
+		{"struct Point { x y }", "expected next token to be ,, got IDENT instead"},
+		{"struct Point { x: 1 }", "expected next token to be ,, got : instead"},
+		{"struct Point { x, x }", "duplicate field x in struct Point"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestMemberAssignStatement(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedTarget string
+		expectedValue  interface{}
+	}{
+		{"p.x = 1;", "p.x", 1},
+		{"p.to.y = z", "p.to.y", "z"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.NewLexer(tt.input)
+		parser := NewParser(lexer)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong amount of statements, expected 1, got=%d", len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.MemberAssignStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.MemberAssignStatement. got=%T", program.Statements[0])
+		}
+
+		if statement.Target.String() != tt.expectedTarget {
+			t.Errorf("wrong target. expected=%q, got=%q", tt.expectedTarget, statement.Target.String())
+		}
+
+		testLiteralExpression(t, statement.Value, tt.expectedValue)
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/vdchnsk/qrk/src/evaluator"
 	"github.com/vdchnsk/qrk/src/fs"
 	"github.com/vdchnsk/qrk/src/lexer"
+	"github.com/vdchnsk/qrk/src/module"
 	"github.com/vdchnsk/qrk/src/object"
 	"github.com/vdchnsk/qrk/src/parser"
 	"github.com/vdchnsk/qrk/src/stdlib"
@@ -36,7 +37,8 @@ func RunFile(path string, out io.Writer) {
 		return
 	}
 
-	env := object.NewEnvironment()
+	loader := module.NewLoader[*object.Module](path, module.SearchPathsFromEnv()...)
+	env := object.NewModuleEnvironment(loader)
 	Interpret(string(data), env, out)
 }
 
@@ -57,7 +59,16 @@ func Interpret(input string, env *object.Environment, out io.Writer) object.Obje
 	return evalRes
 }
 
-func Compile(input string, out io.Writer, symbolTable *compiler.SymbolTable, constants []object.Object, globals []object.Object) object.Object {
+// Compile compiles and runs the input, the returned constants include the ones added by the input,
+// so that the symbols defined by the input keep referring to them in the following compilations
+func Compile(
+	input string,
+	out io.Writer,
+	symbolTable *compiler.SymbolTable,
+	constants []object.Object,
+	globals []object.Object,
+	loader *module.Loader[*compiler.Module],
+) (object.Object, []object.Object) {
 	line := string(input)
 	lexer := lexer.NewLexer(line)
 	parser := parser.NewParser(lexer)
@@ -66,7 +77,7 @@ func Compile(input string, out io.Writer, symbolTable *compiler.SymbolTable, con
 
 	if len(parser.Errors()) != 0 {
 		parser.PrettyPrintErrors(out)
-		return nil
+		return nil, constants
 	}
 
 	for i, f := range stdlib.Funcs {
@@ -74,6 +85,7 @@ func Compile(input string, out io.Writer, symbolTable *compiler.SymbolTable, con
 	}
 
 	compiler := compiler.NewWithState(symbolTable, constants)
+	compiler.SetLoader(loader)
 	err := compiler.Compile(program)
 	if err != nil {
 		fmt.Fprintf(out, "compilation failed: %s\n", err)
@@ -84,8 +96,20 @@ func Compile(input string, out io.Writer, symbolTable *compiler.SymbolTable, con
 	err = vm.Run()
 	if err != nil {
 		fmt.Fprintf(out, "vm error: %s\n", err)
+		printStackTrace(out, err)
 	}
 
 	stackTopElem := vm.LastPoppedStackElem()
-	return stackTopElem
+	return stackTopElem, bytecode.Constants
+}
+
+func printStackTrace(out io.Writer, err error) {
+	uncaught, ok := err.(*vm.UncaughtException)
+	if !ok {
+		return
+	}
+
+	for _, frame := range uncaught.StackTrace() {
+		fmt.Fprintf(out, "\tat %s\n", frame)
+	}
 }
@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/vdchnsk/qrk/src/compiler"
+	"github.com/vdchnsk/qrk/src/lexer"
+	"github.com/vdchnsk/qrk/src/module"
+	"github.com/vdchnsk/qrk/src/object"
+	"github.com/vdchnsk/qrk/src/parser"
+	"github.com/vdchnsk/qrk/src/vm"
+)
+
+func TestCompileLines(t *testing.T) {
+	tests := []struct {
+		lines    []string
+		expected string
+	}{
+		{[]string{"let x = 5;", "x"}, "5"},
+		{[]string{"const x = 5;", "let y = 100;", "x"}, "5"},
+		{[]string{"const x = 5;", `"hello"; x`}, "5"},
+		{[]string{"const x = 5;", `const s = "a";`, "x + 1", "s"}, "a"},
+		{[]string{"fn double(n) { n * 2 }", "const x = 21;", "double(x)"}, "42"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+
+		symbolTable := compiler.NewSymbolTable()
+		constants := []object.Object{}
+		globals := make([]object.Object, vm.GlobalVarsSize)
+		loader := module.NewLoader[*compiler.Module]("")
+
+		var result object.Object
+		for _, line := range tt.lines {
+			result, constants = Compile(line, &out, symbolTable, constants, globals, loader)
+		}
+
+		if out.Len() != 0 {
+			t.Fatalf("unexpected output for %q: %s", tt.lines, out.String())
+		}
+
+		if result == nil || result.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%s, got=%v", tt.lines, tt.expected, result)
+		}
+	}
+}
+
+func TestDefaultArgumentsOnBothBackends(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn f(a, b = a + 1) { b } f(1)", "2"},
+		{"let b = 5; fn f(a = fn() { b }, b = 1) { a() } f()", "1"},
+		{"fn f(a = b, b = 1) { a } f()", "defaults may only refer to earlier parameters: b"},
+		{"let b = 5; fn f(a = b, b = 1) { a } f()", "defaults may only refer to earlier parameters: b"},
+		{"fn f(a = rest, ...rest) { a } f()", "defaults may only refer to earlier parameters: rest"},
+	}
+
+	for _, tt := range tests {
+		if got := runCompiled(tt.input); got != tt.expected {
+			t.Errorf("wrong vm result for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+
+		if got := runInterpreted(tt.input); got != tt.expected {
+			t.Errorf("wrong evaluator result for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestMatchOnBothBackends(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match 1.0 { 1 => "int", _ => "other" }`, "int"},
+		{`match 1 { 1.0 => "float", _ => "other" }`, "float"},
+		{`match 1.0 { x if x > 5 => "big", 1 => "int", _ => "other" }`, "int"},
+		{`match -0.0 { 0 => "zero", _ => "other" }`, "zero"},
+		{`match 1.5 { 1 => "int", _ => "other" }`, "other"},
+		{`match 2 ** 70 { 1180591620717411303424.0 => "big", _ => "other" }`, "big"},
+	}
+
+	for _, tt := range tests {
+		if got := runCompiled(tt.input); got != tt.expected {
+			t.Errorf("wrong vm result for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+
+		if got := runInterpreted(tt.input); got != tt.expected {
+			t.Errorf("wrong evaluator result for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// runCompiled reports the result of running the input on the vm, or the error compiling or running it has failed with
+func runCompiled(input string) string {
+	program := parser.NewParser(lexer.NewLexer(input)).ParseProgram()
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return err.Error()
+	}
+
+	machine := vm.New(comp.Bytecode())
+	if err := machine.Run(); err != nil {
+		return err.Error()
+	}
+
+	return machine.LastPoppedStackElem().Inspect()
+}
+
+// runInterpreted reports the result of evaluating the input, or the message of the error it has failed with
+func runInterpreted(input string) string {
+	result := Interpret(input, object.NewEnvironment(), io.Discard)
+	if err, ok := result.(*object.Error); ok {
+		return err.Message
+	}
+
+	return result.Inspect()
+}
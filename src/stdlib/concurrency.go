@@ -0,0 +1,119 @@
+package stdlib
+
+import "github.com/vdchnsk/qrk/src/object"
+
+// channelBuiltin accepts channel() and channel(capacity), sends to a channel without capacity wait for the receiver
+func channelBuiltin(args ...object.Object) object.Object {
+	capacity := int64(0)
+
+	if len(args) > 0 {
+		integer, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to `channel` must be INTEGER, got %s", args[0].Type())
+		}
+		capacity = integer.Value
+	}
+
+	if capacity < 0 {
+		return newError("capacity of `channel` must not be negative, got %d", capacity)
+	}
+
+	return object.NewChannel(int(capacity))
+}
+
+// sendBuiltin hands a copy of the value over to the receiver, so that the tasks don't share mutable values
+func sendBuiltin(args ...object.Object) object.Object {
+	channel, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("argument to `send` must be CHANNEL, got %s", args[0].Type())
+	}
+
+	ok, err := channel.Send(object.NewCopier().Copy(args[1]))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newError("send on closed channel")
+	}
+
+	return NULL
+}
+
+// recvBuiltin waits for the next value sent to the channel, the result is null once the channel is closed and drained
+func recvBuiltin(args ...object.Object) object.Object {
+	channel, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("argument to `recv` must be CHANNEL, got %s", args[0].Type())
+	}
+
+	value, ok, err := channel.Recv()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NULL
+	}
+
+	return value
+}
+
+func closeBuiltin(args ...object.Object) object.Object {
+	channel, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("argument to `close` must be CHANNEL, got %s", args[0].Type())
+	}
+
+	if !channel.Close() {
+		return newError("close of closed channel")
+	}
+
+	return NULL
+}
+
+// selectBuiltin waits for the first of the channels in the array to receive a value,
+// the result is [index of the channel, value], the value is null if the channel is closed and drained
+func selectBuiltin(args ...object.Object) object.Object {
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `select` must be ARRAY of CHANNEL, got %s", args[0].Type())
+	}
+
+	if len(array.Elements) == 0 {
+		return newError("argument to `select` must not be empty")
+	}
+
+	channels := make([]*object.Channel, len(array.Elements))
+	for i, element := range array.Elements {
+		channel, ok := element.(*object.Channel)
+		if !ok {
+			return newError("argument to `select` must be ARRAY of CHANNEL, got %s at %d", element.Type(), i)
+		}
+		channels[i] = channel
+	}
+
+	chosen, value, ok, err := object.Select(channels)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		value = NULL
+	}
+
+	return &object.Array{Elements: []object.Object{&object.Integer{Value: int64(chosen)}, value}}
+}
+
+// joinBuiltin waits for the spawned task to finish, the error the task has failed with is raised again in the joining code.
+// The result is copied, since the task can be joined by several tasks
+func joinBuiltin(args ...object.Object) object.Object {
+	task, ok := args[0].(*object.Task)
+	if !ok {
+		return newError("argument to `join` must be TASK, got %s", args[0].Type())
+	}
+
+	result, err := task.Join()
+	if err != nil {
+		return object.NewCopier().CopyError(err)
+	}
+
+	return object.NewCopier().Copy(result)
+}
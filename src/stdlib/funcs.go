@@ -9,14 +9,6 @@ import (
 var NULL = &object.Null{}
 
 func lenBuiltin(args ...object.Object) object.Object {
-	maxAllowedArgs := 1
-
-	if len(args) > maxAllowedArgs {
-		return newError(
-			"wrong number of arguments passed to len func, got=%d, supported=%d",
-			len(args), maxAllowedArgs,
-		)
-	}
 	switch arg := args[0].(type) {
 	case *object.String:
 		return &object.Integer{
@@ -37,12 +29,80 @@ func print(args ...object.Object) object.Object {
 	return NULL
 }
 
+// rangeBuiltin accepts range(end), range(start, end) and range(start, end, step)
+func rangeBuiltin(args ...object.Object) object.Object {
+	bounds := make([]int64, len(args))
+
+	for i, arg := range args {
+		integer, ok := arg.(*object.Integer)
+		if !ok {
+			return newError(
+				"argument %d to `range` must be INTEGER, got %s",
+				i, arg.Type(),
+			)
+		}
+		bounds[i] = integer.Value
+	}
+
+	start, end, step := int64(0), bounds[0], int64(1)
+	if len(bounds) > 1 {
+		start, end = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+
+	if step == 0 {
+		return newError("step of `range` must not be zero")
+	}
+
+	return &object.Range{Start: start, End: end, Step: step}
+}
+
+// nextBuiltin resumes the generator up to its next yield, the result is null once the generator has finished
+func nextBuiltin(args ...object.Object) object.Object {
+	generator, ok := args[0].(*object.Generator)
+	if !ok {
+		return newError(
+			"argument to `next` must be GENERATOR, got %s",
+			args[0].Type(),
+		)
+	}
+
+	value, ok, err := generator.Resume()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NULL
+	}
+
+	return value
+}
+
 var FuncsMap = map[string]*object.BuiltInFunction{
-	"len":   {Fn: lenBuiltin, Name: "len", ParamsCount: 1},
-	"print": {Fn: print, Name: "print", ParamsCount: 1},
+	"len":   {Fn: lenBuiltin, Name: "len", Arity: object.ExactArity(1)},
+	"print": {Fn: print, Name: "print", Arity: object.Arity{Min: 1, Max: object.VariadicArity}},
+	"range": {Fn: rangeBuiltin, Name: "range", Arity: object.Arity{Min: 1, Max: 3}},
+	"next":  {Fn: nextBuiltin, Name: "next", Arity: object.ExactArity(1)},
+
+	"channel": {Fn: channelBuiltin, Name: "channel", Arity: object.Arity{Min: 0, Max: 1}},
+	"send":    {Fn: sendBuiltin, Name: "send", Arity: object.ExactArity(2)},
+	"recv":    {Fn: recvBuiltin, Name: "recv", Arity: object.ExactArity(1)},
+	"close":   {Fn: closeBuiltin, Name: "close", Arity: object.ExactArity(1)},
+	"select":  {Fn: selectBuiltin, Name: "select", Arity: object.ExactArity(1)},
+	"join":    {Fn: joinBuiltin, Name: "join", Arity: object.ExactArity(1)},
 }
 
 var Funcs = []*object.BuiltInFunction{
 	FuncsMap["len"],
 	FuncsMap["print"],
+	FuncsMap["range"],
+	FuncsMap["next"],
+	FuncsMap["channel"],
+	FuncsMap["send"],
+	FuncsMap["recv"],
+	FuncsMap["close"],
+	FuncsMap["select"],
+	FuncsMap["join"],
 }
@@ -5,16 +5,37 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// position of the first char of the token in the input
+	Line   int
+	Column int
 }
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"true":   TRUE,
-	"false":  FALSE,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"true":     TRUE,
+	"false":    FALSE,
+	"while":    WHILE,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"for":      FOR,
+	"in":       IN,
+	"match":    MATCH,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"import":   IMPORT,
+	"export":   EXPORT,
+	"as":       AS,
+	"struct":   STRUCT,
+	"yield":    YIELD,
+	"spawn":    SPAWN,
+	"const":    CONST,
 }
 
 func LookupIdentifier(ident string) TokenType {
@@ -31,25 +52,43 @@ const (
 	// Identifiers + literals
 	IDENT  = "IDENT" // add, foobar, x, y, ...
 	INT    = "INT"   // 1343456
+	FLOAT  = "FLOAT" // 3.14, 1e10, 2.5e-3
 	STRING = "STRING"
+	// Parts of an interpolated string, e.g. "a ${x} b ${y} c" is lexed into
+	// INTERPOLATION_START("a "), x, INTERPOLATION_MIDDLE(" b "), y, INTERPOLATION_END(" c")
+	INTERPOLATION_START  = "INTERPOLATION_START"
+	INTERPOLATION_MIDDLE = "INTERPOLATION_MIDDLE"
+	INTERPOLATION_END    = "INTERPOLATION_END"
 	// Operators
-	ASSIGN   = "="
-	PLUS     = "+"
-	MINUS    = "-"
-	BANG     = "!"
-	ASTERISK = "*"
-	SLASH    = "/"
-	LT       = "<"
-	GT       = ">"
-	EQ       = "=="
-	NOT_EQ   = "!="
-	ARROW    = "=>"
-	AND      = "&&"
-	OR       = "||"
+	ASSIGN      = "="
+	PLUS        = "+"
+	MINUS       = "-"
+	BANG        = "!"
+	ASTERISK    = "*"
+	SLASH       = "/"
+	PERCENT     = "%"
+	POWER       = "**"
+	LT          = "<"
+	GT          = ">"
+	LT_EQ       = "<="
+	GT_EQ       = ">="
+	EQ          = "=="
+	NOT_EQ      = "!="
+	ARROW       = "=>"
+	AND         = "&&"
+	OR          = "||"
+	BIT_AND     = "&"
+	BIT_OR      = "|"
+	BIT_XOR     = "^"
+	TILDE       = "~"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	ELLIPSIS  = "..."
+	DOT       = "."
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACE    = "{"
@@ -64,6 +103,23 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	FOR      = "FOR"
+	IN       = "IN"
+	MATCH    = "MATCH"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	AS       = "AS"
+	STRUCT   = "STRUCT"
+	YIELD    = "YIELD"
+	SPAWN    = "SPAWN"
+	CONST    = "CONST"
 	// Errors
 	LEXING_ERROR = "LEXING_ERROR"
 )
@@ -1,8 +1,10 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/vdchnsk/qrk/src/code"
 	"github.com/vdchnsk/qrk/src/object"
 )
 
@@ -11,7 +13,126 @@ var (
 		return fmt.Errorf("calling a non-function object: %s", got)
 	}
 
-	ErrWrongNumberOfArguments = func(expected, got int) error {
-		return fmt.Errorf("wrong number of arguments, expected=%d, got=%d", expected, got)
+	ErrWrongNumberOfArguments = func(function string, expected object.Arity, got int) error {
+		return errors.New(object.WrongArgumentsCountMessage(function, expected, got))
+	}
+
+	ErrUninitializedGlobal = func(index int) error {
+		return fmt.Errorf("global binding %d is used before initialization", index)
+	}
+
+	ErrUninitializedLocal = func(index int) error {
+		return fmt.Errorf("local binding %d is used before initialization", index)
+	}
+
+	ErrUninitializedFree = func(index int) error {
+		return fmt.Errorf("captured binding %d is used before initialization", index)
+	}
+
+	ErrNotIterable = func(got object.ObjectType) error {
+		return fmt.Errorf("object is not iterable: %s", got)
+	}
+
+	ErrIndexOutOfBounds = func(index int64, length int) error {
+		return fmt.Errorf("index out of bounds: %d, length is %d", index, length)
+	}
+
+	ErrSliceNotSupported = func(got object.ObjectType) error {
+		return fmt.Errorf("slice operator not supported: %s", got)
+	}
+
+	ErrSliceBoundNotInteger = func(got object.ObjectType) error {
+		return fmt.Errorf("slice bound must be an integer, got %s", got)
+	}
+
+	ErrMemberAccessNotSupported = func(got object.ObjectType) error {
+		return fmt.Errorf("member access not supported: %s", got)
+	}
+
+	ErrNoSuchField = func(structName, field string) error {
+		return fmt.Errorf("no such field: %s.%s", structName, field)
+	}
+
+	ErrNotAFunctionConstant = func(got object.Object) error {
+		return fmt.Errorf("not a function constant: %+v", got)
+	}
+
+	ErrUnknownOperator = func(left object.ObjectType, op code.Opcode, right object.ObjectType) error {
+		return fmt.Errorf("unknown operator: %s %s %s", left, binaryOperators[op], right)
 	}
 )
+
+// binaryOperators maps the opcodes of binary operations back to the operators they are compiled from
+var binaryOperators = map[code.Opcode]string{
+	code.OpAdd:        "+",
+	code.OpSub:        "-",
+	code.OpMul:        "*",
+	code.OpDiv:        "/",
+	code.OpMod:        "%",
+	code.OpPow:        "**",
+	code.OpBitAnd:     "&",
+	code.OpBitOr:      "|",
+	code.OpBitXor:     "^",
+	code.OpShiftLeft:  "<<",
+	code.OpShiftRight: ">>",
+}
+
+// UncaughtException is returned by Run when neither a thrown value, nor a runtime error is caught
+type UncaughtException struct {
+	Value object.Object
+
+	stackTrace []string
+}
+
+func (ue *UncaughtException) Error() string {
+	if exception, ok := ue.Value.(*object.Exception); ok {
+		return exception.Message
+	}
+
+	return "uncaught exception: " + ue.Value.Inspect()
+}
+
+// StackTrace is the trace of the place the runtime error happened at, or the value was thrown at
+func (ue *UncaughtException) StackTrace() []string {
+	return ue.stackTrace
+}
+
+// thrownValue stops the execution once `throw` is executed
+type thrownValue struct {
+	value      object.Object
+	stackTrace []string
+}
+
+func (tv *thrownValue) Error() string {
+	return "uncaught exception: " + tv.value.Inspect()
+}
+
+// generatorSuspension stops the execution of the generator once it yields, or once its body finishes
+type generatorSuspension struct {
+	value object.Object
+	done  bool
+}
+
+func (gs *generatorSuspension) Error() string {
+	return "generator is suspended outside of a generator call"
+}
+
+// errorFromObject turns the error reported by a builtin into the error stopping the execution,
+// errors carrying a thrown value keep being that value, so that they can be caught as is
+func errorFromObject(err *object.Error) error {
+	if err.Value != nil {
+		return &thrownValue{value: err.Value, stackTrace: err.StackTrace}
+	}
+
+	return errors.New(err.Message)
+}
+
+// objectErrorFrom turns the exception which isn't caught inside of a generator or a task
+// into the error reported by the generator or the task
+func objectErrorFrom(exception object.Object, stackTrace []string) *object.Error {
+	if ex, ok := exception.(*object.Exception); ok {
+		return &object.Error{Message: ex.Message, Value: ex, StackTrace: stackTrace}
+	}
+
+	return &object.Error{Message: "uncaught exception: " + exception.Inspect(), Value: exception, StackTrace: stackTrace}
+}
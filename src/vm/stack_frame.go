@@ -6,19 +6,49 @@ import (
 )
 
 type StackFrame struct {
-	fn          *object.CompiledFunction
+	closure     *object.Closure
 	ip          int
 	basePointer int
+	// amount of arguments the function is called with, parameters past them take their default values
+	argsCount int
+
+	// exception handlers of the try statements being executed in the frame, innermost last
+	handlers []handler
+	// stack pointers at the beginning of the loops being executed in the frame, innermost last,
+	// `break` and `continue` drop the values pushed after it
+	loops []int
+}
+
+// handler is where the execution resumes once an exception is thrown inside of a try statement
+type handler struct {
+	position int
+	// stack pointer at the beginning of the try statement, values pushed after it are dropped
+	stackPointer int
+	// amount of loops the try statement is nested in, loops entered after it are left
+	loopsCount int
 }
 
-func NewStackFrame(fn *object.CompiledFunction, basePointer int) *StackFrame {
+func NewStackFrame(closure *object.Closure, basePointer int) *StackFrame {
 	return &StackFrame{
-		fn:          fn,
+		closure:     closure,
 		ip:          -1,
 		basePointer: basePointer,
 	}
 }
 
+// rebase moves the frame of a resumed generator to the new place on the stack
+func (sf *StackFrame) rebase(basePointer int) {
+	offset := basePointer - sf.basePointer
+
+	sf.basePointer = basePointer
+	for i := range sf.handlers {
+		sf.handlers[i].stackPointer += offset
+	}
+	for i := range sf.loops {
+		sf.loops[i] += offset
+	}
+}
+
 func (sf *StackFrame) Instructions() code.Instructions {
-	return sf.fn.Instructions
+	return sf.closure.Fn.Instructions
 }
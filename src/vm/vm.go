@@ -2,6 +2,8 @@ package vm
 
 import (
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/vdchnsk/qrk/src/code"
 	"github.com/vdchnsk/qrk/src/compiler"
@@ -45,10 +47,11 @@ func nativeToObjectBoolean(nativeValue bool) object.Object {
 
 func New(bytecode *compiler.Bytecode) *VM {
 	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainClosure := &object.Closure{Fn: mainFn}
 
 	stackFrames := make([]*StackFrame, MaxStackFrames)
 
-	mainStackFrame := NewStackFrame(mainFn, 0)
+	mainStackFrame := NewStackFrame(mainClosure, 0)
 	stackFrames[0] = mainStackFrame
 
 	stack := make([]object.Object, StackSize)
@@ -71,7 +74,24 @@ func NewVmWithGlobalStore(bytecode *compiler.Bytecode, globals []object.Object)
 	return vm
 }
 
+// Run executes the bytecode, runtime errors and thrown values are handled by the innermost try statement,
+// the ones which aren't caught are returned as UncaughtException
 func (vm *VM) Run() error {
+	for {
+		err := vm.execute()
+		if err == nil {
+			return nil
+		}
+
+		exception, stackTrace := vm.exceptionFrom(err)
+		if !vm.unwind(exception, 0) {
+			return &UncaughtException{Value: exception, stackTrace: stackTrace}
+		}
+	}
+}
+
+// execute runs instructions until the end of the program, or until an error
+func (vm *VM) execute() error {
 	for vm.curStackFrame().ip < len(vm.curStackFrame().Instructions())-1 {
 		vm.curStackFrame().ip++
 
@@ -96,7 +116,8 @@ func (vm *VM) Run() error {
 				return err
 			}
 
-		case code.OpAdd, code.OpDiv, code.OpMul, code.OpSub:
+		case code.OpAdd, code.OpDiv, code.OpMul, code.OpSub, code.OpMod, code.OpPow,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight:
 			err := vm.executeBinaryOperation(opcode)
 			if err != nil {
 				return err
@@ -114,22 +135,29 @@ func (vm *VM) Run() error {
 				return err
 			}
 
-		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpAnd, code.OpOr:
+		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpGreaterThanOrEqual,
+			code.OpLessThan, code.OpLessThanOrEqual:
 			err := vm.executeComparisonOperation(opcode)
 			if err != nil {
-				return nil
+				return err
 			}
 
 		case code.OpBang:
 			err := vm.executeBangOperation()
 			if err != nil {
-				return nil
+				return err
 			}
 
 		case code.OpMinus:
 			err := vm.executeMinusOperation()
 			if err != nil {
-				return nil
+				return err
+			}
+
+		case code.OpBitNot:
+			err := vm.executeBitNotOperation()
+			if err != nil {
+				return err
 			}
 
 		case code.OpGoto:
@@ -138,6 +166,23 @@ func (vm *VM) Run() error {
 
 			vm.curStackFrame().ip = newPosOperand - 1
 
+		case code.OpGotoTable:
+			argIp := instructionPointer + 1
+			tableIndex := int(utils.ReadUint16(instructions[argIp:]))
+
+			vm.curStackFrame().ip += 2
+
+			table := vm.constants[tableIndex].(*object.GotoTable)
+
+			key, ok := object.PatternKey(vm.StackTop())
+			if !ok {
+				continue
+			}
+
+			if target, ok := table.Targets[key]; ok {
+				vm.curStackFrame().ip = target - 1
+			}
+
 		case code.OpGotoNotTruthy:
 			condition := vm.stackPop()
 
@@ -156,6 +201,21 @@ func (vm *VM) Run() error {
 
 			vm.curStackFrame().ip += op.OperandWidths[0]
 
+		case code.OpGotoNotTruthyOrPop, code.OpGotoTruthyOrPop:
+			argIp := instructionPointer + 1
+			newPosOperand := int(utils.ReadUint16(instructions[argIp:]))
+
+			vm.curStackFrame().ip += 2
+
+			// the value deciding the result of `&&` or `||` is left on the stack
+			isDecided := isTruthy(vm.StackTop()) == (opcode == code.OpGotoTruthyOrPop)
+			if isDecided {
+				vm.curStackFrame().ip = newPosOperand - 1
+				continue
+			}
+
+			vm.stackPop()
+
 		case code.OpNull:
 			err := vm.stackPush(Null)
 			if err != nil {
@@ -185,7 +245,12 @@ func (vm *VM) Run() error {
 			}
 			vm.curStackFrame().ip += op.OperandWidths[0]
 
+			// hoisted function names can be referenced before their declaration is executed
 			value := vm.globals[globalIndex]
+			if value == nil {
+				return ErrUninitializedGlobal(int(globalIndex))
+			}
+
 			if err = vm.stackPush(value); err != nil {
 				return err
 			}
@@ -204,7 +269,12 @@ func (vm *VM) Run() error {
 			local := vm.stackPop()
 			localLocation := frame.basePointer + int(localIndex)
 
-			vm.stack[localLocation] = local
+			// captured locals live in cells shared with the closures
+			if cell, ok := vm.stack[localLocation].(*object.Cell); ok {
+				cell.Value = local
+			} else {
+				vm.stack[localLocation] = local
+			}
 
 		case code.OpGetLocal:
 			localIndex := utils.ReadUint8(instructions[instructionPointer+1:])
@@ -220,6 +290,14 @@ func (vm *VM) Run() error {
 			location := frame.basePointer + int(localIndex)
 			local := vm.stack[location]
 
+			if cell, ok := local.(*object.Cell); ok {
+				local = cell.Value
+			}
+
+			if local == nil {
+				return ErrUninitializedLocal(int(localIndex))
+			}
+
 			if err := vm.stackPush(local); err != nil {
 				return err
 			}
@@ -237,6 +315,81 @@ func (vm *VM) Run() error {
 				return err
 			}
 
+		case code.OpGetFree:
+			freeIndex := utils.ReadUint8(instructions[instructionPointer+1:])
+
+			op, err := code.LookupOperation(instructionByte)
+			if err != nil {
+				return err
+			}
+			vm.curStackFrame().ip += op.OperandWidths[0]
+
+			free := vm.curStackFrame().closure.Free[freeIndex].Value
+			if free == nil {
+				return ErrUninitializedFree(int(freeIndex))
+			}
+
+			if err := vm.stackPush(free); err != nil {
+				return err
+			}
+
+		case code.OpSetFree:
+			freeIndex := utils.ReadUint8(instructions[instructionPointer+1:])
+
+			op, err := code.LookupOperation(instructionByte)
+			if err != nil {
+				return err
+			}
+			vm.curStackFrame().ip += op.OperandWidths[0]
+
+			closure := vm.curStackFrame().closure
+			closure.Free[freeIndex].Value = vm.stackPop()
+
+		case code.OpCaptureLocal:
+			localIndex := utils.ReadUint8(instructions[instructionPointer+1:])
+			vm.curStackFrame().ip += 1
+
+			location := vm.curStackFrame().basePointer + int(localIndex)
+
+			cell, ok := vm.stack[location].(*object.Cell)
+			if !ok {
+				cell = &object.Cell{Value: vm.stack[location]}
+				vm.stack[location] = cell
+			}
+
+			if err := vm.stackPush(cell); err != nil {
+				return err
+			}
+
+		case code.OpCaptureFree:
+			freeIndex := utils.ReadUint8(instructions[instructionPointer+1:])
+			vm.curStackFrame().ip += 1
+
+			closure := vm.curStackFrame().closure
+			if err := vm.stackPush(closure.Free[freeIndex]); err != nil {
+				return err
+			}
+
+		case code.OpClosure:
+			argIp := instructionPointer + 1
+			constantIndex := int(utils.ReadUint16(instructions[argIp:]))
+			freeCount := int(utils.ReadUint8(instructions[argIp+2:]))
+
+			op, err := code.LookupOperation(instructionByte)
+			if err != nil {
+				return err
+			}
+			vm.curStackFrame().ip += op.OperandWidths[0] + op.OperandWidths[1]
+
+			if err := vm.pushClosure(constantIndex, freeCount); err != nil {
+				return err
+			}
+
+		case code.OpCurrentClosure:
+			if err := vm.stackPush(vm.curStackFrame().closure); err != nil {
+				return err
+			}
+
 		case code.OpArray:
 			argIp := instructionPointer + 1
 			arraySize := int(utils.ReadUint16(instructions[argIp:]))
@@ -289,9 +442,123 @@ func (vm *VM) Run() error {
 		case code.OpIndex:
 			err := vm.executeIndexExpression()
 			if err != nil {
-				return nil
+				return err
+			}
+
+		case code.OpSetIndex:
+			err := vm.executeSetIndex()
+			if err != nil {
+				return err
+			}
+
+		case code.OpSlice:
+			err := vm.executeSliceExpression()
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetField:
+			nameIndex := utils.ReadUint16(instructions[instructionPointer+1:])
+			vm.curStackFrame().ip += 2
+
+			name := vm.constants[nameIndex].(*object.String).Value
+			if err := vm.executeGetField(name); err != nil {
+				return err
+			}
+
+		case code.OpSetField:
+			nameIndex := utils.ReadUint16(instructions[instructionPointer+1:])
+			vm.curStackFrame().ip += 2
+
+			name := vm.constants[nameIndex].(*object.String).Value
+			if err := vm.executeSetField(name); err != nil {
+				return err
+			}
+
+		case code.OpGetMethod:
+			nameIndex := utils.ReadUint16(instructions[instructionPointer+1:])
+			vm.curStackFrame().ip += 2
+
+			name := vm.constants[nameIndex].(*object.String).Value
+			if err := vm.executeGetMethod(name); err != nil {
+				return err
+			}
+
+		case code.OpConcat:
+			argIp := instructionPointer + 1
+			partsCount := int(utils.ReadUint16(instructions[argIp:]))
+
+			vm.curStackFrame().ip += 2
+
+			start := vm.stackPointer - partsCount
+			end := vm.stackPointer
+
+			str := vm.buildString(start, end)
+			vm.stackPointer -= partsCount
+
+			if err := vm.stackPush(str); err != nil {
+				return err
+			}
+
+		case code.OpIter:
+			iterable := vm.stackPop()
+
+			iterator, ok := object.NewIterator(iterable)
+			if !ok {
+				return ErrNotIterable(iterable.Type())
+			}
+
+			if err := vm.stackPush(iterator); err != nil {
+				return err
+			}
+
+		case code.OpIterNext:
+			argIp := instructionPointer + 1
+			loopEndPos := int(utils.ReadUint16(instructions[argIp:]))
+			bindingsCount := int(utils.ReadUint8(instructions[argIp+2:]))
+
+			op, err := code.LookupOperation(instructionByte)
+			if err != nil {
+				return err
+			}
+			vm.curStackFrame().ip += op.OperandWidths[0] + op.OperandWidths[1]
+
+			if err := vm.executeIterNext(loopEndPos, bindingsCount); err != nil {
+				return err
 			}
 
+		case code.OpLoop:
+			frame := vm.curStackFrame()
+			frame.loops = append(frame.loops, vm.stackPointer)
+
+		case code.OpEndLoop:
+			frame := vm.curStackFrame()
+			frame.loops = frame.loops[:len(frame.loops)-1]
+
+		case code.OpUnwindLoop:
+			frame := vm.curStackFrame()
+			vm.stackPointer = frame.loops[len(frame.loops)-1]
+
+		case code.OpTry:
+			argIp := instructionPointer + 1
+			handlerPosition := int(utils.ReadUint16(instructions[argIp:]))
+
+			vm.curStackFrame().ip += 2
+
+			frame := vm.curStackFrame()
+			frame.handlers = append(frame.handlers, handler{
+				position:     handlerPosition,
+				stackPointer: vm.stackPointer,
+				loopsCount:   len(frame.loops),
+			})
+
+		case code.OpEndTry:
+			frame := vm.curStackFrame()
+			frame.handlers = frame.handlers[:len(frame.handlers)-1]
+
+		case code.OpThrow:
+			return vm.throw(vm.stackPop())
+
 		case code.OpCall:
 			argsCountOperand := utils.ReadUint8(instructions[instructionPointer+1:])
 
@@ -305,7 +572,38 @@ func (vm *VM) Run() error {
 				return err
 			}
 
+		case code.OpCallMethod:
+			argsCount := int(utils.ReadUint8(instructions[instructionPointer+1:]))
+			vm.curStackFrame().ip += 1
+
+			if err := vm.callMethod(argsCount); err != nil {
+				return err
+			}
+
+		case code.OpSpawn:
+			argsCount := int(utils.ReadUint8(instructions[instructionPointer+1:]))
+			vm.curStackFrame().ip += 1
+
+			if err := vm.spawn(argsCount); err != nil {
+				return err
+			}
+
+		case code.OpGotoArgPassed:
+			argIp := instructionPointer + 1
+			defaultEndPos := int(utils.ReadUint16(instructions[argIp:]))
+			paramIndex := int(utils.ReadUint8(instructions[argIp+2:]))
+
+			vm.curStackFrame().ip += 3
+
+			if paramIndex < vm.curStackFrame().argsCount {
+				vm.curStackFrame().ip = defaultEndPos - 1
+			}
+
 		case code.OpReturnValue:
+			if vm.curStackFrame().closure.Fn.IsGenerator {
+				return &generatorSuspension{done: true}
+			}
+
 			returnValue := vm.stackPop()
 
 			frame := vm.popStackFrame()
@@ -316,6 +614,10 @@ func (vm *VM) Run() error {
 			}
 
 		case code.OpReturn:
+			if vm.curStackFrame().closure.Fn.IsGenerator {
+				return &generatorSuspension{done: true}
+			}
+
 			frame := vm.popStackFrame()
 			vm.stackPointer = frame.basePointer - 1
 
@@ -323,8 +625,64 @@ func (vm *VM) Run() error {
 				return err
 			}
 
+		case code.OpYield:
+			return &generatorSuspension{value: vm.stackPop()}
+
 		case code.OpPop:
 			vm.stackPop()
+
+		case code.OpDup:
+			if err := vm.stackPush(vm.StackTop()); err != nil {
+				return err
+			}
+
+		case code.OpSwap:
+			top := vm.stackPointer - 1
+			vm.stack[top], vm.stack[top-1] = vm.stack[top-1], vm.stack[top]
+
+		case code.OpMatchLiteral:
+			argIp := instructionPointer + 1
+			literalIndex := int(utils.ReadUint16(instructions[argIp:]))
+
+			vm.curStackFrame().ip += 2
+
+			matches := object.SamePatternKey(vm.StackTop(), vm.constants[literalIndex])
+			if err := vm.stackPush(nativeToObjectBoolean(matches)); err != nil {
+				return err
+			}
+
+		case code.OpMatchArray:
+			argIp := instructionPointer + 1
+			length := int(utils.ReadUint16(instructions[argIp:]))
+			hasRest := utils.ReadUint8(instructions[argIp+2:]) == 1
+
+			vm.curStackFrame().ip += 3
+
+			matches := false
+			if array, ok := vm.StackTop().(*object.Array); ok {
+				matches = len(array.Elements) == length || hasRest && len(array.Elements) > length
+			}
+
+			if err := vm.stackPush(nativeToObjectBoolean(matches)); err != nil {
+				return err
+			}
+
+		case code.OpMatchHashMap:
+			argIp := instructionPointer + 1
+			keysIndex := int(utils.ReadUint16(instructions[argIp:]))
+
+			vm.curStackFrame().ip += 2
+
+			keys := vm.constants[keysIndex].(*object.Array)
+
+			matches := false
+			if hashMap, ok := vm.StackTop().(*object.HashMap); ok {
+				matches = hasKeys(hashMap, keys.Elements)
+			}
+
+			if err := vm.stackPush(nativeToObjectBoolean(matches)); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -338,24 +696,53 @@ func (vm *VM) callFunc(argsCount int) error {
 	fn := vm.stack[fnStackPos]
 
 	switch fn := fn.(type) {
-	case *object.CompiledFunction:
-		if fn.ParamsCount != argsCount {
-			return ErrWrongNumberOfArguments(fn.ParamsCount, argsCount)
+	case *object.Closure:
+		if !fn.Fn.Arity.Accepts(argsCount) {
+			return ErrWrongNumberOfArguments(fn.Fn.Name, fn.Fn.Arity, argsCount)
 		}
 
+		if basePointer+fn.Fn.LocalsCount >= StackSize {
+			return fmt.Errorf("stack overflow")
+		}
+
+		vm.bindArguments(fn.Fn, basePointer, argsCount)
+		vm.clearLocals(fn.Fn, basePointer)
+
 		stackFrame := NewStackFrame(fn, basePointer)
+		stackFrame.argsCount = argsCount
+
+		if fn.Fn.IsGenerator {
+			return vm.pushGenerator(stackFrame)
+		}
+
 		vm.pushStackFrame(stackFrame)
 
-		vm.createStackVacuum(stackFrame.basePointer, fn.LocalsCount)
+		vm.createStackVacuum(stackFrame.basePointer, fn.Fn.LocalsCount)
+
+	case *object.StructType:
+		if !fn.Arity().Accepts(argsCount) {
+			return ErrWrongNumberOfArguments(fn.Name, fn.Arity(), argsCount)
+		}
+
+		instance := fn.New(vm.stack[basePointer:vm.stackPointer])
+
+		vm.stackPointer = basePointer - 1
+		vm.stackPush(instance)
 
 	case *object.BuiltInFunction:
-		if argsCount != fn.ParamsCount {
-			return ErrWrongNumberOfArguments(fn.ParamsCount, argsCount)
+		if !fn.Arity.Accepts(argsCount) {
+			return ErrWrongNumberOfArguments(fn.Name, fn.Arity, argsCount)
 		}
 
 		args := vm.stack[basePointer:vm.stackPointer]
 
 		result := fn.Fn(args...)
+		if err, ok := result.(*object.Error); ok {
+			return errorFromObject(err)
+		}
+
+		// drop the function and its arguments, so the result takes their place
+		vm.stackPointer = basePointer - 1
 
 		if result != nil {
 			vm.stackPush(result)
@@ -370,6 +757,195 @@ func (vm *VM) callFunc(argsCount int) error {
 	return nil
 }
 
+// spawn replaces the function and its arguments with the task running the call in a VM of its own.
+// The VM shares the constants, but works on deep copies of the globals, of the arguments and of the values captured
+// by the closure, so that the task and the code spawning it don't see each other's assignments
+func (vm *VM) spawn(argsCount int) error {
+	fnStackPos := vm.stackPointer - argsCount - 1
+
+	fn := vm.stack[fnStackPos]
+	args := append([]object.Object{}, vm.stack[fnStackPos+1:vm.stackPointer]...)
+
+	name := ""
+	if closure, ok := fn.(*object.Closure); ok {
+		name = closure.Fn.Name
+	}
+
+	copier := object.NewCopier()
+
+	fn = copier.Copy(fn)
+	for i, arg := range args {
+		args[i] = copier.Copy(arg)
+	}
+
+	globals := make([]object.Object, len(vm.globals))
+	for i, global := range vm.globals {
+		if global != nil {
+			globals[i] = copier.Copy(global)
+		}
+	}
+
+	// the main function of the task only calls the spawned function, leaving the result on the stack
+	taskVm := NewVmWithGlobalStore(&compiler.Bytecode{
+		Instructions: code.MakeInstruction(code.OpCall, argsCount),
+		Constants:    vm.constants,
+	}, globals)
+
+	taskVm.stack[0] = fn
+	copy(taskVm.stack[1:], args)
+	taskVm.stackPointer = argsCount + 1
+
+	task := object.NewTask(name, func() (object.Object, *object.Error) {
+		if err := taskVm.Run(); err != nil {
+			if uncaught, ok := err.(*UncaughtException); ok {
+				return nil, objectErrorFrom(uncaught.Value, uncaught.StackTrace())
+			}
+			return nil, &object.Error{Message: err.Error()}
+		}
+
+		return taskVm.StackTop(), nil
+	})
+
+	vm.stackPointer = fnStackPos
+
+	return vm.stackPush(task)
+}
+
+// generatorState is the generator call set aside between resumptions
+type generatorState struct {
+	frame *StackFrame
+	// the part of the stack owned by the frame, starting at its base pointer
+	stack []object.Object
+}
+
+// pushGenerator replaces the generator function and its arguments with the generator,
+// the frame of the call doesn't run until the generator is resumed
+func (vm *VM) pushGenerator(frame *StackFrame) error {
+	locals := vm.stack[frame.basePointer : frame.basePointer+frame.closure.Fn.LocalsCount]
+
+	state := &generatorState{
+		frame: frame,
+		stack: append([]object.Object{}, locals...),
+	}
+
+	generator := object.NewGenerator(frame.closure.Fn.Name, func() (object.Object, bool, *object.Error) {
+		return vm.resumeGenerator(state)
+	})
+
+	vm.stackPointer = frame.basePointer - 1
+
+	return vm.stackPush(generator)
+}
+
+// resumeGenerator puts the frame of the generator back on top of the stack and runs it up to the next yield.
+// Exceptions which aren't caught inside of the generator are reported to the code resuming it
+func (vm *VM) resumeGenerator(state *generatorState) (object.Object, bool, *object.Error) {
+	base := vm.stackPointer
+	if base+len(state.stack) >= StackSize {
+		return nil, false, &object.Error{Message: "stack overflow"}
+	}
+
+	copy(vm.stack[base:], state.stack)
+	vm.stackPointer = base + len(state.stack)
+
+	state.frame.rebase(base)
+	vm.pushStackFrame(state.frame)
+
+	generatorFrame := vm.stackFramesIndex - 1
+
+	for {
+		err := vm.execute()
+
+		if suspension, ok := err.(*generatorSuspension); ok {
+			vm.popStackFrame()
+
+			state.stack = append(state.stack[:0], vm.stack[base:vm.stackPointer]...)
+			vm.stackPointer = base
+
+			return suspension.value, !suspension.done, nil
+		}
+
+		exception, stackTrace := vm.exceptionFrom(err)
+		if !vm.unwind(exception, generatorFrame) {
+			vm.stackFramesIndex = generatorFrame
+			vm.stackPointer = base
+
+			return nil, false, objectErrorFrom(exception, stackTrace)
+		}
+	}
+}
+
+// callMethod calls the method pushed by OpGetMethod, the receiver right after it becomes the `self` argument,
+// or is dropped if the method doesn't take one
+func (vm *VM) callMethod(argsCount int) error {
+	receiverPos := vm.stackPointer - argsCount - 1
+
+	if object.TakesSelf(vm.stack[receiverPos-1]) {
+		return vm.callFunc(argsCount + 1)
+	}
+
+	copy(vm.stack[receiverPos:], vm.stack[receiverPos+1:vm.stackPointer])
+	vm.stackPointer--
+
+	return vm.callFunc(argsCount)
+}
+
+func (vm *VM) pushClosure(constantIndex int, freeCount int) error {
+	constant := vm.constants[constantIndex]
+
+	fn, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return ErrNotAFunctionConstant(constant)
+	}
+
+	// cells of the captured variables were pushed right before OpClosure, in the order of the free symbols,
+	// the closure of the function itself is pushed as a value, it's never assigned to, so it gets a cell of its own
+	free := make([]*object.Cell, freeCount)
+	for i := range freeCount {
+		captured := vm.stack[vm.stackPointer-freeCount+i]
+
+		cell, ok := captured.(*object.Cell)
+		if !ok {
+			cell = &object.Cell{Value: captured}
+		}
+		free[i] = cell
+	}
+	vm.stackPointer -= freeCount
+
+	return vm.stackPush(&object.Closure{Fn: fn, Free: free})
+}
+
+// bindArguments lays the arguments out as the parameter locals of the function:
+// parameters without arguments are nulled until their default values are assigned,
+// arguments past the parameters are collected into the rest parameter
+func (vm *VM) bindArguments(fn *object.CompiledFunction, basePointer, argsCount int) {
+	for i := argsCount; i < fn.ParamsCount; i++ {
+		vm.stack[basePointer+i] = Null
+	}
+
+	if fn.Arity.Max != object.VariadicArity {
+		return
+	}
+
+	rest := []object.Object{}
+	if argsCount > fn.ParamsCount {
+		rest = append(rest, vm.stack[basePointer+fn.ParamsCount:basePointer+argsCount]...)
+	}
+
+	vm.stack[basePointer+fn.ParamsCount] = &object.Array{Elements: rest}
+}
+
+// clearLocals empties the locals following the parameters, the stack may still hold cells of the previous calls there,
+// assignments to the locals must not write through them
+func (vm *VM) clearLocals(fn *object.CompiledFunction, basePointer int) {
+	paramsCount := fn.ParamsCount
+	if fn.Arity.Max == object.VariadicArity {
+		paramsCount++
+	}
+
+	clear(vm.stack[basePointer+paramsCount : basePointer+fn.LocalsCount])
+}
+
 func (vm *VM) createStackVacuum(sfBasePointer int, vacuumInstructions int) {
 	vm.stackPointer = sfBasePointer + vacuumInstructions
 }
@@ -394,10 +970,14 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	rightType := right.Type()
 	leftType := left.Type()
 
-	if leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ {
+	if object.IsIntegral(left) && object.IsIntegral(right) {
 		return vm.executeBinaryIntOperation(op, left, right)
 	}
 
+	if isNumeric(left) && isNumeric(right) {
+		return vm.executeBinaryFloatOperation(op, left, right)
+	}
+
 	if leftType == object.STRING_OBJ && rightType == object.STRING_OBJ {
 		return vm.executeBinaryStringOperation(op, left, right)
 	}
@@ -412,20 +992,19 @@ func (vm *VM) executeComparisonOperation(op code.Opcode) error {
 	rightType := right.Type()
 	leftType := left.Type()
 
-	if leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ {
-
+	if object.IsIntegral(left) && object.IsIntegral(right) {
 		return vm.executeIntegerComparison(op, left, right)
 	}
 
+	if isNumeric(left) && isNumeric(right) {
+		return vm.executeFloatComparison(op, toFloat(left), toFloat(right))
+	}
+
 	switch op {
 	case code.OpEqual:
 		return vm.stackPush(nativeToObjectBoolean(right == left))
 	case code.OpNotEqual:
 		return vm.stackPush(nativeToObjectBoolean(right != left))
-	case code.OpAnd:
-		return vm.stackPush(nativeToObjectBoolean(left.(*object.Boolean).Value && right.(*object.Boolean).Value))
-	case code.OpOr:
-		return vm.stackPush(nativeToObjectBoolean(left.(*object.Boolean).Value || right.(*object.Boolean).Value))
 	default:
 		return fmt.Errorf("unsupported type for binary operation: %s %s", leftType, rightType)
 	}
@@ -433,9 +1012,28 @@ func (vm *VM) executeComparisonOperation(op code.Opcode) error {
 }
 
 func (vm *VM) executeIntegerComparison(opcode code.Opcode, left, right object.Object) error {
-	rightValue := right.(*object.Integer).Value
-	leftValue := left.(*object.Integer).Value
+	comparison := object.CompareIntegers(left, right)
 
+	switch opcode {
+	case code.OpEqual:
+		return vm.stackPush(nativeToObjectBoolean(comparison == 0))
+	case code.OpNotEqual:
+		return vm.stackPush(nativeToObjectBoolean(comparison != 0))
+	case code.OpGreaterThan:
+		return vm.stackPush(nativeToObjectBoolean(comparison > 0))
+	case code.OpGreaterThanOrEqual:
+		return vm.stackPush(nativeToObjectBoolean(comparison >= 0))
+	case code.OpLessThan:
+		return vm.stackPush(nativeToObjectBoolean(comparison < 0))
+	case code.OpLessThanOrEqual:
+		return vm.stackPush(nativeToObjectBoolean(comparison <= 0))
+	default:
+		return fmt.Errorf("unknown integer operator %d", opcode)
+	}
+
+}
+
+func (vm *VM) executeFloatComparison(opcode code.Opcode, leftValue, rightValue float64) error {
 	switch opcode {
 	case code.OpEqual:
 		return vm.stackPush(nativeToObjectBoolean(leftValue == rightValue))
@@ -443,17 +1041,62 @@ func (vm *VM) executeIntegerComparison(opcode code.Opcode, left, right object.Ob
 		return vm.stackPush(nativeToObjectBoolean(leftValue != rightValue))
 	case code.OpGreaterThan:
 		return vm.stackPush(nativeToObjectBoolean(leftValue > rightValue))
+	case code.OpGreaterThanOrEqual:
+		return vm.stackPush(nativeToObjectBoolean(leftValue >= rightValue))
+	case code.OpLessThan:
+		return vm.stackPush(nativeToObjectBoolean(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return vm.stackPush(nativeToObjectBoolean(leftValue <= rightValue))
 	default:
-		return fmt.Errorf("unknown integer operator %d", opcode)
+		return fmt.Errorf("unknown float operator %d", opcode)
 	}
-
 }
 
 func (vm *VM) executeBinaryIntOperation(op code.Opcode, left, right object.Object) error {
-	leftValue := left.(*object.Integer).Value
-	rightValue := right.(*object.Integer).Value
+	var result object.Object
+	var err error
+
+	switch op {
+	case code.OpAdd:
+		result = object.AddIntegers(left, right)
+	case code.OpSub:
+		result = object.SubIntegers(left, right)
+	case code.OpDiv:
+		result, err = object.DivIntegers(left, right)
+	case code.OpMul:
+		result = object.MulIntegers(left, right)
+	case code.OpMod:
+		result, err = object.ModIntegers(left, right)
+	case code.OpPow:
+		result, err = object.PowIntegers(left, right)
+	case code.OpBitAnd:
+		result = object.BitAndIntegers(left, right)
+	case code.OpBitOr:
+		result = object.BitOrIntegers(left, right)
+	case code.OpBitXor:
+		result = object.BitXorIntegers(left, right)
+	case code.OpShiftLeft:
+		result, err = object.ShiftLeftIntegers(left, right)
+	case code.OpShiftRight:
+		result, err = object.ShiftRightIntegers(left, right)
+	default:
+		return fmt.Errorf("unknown integer operator %d", op)
+	}
+
+	if err != nil {
+		return err
+	}
 
-	var result int64
+	vm.stackPush(result)
+
+	return nil
+}
+
+func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left, right object.Object) error {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	var result float64
 
 	switch op {
 	case code.OpAdd:
@@ -464,13 +1107,15 @@ func (vm *VM) executeBinaryIntOperation(op code.Opcode, left, right object.Objec
 		result = leftValue / rightValue
 	case code.OpMul:
 		result = leftValue * rightValue
+	case code.OpMod:
+		result = math.Mod(leftValue, rightValue)
+	case code.OpPow:
+		result = math.Pow(leftValue, rightValue)
 	default:
-		return fmt.Errorf("unknown integer operator %d", op)
+		return ErrUnknownOperator(left.Type(), op, right.Type())
 	}
 
-	vm.stackPush(&object.Integer{Value: result})
-
-	return nil
+	return vm.stackPush(&object.Float{Value: result})
 }
 
 func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
@@ -483,7 +1128,7 @@ func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Ob
 	case code.OpAdd:
 		result = leftValue + rightValue
 	default:
-		return fmt.Errorf("unknown integer operator %d", op)
+		return ErrUnknownOperator(left.Type(), op, right.Type())
 	}
 
 	vm.stackPush(&object.String{Value: result})
@@ -509,14 +1154,42 @@ func (vm *VM) executeBangOperation() error {
 func (vm *VM) executeMinusOperation() error {
 	operand := vm.stackPop()
 
-	if operand.Type() != object.INTEGER_OBJ {
+	switch operand := operand.(type) {
+	case *object.Integer, *object.BigInteger:
+		return vm.stackPush(object.NegateInteger(operand))
+	case *object.Float:
+		return vm.stackPush(&object.Float{Value: -operand.Value})
+	default:
 		return fmt.Errorf("unsupported type for minus operator: %s", operand.Type())
 	}
+}
 
-	currentValue := operand.(*object.Integer).Value
-	oppositeInt := &object.Integer{Value: -currentValue}
+func (vm *VM) executeBitNotOperation() error {
+	operand := vm.stackPop()
+
+	if !object.IsIntegral(operand) {
+		return fmt.Errorf("unsupported type for bitwise not operator: %s", operand.Type())
+	}
 
-	return vm.stackPush(oppositeInt)
+	return vm.stackPush(object.BitNotInteger(operand))
+}
+
+func isNumeric(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInteger, *object.Float:
+		return true
+	default:
+		return false
+	}
+}
+
+// toFloat widens a numeric object to float64, so ints and floats can be mixed in one operation
+func toFloat(obj object.Object) float64 {
+	if float, ok := obj.(*object.Float); ok {
+		return float.Value
+	}
+
+	return object.IntegerToFloat(obj)
 }
 
 func (vm *VM) executeIndexExpression() error {
@@ -548,19 +1221,25 @@ func (vm *VM) executeIndexExpression() error {
 		}
 		return vm.stackPush(obj)
 
+	case left.Type() == object.EXCEPTION_OBJ:
+		field, ok := left.(*object.Exception).Field(index)
+		if !ok {
+			return vm.stackPush(Null)
+		}
+		return vm.stackPush(field)
+
 	default:
 		return fmt.Errorf("index operator not supported: %s", left.Type())
 	}
 }
 
 func (vm *VM) executeArrayIndex(array *object.Array, index *object.Integer) (object.Object, error) {
-	max := int64(len(array.Elements) - 1)
-
-	if index.Value < 0 || index.Value > max {
+	position, ok := array.Position(index.Value)
+	if !ok {
 		return Null, nil
 	}
 
-	return array.Elements[index.Value], nil
+	return array.Elements[position], nil
 }
 
 func (vm *VM) executeHashmapIndex(hashMap *object.HashMap, key object.Hashable) (object.Object, error) {
@@ -572,6 +1251,217 @@ func (vm *VM) executeHashmapIndex(hashMap *object.HashMap, key object.Hashable)
 	return pair.Value, nil
 }
 
+func hasKeys(hashMap *object.HashMap, keys []object.Object) bool {
+	for _, key := range keys {
+		if _, ok := hashMap.Pairs[key.(object.Hashable).HashKey()]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (vm *VM) executeSliceExpression() error {
+	end := vm.stackPop()
+	start := vm.stackPop()
+	left := vm.stackPop()
+
+	switch left := left.(type) {
+	case *object.Array:
+		startIdx, endIdx, err := sliceBounds(start, end, int64(len(left.Elements)))
+		if err != nil {
+			return err
+		}
+
+		elements := make([]object.Object, endIdx-startIdx)
+		copy(elements, left.Elements[startIdx:endIdx])
+
+		return vm.stackPush(&object.Array{Elements: elements})
+
+	case *object.String:
+		chars := []rune(left.Value)
+
+		startIdx, endIdx, err := sliceBounds(start, end, int64(len(chars)))
+		if err != nil {
+			return err
+		}
+
+		return vm.stackPush(&object.String{Value: string(chars[startIdx:endIdx])})
+
+	default:
+		return ErrSliceNotSupported(left.Type())
+	}
+}
+
+// sliceBounds resolves the bounds of a slice of the collection of the given length,
+// the end never precedes the start
+func sliceBounds(start, end object.Object, length int64) (int64, int64, error) {
+	startIdx, err := sliceBound(start, 0, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	endIdx, err := sliceBound(end, length, length)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return min(startIdx, endIdx), endIdx, nil
+}
+
+// sliceBound clamps the bound of a slice into [0, length], negative bound counts from the end,
+// null bound falls back to fallback
+func sliceBound(bound object.Object, fallback, length int64) (int64, error) {
+	if bound == Null {
+		return fallback, nil
+	}
+
+	integer, ok := bound.(*object.Integer)
+	if !ok {
+		return 0, ErrSliceBoundNotInteger(bound.Type())
+	}
+
+	index := integer.Value
+	if index < 0 {
+		index += length
+	}
+
+	return min(max(index, 0), length), nil
+}
+
+func (vm *VM) executeSetIndex() error {
+	value := vm.stackPop()
+	index := vm.stackPop()
+	left := vm.stackPop()
+
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return fmt.Errorf("array index must be INTEGER, got %s", index.Type())
+		}
+
+		position, ok := left.Position(idx.Value)
+		if !ok {
+			return ErrIndexOutOfBounds(idx.Value, len(left.Elements))
+		}
+
+		left.Elements[position] = value
+
+		return nil
+
+	case *object.HashMap:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return fmt.Errorf("unusable as hashmap key: %s", index.Type())
+		}
+
+		left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: value}
+
+		return nil
+
+	default:
+		return fmt.Errorf("index assignment not supported: %s", left.Type())
+	}
+}
+
+func (vm *VM) executeGetField(name string) error {
+	value, err := vm.member(vm.stackPop(), name)
+	if err != nil {
+		return err
+	}
+
+	return vm.stackPush(value)
+}
+
+func (vm *VM) executeGetMethod(name string) error {
+	receiver := vm.stackPop()
+
+	method, err := vm.member(receiver, name)
+	if err != nil {
+		return err
+	}
+
+	if err := vm.stackPush(method); err != nil {
+		return err
+	}
+
+	return vm.stackPush(receiver)
+}
+
+// member looks up the field of a record, or the key of a hashmap or an exception,
+// `person.name` is the same as `person["name"]`
+func (vm *VM) member(left object.Object, name string) (object.Object, error) {
+	switch left := left.(type) {
+	case *object.Struct:
+		value, ok := left.Field(name)
+		if !ok {
+			return nil, ErrNoSuchField(left.StructType.Name, name)
+		}
+		return value, nil
+
+	case *object.HashMap:
+		return vm.executeHashmapIndex(left, &object.String{Value: name})
+
+	case *object.Exception:
+		field, ok := left.Field(&object.String{Value: name})
+		if !ok {
+			return Null, nil
+		}
+		return field, nil
+
+	default:
+		return nil, ErrMemberAccessNotSupported(left.Type())
+	}
+}
+
+func (vm *VM) executeSetField(name string) error {
+	value := vm.stackPop()
+	left := vm.stackPop()
+
+	switch left := left.(type) {
+	case *object.Struct:
+		if !left.SetField(name, value) {
+			return ErrNoSuchField(left.StructType.Name, name)
+		}
+		return nil
+
+	case *object.HashMap:
+		key := &object.String{Value: name}
+		left.Pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+		return nil
+
+	default:
+		return ErrMemberAccessNotSupported(left.Type())
+	}
+}
+
+func (vm *VM) executeIterNext(loopEndPos int, bindingsCount int) error {
+	iterator, ok := vm.StackTop().(object.Iterator)
+	if !ok {
+		return ErrNotIterable(vm.StackTop().Type())
+	}
+
+	key, value, ok := iterator.Next()
+	if !ok {
+		if fallible, isFallible := iterator.(object.FallibleIterator); isFallible && fallible.Err() != nil {
+			return errorFromObject(fallible.Err())
+		}
+
+		vm.curStackFrame().ip = loopEndPos - 1
+		return nil
+	}
+
+	if bindingsCount == 1 {
+		return vm.stackPush(object.SingleBinding(iterator, key, value))
+	}
+
+	if err := vm.stackPush(key); err != nil {
+		return err
+	}
+
+	return vm.stackPush(value)
+}
+
 func (vm *VM) StackTop() object.Object {
 	if vm.stackPointer == 0 {
 		return nil
@@ -624,6 +1514,17 @@ func (vm *VM) buildArray(startStackPointer, endStackPointer int) object.Object {
 	return &object.Array{Elements: elements}
 }
 
+// buildString joins the stack elements into a string, non-string elements are joined by their Inspect representation
+func (vm *VM) buildString(startStackPointer, endStackPointer int) object.Object {
+	var out strings.Builder
+
+	for i := startStackPointer; i < endStackPointer; i++ {
+		out.WriteString(vm.stack[i].Inspect())
+	}
+
+	return &object.String{Value: out.String()}
+}
+
 func (vm *VM) buildHashmap(startStackPointer, endStackPointer int) (object.Object, error) {
 	hashmap := &object.HashMap{
 		Pairs: make(map[object.HashKey]object.HashPair),
@@ -648,6 +1549,65 @@ func (vm *VM) curStackFrame() *StackFrame {
 	return vm.stackFrames[vm.stackFramesIndex-1]
 }
 
+// exceptionFrom turns the error which stopped the execution into the thrown value along with its stack trace,
+// runtime errors become exceptions carrying the stack trace of the place they happened at
+func (vm *VM) exceptionFrom(err error) (object.Object, []string) {
+	if thrown, ok := err.(*thrownValue); ok {
+		return thrown.value, thrown.stackTrace
+	}
+
+	stackTrace := vm.stackTrace()
+
+	return &object.Exception{Message: err.Error(), StackTrace: stackTrace}, stackTrace
+}
+
+// throw stops the execution with the thrown value, exceptions keep the stack trace of the place they happened at,
+// other values get the one of the place they're thrown at
+func (vm *VM) throw(value object.Object) error {
+	if exception, ok := value.(*object.Exception); ok {
+		return &thrownValue{value: exception, stackTrace: exception.StackTrace}
+	}
+
+	return &thrownValue{value: value, stackTrace: vm.stackTrace()}
+}
+
+// unwind drops stack frames until the one with an exception handler, and resumes the execution at the handler
+// with the exception pushed on the stack. Frames below the bottom frame aren't dropped.
+// It tells whether the exception is caught
+func (vm *VM) unwind(exception object.Object, bottomFrame int) bool {
+	for {
+		frame := vm.curStackFrame()
+
+		if len(frame.handlers) > 0 {
+			handler := frame.handlers[len(frame.handlers)-1]
+			frame.handlers = frame.handlers[:len(frame.handlers)-1]
+
+			vm.stackPointer = handler.stackPointer
+			frame.loops = frame.loops[:handler.loopsCount]
+			frame.ip = handler.position - 1
+
+			return vm.stackPush(exception) == nil
+		}
+
+		isBottomFrame := vm.stackFramesIndex-1 == bottomFrame
+		if isBottomFrame {
+			return false
+		}
+
+		vm.popStackFrame()
+	}
+}
+
+func (vm *VM) stackTrace() []string {
+	trace := []string{}
+
+	for i := vm.stackFramesIndex - 1; i > 0; i-- {
+		trace = append(trace, object.FrameName(vm.stackFrames[i].closure.Fn.Name))
+	}
+
+	return append(trace, object.MainFrameName)
+}
+
 func (vm *VM) pushStackFrame(frame *StackFrame) {
 	vm.stackFrames[vm.stackFramesIndex] = frame
 	vm.stackFramesIndex++
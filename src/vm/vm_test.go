@@ -2,11 +2,15 @@ package vm
 
 import (
 	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/vdchnsk/qrk/src/ast"
 	"github.com/vdchnsk/qrk/src/compiler"
 	"github.com/vdchnsk/qrk/src/lexer"
+	"github.com/vdchnsk/qrk/src/module"
 	"github.com/vdchnsk/qrk/src/object"
 	"github.com/vdchnsk/qrk/src/parser"
 )
@@ -18,7 +22,7 @@ func parse(input string) *ast.Program {
 	return p.ParseProgram()
 }
 
-func testObject[T bool | int64 | string](expected T, actual object.Object) error {
+func testObject[T bool | int64 | float64 | string](expected T, actual object.Object) error {
 	var result T
 
 	switch v := actual.(type) {
@@ -28,6 +32,12 @@ func testObject[T bool | int64 | string](expected T, actual object.Object) error
 		}
 		result = any(v.Value).(T)
 
+	case *object.Float:
+		if _, ok := any(expected).(float64); !ok {
+			return fmt.Errorf("expected float64, got %T", expected)
+		}
+		result = any(v.Value).(T)
+
 	case *object.Boolean:
 		if _, ok := any(expected).(bool); !ok {
 			return fmt.Errorf("expected bool, got %T", expected)
@@ -97,9 +107,24 @@ func testExpectedObject(t *testing.T, expectedObj interface{}, actualObj object.
 	case int:
 		err = testObject(int64(expectedObj), actualObj)
 
+	case float64:
+		err = testObject(expectedObj, actualObj)
+
 	case bool:
 		err = testObject(expectedObj, actualObj)
 
+	case *big.Int:
+		actualBigInt, ok := actualObj.(*object.BigInteger)
+		if !ok {
+			t.Errorf("object is not BigInteger. got=%T (%+v)", actualObj, actualObj)
+			return
+		}
+
+		if actualBigInt.Value.Cmp(expectedObj) != 0 {
+			t.Errorf("object has wrong value. got=%s, expected=%s", actualBigInt.Value, expectedObj)
+			return
+		}
+
 	case string:
 		err = testObject(expectedObj, actualObj)
 
@@ -183,6 +208,152 @@ func TestIntegerArithmetic(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestExtendedOperators(t *testing.T) {
+	twoToThe100, _ := new(big.Int).SetString("1267650600228229401496703205376", 10)
+
+	tests := []vmTestCase{
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7.5 % 2", 1.5},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"2 ** -1", 0.5},
+		{"2.0 ** 3", 8.0},
+		{"2 ** 100", twoToThe100},
+		{"1 << 100", twoToThe100},
+		{"1 << 100 >> 100", 1},
+		{"-16 >> 2", -4},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 | 2 ^ 3 & 4", 3},
+		{"1 + 2 << 1", 6},
+		{"2 * 3 % 4", 2},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 1", true},
+		{"1 >= 2", false},
+		{"1.5 <= 2", true},
+		{"2 >= 2.5", false},
+		{"1 < 2", true},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestOperatorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / 0", "division by zero"},
+		{"1 % 0", "division by zero"},
+		{"1 << -1", "negative shift count"},
+		{"1 << 100000", "shift count is greater than 65536"},
+		{"10 ** 100000", "result of exponentiation is larger than 65536 bits"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{`"a" - "b"`, "unknown operator: STRING - STRING"},
+		{`~"a"`, "unsupported type for bitwise not operator: STRING"},
+		{`1 <= "a"`, "unsupported type for binary operation: INTEGER STRING"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := compiler.New()
+		if err := compiler.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(compiler.Bytecode())
+
+		err := vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error for %q, got none", tt.input)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error. expected=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+// Operands have to be evaluated left to right, even for operators like `<`
+func TestComparisonOperandsEvaluationOrder(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+				let calls = 0;
+				let first = fn() { calls = calls * 10 + 1; 1 };
+				let second = fn() { calls = calls * 10 + 2; 2 };
+				first() < second();
+				calls
+			`,
+			expected: 12,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	maxInt64PlusOne, _ := new(big.Int).SetString("9223372036854775808", 10)
+	minInt64MinusOne, _ := new(big.Int).SetString("-9223372036854775809", 10)
+	factorial25, _ := new(big.Int).SetString("15511210043330985984000000", 10)
+
+	tests := []vmTestCase{
+		{"9223372036854775807 + 1", maxInt64PlusOne},
+		{"-9223372036854775808 - 1", minInt64MinusOne},
+		{"-9223372036854775808 / -1", maxInt64PlusOne},
+		{"-(-9223372036854775808)", maxInt64PlusOne},
+		{"9223372036854775808", maxInt64PlusOne},
+		{"9223372036854775807 + 1 - 1", 9223372036854775807},
+		{"-9223372036854775808", -9223372036854775808},
+		{"99999999999999999999 / 99999999999999999999", 1},
+		{"9223372036854775808 > 9223372036854775807", true},
+		{"9223372036854775808 == 9223372036854775807 + 1", true},
+		{"9223372036854775808 * 0.5", 4611686018427387904.0},
+		{
+			input: `
+				fn factorial(n) {
+					if n < 2 {
+						return 1;
+					}
+					n * factorial(n - 1);
+				}
+
+				factorial(25);
+			`,
+			expected: factorial25,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5", 1.5},
+		{"1e3", 1000.0},
+		{"2.5e-1", 0.25},
+		{"1.5 + 2.25", 3.75},
+		{"1.5 + 2", 3.5},
+		{"2 - 0.5", 1.5},
+		{"3 / 2.0", 1.5},
+		{"0.5 * 4", 2.0},
+		{"-1.5", -1.5},
+		{"-1.5 + 1", -0.5},
+		{"1.5 > 1", true},
+		{"1 < 1.5", true},
+		{"2 == 2.0", true},
+		{"2.0 != 2", false},
+	}
+
+	runVmTests(t, tests)
+}
+
 func TestStringOperations(t *testing.T) {
 	tests := []vmTestCase{
 		{`"foo"`, "foo"},
@@ -192,6 +363,32 @@ func TestStringOperations(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []vmTestCase{
+		{`"${1}"`, "1"},
+		{`"a ${1 + 2} b"`, "a 3 b"},
+		{`let name = "qrk"; "hello ${name}!"`, "hello qrk!"},
+		{`"${1.5} ${true} ${[1, 2]}"`, "1.5 true [1, 2]"},
+		{`"${ {"k": "v"}["k"] }"`, "v"},
+		{`let x = 2; "outer ${"inner ${x * 2}"}"`, "outer inner 4"},
+		{`let f = fn(n) { "n=${n}" }; f(7)`, "n=7"},
+		{`"\${escaped}"`, "${escaped}"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestComments(t *testing.T) {
+	tests := []vmTestCase{
+		{"// comment\n1 // trailing", 1},
+		{"/* block */ 1 + /* inline */ 2", 3},
+		{"let x = 10; /* a /* nested */ b */ x / 2", 5},
+		{`"// not a comment"`, "// not a comment"},
+	}
+
+	runVmTests(t, tests)
+}
+
 func TestBooleanExpressions(t *testing.T) {
 	tests := []vmTestCase{
 		{"true;", true},
@@ -218,6 +415,40 @@ func TestBooleanExpressions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestLogicalOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"1 && 2", 2},
+		{`"a" || "b"`, "a"},
+		{"false || 5", 5},
+		{"false && 5", false},
+		{"!(if false { 1 } && 5)", true},
+		{"true || false && false", true},
+		{"(true || false) && false", false},
+		{"1 < 2 && 2 < 3", true},
+		{
+			input: `
+				let calls = 0;
+				let touch = fn() { calls = calls + 1; true };
+				false && touch();
+				true || touch();
+				true && touch();
+				false || touch();
+				calls
+			`,
+			expected: 2,
+		},
+		{
+			input: `
+				let f = fn(x) { x || "default" };
+				f(false) + " " + f("given")
+			`,
+			expected: "default given",
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
 func TestConditionals(t *testing.T) {
 	tests := []vmTestCase{
 		{"if true { 10 }", 10},
@@ -230,6 +461,8 @@ func TestConditionals(t *testing.T) {
 		{"if 1 > 2 { 10 }", Null},
 		{"if false { 10 }", Null},
 		{"if (if false { 10 }) { 10 } else { 20 }", 20},
+		{"let a = 1; if true { a = 2; }", Null},
+		{"if true { }", Null},
 	}
 
 	runVmTests(t, tests)
@@ -279,7 +512,9 @@ func TestIndexExpression(t *testing.T) {
 		{"[[1, 1, 1]][0][0]", 1},
 		{"[][0]", Null},
 		{"[1, 2, 3][99]", Null},
-		{"[1][-1]", Null},
+		{"[1][-1]", 1},
+		{"[1, 2, 3][-3]", 1},
+		{"[1, 2, 3][-4]", Null},
 
 		{"{1: 1, 2: 2}[1]", 1},
 		{"{1: 1}[2]", Null},
@@ -289,6 +524,179 @@ func TestIndexExpression(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestIndexAssignStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let a = [1, 2, 3]; a[0] = 10; a", []int{10, 2, 3}},
+		{"let a = [1, 2, 3]; let b = a; b[2] = 30; a", []int{1, 2, 30}},
+		{"let grid = [[0, 0], [0, 0]]; grid[1][0] = 5; grid[1][0]", 5},
+		{`let m = {"age": 10}; m["age"] = m["age"] + 1; m["age"]`, 11},
+		{`let m = {}; m[1] = "one"; m[true] = "yes"; m[1] + " " + m[true]`, "one yes"},
+		{"let a = [0]; let inc = fn(arr) { arr[0] = arr[0] + 1; }; inc(a); inc(a); a[0]", 2},
+		{"let a = [0, 0]; for i in range(0, 2, 1) { a[i] = i + 1; } a", []int{1, 2}},
+		{"let a = [1, 2, 3]; a[-1] = 7; a", []int{1, 2, 7}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIndexAssignErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1]; a[1] = 2;", "index out of bounds: 1, length is 1"},
+		{"let a = [1]; a[-2] = 2;", "index out of bounds: -2, length is 1"},
+		{`let a = [1]; a["x"] = 2;`, "array index must be INTEGER, got STRING"},
+		{"let m = {}; m[[1]] = 2;", "unusable as hashmap key: ARRAY"},
+		{`let s = "ab"; s[0] = "c";`, "index assignment not supported: STRING"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := compiler.New()
+		if err := compiler.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(compiler.Bytecode())
+
+		err := vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error for %q, got none", tt.input)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error. expected=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestLetDestructuringStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let [a, b] = [1, 2]; a + b", 3},
+		{"let [head, ...tail] = [1, 2, 3]; tail", []int{2, 3}},
+		{"let [a, b, ...rest] = [1, 2]; rest", []int{}},
+		{"let [...all] = [1, 2]; all", []int{1, 2}},
+		{"let [a, b] = [1]; b", Null},
+		{"let [x, [y, z]] = [1, [2, 3]]; x + y + z", 6},
+		{`let {name, age} = {"name": "Ann", "age": 30}; name`, "Ann"},
+		{`let {missing} = {}; missing`, Null},
+		{`let [first, {age}] = [1, {"age": 30}]; first + age`, 31},
+		{"let a = [1, 2]; let [x, ...rest] = a; rest[0] = 5; a", []int{1, 2}},
+		{"let swap = fn(pair) { let [a, b] = pair; [b, a] }; swap([1, 2])", []int{2, 1}},
+		{"let x = 1; let [x, y] = [x + 1, x + 2]; x + y", 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3, 4, 5][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4, 5][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4, 5][-2:]", []int{4, 5}},
+		{"[1, 2, 3, 4, 5][:-3]", []int{1, 2}},
+		{"[1, 2, 3][:]", []int{1, 2, 3}},
+		{"[1, 2, 3][2:1]", []int{}},
+		{"[1, 2, 3][-10:10]", []int{1, 2, 3}},
+		{"let a = [1, 2]; let b = a[:]; b[0] = 5; a", []int{1, 2}},
+		{`"hello world"[0:5]`, "hello"},
+		{`"hello world"[-5:]`, "world"},
+		{`"héllo"[1:2]`, "é"},
+		{`"abc"[5:]`, ""},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLetDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a] = 1;", "index operator not supported: INTEGER"},
+		{`let [...rest] = {};`, "slice operator not supported: HASH_MAP"},
+		{`let {name} = "ann";`, "index operator not supported: STRING"},
+		{"1[0:1]", "slice operator not supported: INTEGER"},
+		{`[1, 2]["a":]`, "slice bound must be an integer, got STRING"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := compiler.New()
+		if err := compiler.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(compiler.Bytecode())
+
+		err := vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error for %q, got none", tt.input)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error. expected=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	describe := `
+		let describe = fn(v) {
+			match v {
+				0 => "zero",
+				-1 => "minus one",
+				"x" => "ex",
+				true => "yes",
+				2.5 => "float",
+				[] => "empty",
+				[a, b] if a == b => "same ${a}",
+				[a, b] => "pair ${a} ${b}",
+				[1, ...rest] => "rest ${rest}",
+				{"type": "circle", "r": r} => "circle ${r}",
+				{"type": t} => "shape ${t}",
+				_ => "other",
+			}
+		};
+	`
+
+	tests := []vmTestCase{
+		{describe + "describe(0)", "zero"},
+		{describe + "describe(-1)", "minus one"},
+		{describe + `describe("x")`, "ex"},
+		{describe + "describe(true)", "yes"},
+		{describe + "describe(2.5)", "float"},
+		{describe + "describe([])", "empty"},
+		{describe + "describe([3, 3])", "same 3"},
+		{describe + "describe([3, 4])", "pair 3 4"},
+		{describe + "describe([1, 2, 3])", "rest [2, 3]"},
+		{describe + "describe([2, 2, 3])", "other"},
+		{describe + `describe({"type": "circle", "r": 2})`, "circle 2"},
+		{describe + `describe({"type": "square"})`, "shape square"},
+		{describe + `describe({"kind": "square"})`, "other"},
+		{describe + "describe(1.0)", "other"},
+		{describe + "describe(0.0)", "zero"},
+		{describe + "describe(-0.0)", "zero"},
+		{describe + "describe(-1.0)", "minus one"},
+		{`match 1 { 1.0 => "float", 1 => "int" }`, "float"},
+		{`match 2.0 { 1 => "one", 2 => "two" }`, "two"},
+		{`match 2.0 { n if n > 5 => "big", 2 => "two" }`, "two"},
+		{describe + "describe(false)", "other"},
+		{"match 5 { 1 => 10 }", Null},
+		{"match 5 { 1 => 10, n => n * 2 }", 10},
+		{"match 1 { 1 => 10, 1 => 20 }", 10},
+		{"match 2 { n if n > 5 => 1, n if n > 1 => 2, _ => 3 }", 2},
+		{"match [1, [2, 3]] { [x, [y, z]] => { let sum = x + y; sum + z } }", 6},
+		{"let f = fn(x) { match x { 1 => { return 10; }, _ => 20 } }; f(1) + f(2)", 30},
+		{"let total = 0; for i in range(0, 4, 1) { total = total + match i { 0 => 100, k => k }; } total", 106},
+	}
+
+	runVmTests(t, tests)
+}
+
 func TestCallingFunctionWithoutArguments(t *testing.T) {
 	tests := []vmTestCase{
 		{"fn() { 5 } ()", 5},
@@ -503,15 +911,35 @@ func TestFunctionCalls_WrongArguments(t *testing.T) {
 	tests := []vmTestCase{
 		{
 			input:    `fn() { }(1);`,
-			expected: ErrWrongNumberOfArguments(0, 1),
+			expected: ErrWrongNumberOfArguments("", object.ExactArity(0), 1),
 		},
 		{
 			input:    `fn(a, b) { }(1);`,
-			expected: ErrWrongNumberOfArguments(2, 1),
+			expected: ErrWrongNumberOfArguments("", object.ExactArity(2), 1),
 		},
 		{
 			input:    `fn(a) { }();`,
-			expected: ErrWrongNumberOfArguments(1, 0),
+			expected: ErrWrongNumberOfArguments("", object.ExactArity(1), 0),
+		},
+		{
+			input:    `fn log(level, msg = "") { } log();`,
+			expected: ErrWrongNumberOfArguments("log", object.Arity{Min: 1, Max: 2}, 0),
+		},
+		{
+			input:    `let log = fn(level, msg = "") { }; log(1, 2, 3);`,
+			expected: ErrWrongNumberOfArguments("log", object.Arity{Min: 1, Max: 2}, 3),
+		},
+		{
+			input:    `fn log(level, ...rest) { } log();`,
+			expected: ErrWrongNumberOfArguments("log", object.Arity{Min: 1, Max: object.VariadicArity}, 0),
+		},
+		{
+			input:    `len("a", "b");`,
+			expected: ErrWrongNumberOfArguments("len", object.ExactArity(1), 2),
+		},
+		{
+			input:    `range();`,
+			expected: ErrWrongNumberOfArguments("range", object.Arity{Min: 1, Max: 3}, 0),
 		},
 	}
 
@@ -572,3 +1000,778 @@ func TestStdLibFuncs_Print(t *testing.T) {
 
 	runVmTests(t, tests)
 }
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+				let new_closure = fn(a) {
+					fn() { a; };
+				};
+
+				let closure = new_closure(99);
+				closure();
+			`,
+			expected: 99,
+		},
+		{
+			input: `
+				let new_adder = fn(a, b) {
+					fn(c) { a + b + c };
+				};
+
+				let adder = new_adder(1, 2);
+				adder(8);
+			`,
+			expected: 11,
+		},
+		{
+			input: `
+				let new_adder = fn(a, b) {
+					let c = a + b;
+					fn(d) { c + d };
+				};
+
+				let adder = new_adder(1, 2);
+				adder(8);
+			`,
+			expected: 11,
+		},
+		{
+			input: `
+				let new_adder_outer = fn(a, b) {
+					let c = a + b;
+					fn(d) {
+						let e = d + c;
+						fn(f) { e + f; };
+					};
+				};
+
+				let new_adder_inner = new_adder_outer(1, 2);
+				let adder = new_adder_inner(3);
+				adder(8);
+			`,
+			expected: 14,
+		},
+		{
+			input: `
+				let a = 1;
+				let new_adder_outer = fn(b) {
+					fn(c) {
+						fn(d) { a + b + c + d };
+					};
+				};
+
+				let new_adder_inner = new_adder_outer(2);
+				let adder = new_adder_inner(3);
+				adder(8);
+			`,
+			expected: 14,
+		},
+		{
+			input: `
+				let new_closure = fn(a, b) {
+					let one = fn() { a; };
+					let two = fn() { b; };
+					fn() { one() + two(); };
+				};
+
+				let closure = new_closure(9, 90);
+				closure();
+			`,
+			expected: 99,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+				fn countdown(x) {
+					if x == 0 {
+						return 0;
+					}
+					countdown(x - 1);
+				}
+
+				countdown(1);
+			`,
+			expected: 0,
+		},
+		{
+			input: `
+				fn fibonacci(n) {
+					if n < 2 {
+						return n;
+					}
+					return fibonacci(n-2) + fibonacci(n-1);
+				}
+
+				fibonacci(15);
+			`,
+			expected: 610,
+		},
+		{
+			input: `
+				let wrapper = fn() {
+					fn countdown(x) {
+						if x == 0 {
+							return 0;
+						}
+						countdown(x - 1);
+					}
+
+					countdown(1);
+				};
+
+				wrapper();
+			`,
+			expected: 0,
+		},
+		{
+			input: `
+				let wrapper = fn() {
+					fn countdown(x) {
+						let step = fn() { countdown(x - 1) };
+
+						if x == 0 {
+							return 0;
+						}
+						step();
+					}
+
+					countdown(3);
+				};
+
+				wrapper();
+			`,
+			expected: 0,
+		},
+		{
+			input: `
+				fn is_even(n) {
+					if n == 0 {
+						return true;
+					}
+					is_odd(n - 1);
+				}
+
+				fn is_odd(n) {
+					if n == 0 {
+						return false;
+					}
+					is_even(n - 1);
+				}
+
+				is_even(10) == is_odd(7);
+			`,
+			expected: true,
+		},
+		{
+			input: `
+				let wrapper = fn(n) {
+					fn is_even(n) {
+						if n == 0 { return true; }
+						is_odd(n - 1);
+					}
+
+					fn is_odd(n) {
+						if n == 0 { return false; }
+						is_even(n - 1);
+					}
+
+					is_even(n);
+				};
+
+				wrapper(10) == wrapper(7);
+			`,
+			expected: false,
+		},
+		{"let r = 0; if true { fn a() { b() + 1 } fn b() { 4 } r = a(); } r", 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestAssignStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one = 2; one", 2},
+		{"let one = 1; one = one + one; one", 2},
+		{
+			input: `
+				let global_num = 1;
+				let change = fn() { global_num = 10; };
+				change();
+				global_num;
+			`,
+			expected: 10,
+		},
+		{
+			input: `
+				let sum = fn(a, b) {
+					let c = a;
+					c = c + b;
+					a = 0;
+					c + a;
+				};
+				sum(1, 2);
+			`,
+			expected: 3,
+		},
+		{
+			input: `
+				let new_counter = fn() {
+					let count = 0;
+					fn() { count = count + 1; count };
+				};
+
+				let counter = new_counter();
+				counter();
+				counter();
+				counter();
+			`,
+			expected: 3,
+		},
+		{`let f = fn() { let c = 0; let inc = fn() { c = c + 1 }; inc(); inc(); c }; f()`, 2},
+		{`let f = fn() { let i = 0; let g = fn() { i }; i = 5; g() }; f()`, 5},
+		{`let f = fn(a) { let set = fn() { a = 7 }; set(); a }; f(1)`, 7},
+		{`let f = fn() { let x = 1; let mid = fn() { fn() { x = x + 10 } }; mid()(); x }; f()`, 11},
+		{`let mk = fn() { let n = 0; [fn() { n = n + 1; n }, fn() { n }] }; let pair = mk(); pair[0](); pair[0](); pair[1]()`, 2},
+		{`fn counter() { let k = 0; fn() { k = k + 1; k } } let first = counter(); let second = counter(); first(); first(); second()`, 1},
+		{`fn sum() { let total = 0; fn add(n) { if n == 0 { return 0; } total = total + n; add(n - 1) } add(3); total } sum()`, 6},
+		{`fn* gen() { let n = 0; let bump = fn() { n = n + 1 }; while true { bump(); yield n; } } let g = gen(); next(g); next(g)`, 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestConsts(t *testing.T) {
+	tests := []vmTestCase{
+		{"const A = 4; A", 4},
+		{"const A = -4; const B = A; A + B", -8},
+		{`const NAME = "qrk"; fn f() { NAME + "!" } f()`, "qrk!"},
+		{"const A = 4; fn f() { let A = 1; A = A + 1; A } f()", 2},
+		{"const A = [1]; A[0] = 2; A[0]", 2},
+		{"fn make() { const LIST = [1, 2]; fn() { LIST[1] } } make()()", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let i = 0; while i < 10 { i = i + 1; } i", 10},
+		{"let i = 0; while false { i = i + 1; } i", 0},
+		{"let i = 0; while true { i = i + 1; if i == 5 { break; } } i", 5},
+		{
+			input: `
+				let i = 0;
+				let sum = 0;
+				while i < 10 {
+					i = i + 1;
+					if i == 3 { continue; }
+					sum = sum + i;
+				}
+				sum;
+			`,
+			expected: 52,
+		},
+		{
+			input: `
+				let count_pairs = fn(n) {
+					let pairs = 0;
+					let i = 0;
+					while i < n {
+						let j = 0;
+						while true {
+							if j == i { break; }
+							pairs = pairs + 1;
+							j = j + 1;
+						}
+						i = i + 1;
+					}
+					pairs;
+				};
+				count_pairs(5);
+			`,
+			expected: 10,
+		},
+		{
+			input: `
+				let find = fn(limit) {
+					let i = 0;
+					while true {
+						if i * i > limit { return i; }
+						i = i + 1;
+					}
+				};
+				find(50);
+			`,
+			expected: 8,
+		},
+		{"let i = 0; let s = 0; while i < 3 { i = i + 1; s = s + if true { continue } else { i } } s", 0},
+		{"let i = 0; let m = {}; while i < 4 { i = i + 1; m[if i % 2 == 0 { continue } else { i }] = i; } m[3]", 3},
+		{"let i = 0; while true { i = i + 1; let x = [i, if i == 3 { break } else { i }]; } i", 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestForInStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let sum = 0; for x in [1, 2, 3] { sum = sum + x; } sum", 6},
+		{"let sum = 0; for i, x in [10, 20, 30] { sum = sum + i; } sum", 3},
+		{"let sum = 0; for x in [] { sum = sum + 1; } sum", 0},
+		{"let loop = fn() { for x in [1, 2] { x; } }; loop()", Null},
+		{"if true { for x in [1, 2] { x; } }", Null},
+		{`let keys = ""; for k in {"b": 1, "a": 2} { keys = keys + k; } keys`, "ab"},
+		{`let sum = 0; for k, v in {"b": 1, "a": 2} { sum = sum + v; } sum`, 3},
+		{`let out = ""; for c in "abc" { out = c + out; } out`, "cba"},
+		{"let sum = 0; for n in range(0, 5, 1) { sum = sum + n; } sum", 10},
+		{"let sum = 0; for n in range(10, 0, -3) { sum = sum + n; } sum", 22},
+		{"let sum = 0; for n in range(0, 10, 1) { if n == 2 { continue; } if n == 5 { break; } sum = sum + n; } sum", 8},
+		{"let sum = 0; for x in [1, 2, 3] { sum = sum + if x == 2 { continue } else { x } } sum", 4},
+		{"let sum = 0; for x in [1, 2, 3] { sum = sum + if x == 2 { break } else { x } } sum", 1},
+		{"let arr = []; for x in range(5) { arr = [x, if x > 2 { break } else { x }]; } arr", []int{2, 2}},
+		{
+			input: `
+				let first = fn(arr) {
+					for x in arr { return x; }
+				};
+				first([7, 8]);
+			`,
+			expected: 7,
+		},
+		{
+			input: `
+				let pairs = fn(n) {
+					let count = 0;
+					for i in range(0, n, 1) {
+						for j in range(0, i, 1) {
+							if j == 3 { break; }
+							count = count + 1;
+						}
+					}
+					count;
+				};
+				pairs(6);
+			`,
+			expected: 12,
+		},
+		{
+			input: `
+				let sum = fn(arr) {
+					let total = 0;
+					for x in arr { total = total + x; }
+					total;
+				};
+				sum(range(0, 4, 1)) + sum([len("abc")]);
+			`,
+			expected: 9,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestArrowFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let add = (x, y) => x + y; add(1, 2);", 3},
+		{"let inc = x => x + 1; inc(1);", 2},
+		{"(() => 5)();", 5},
+		{"let double = (x) => { let y = x * 2; y }; double(4);", 8},
+		{"let apply = fn(f, v) { f(v) }; apply(x => x * 10, 4);", 40},
+		{"let adder = a => b => a + b; adder(1)(2);", 3},
+		{`let handlers = {"double": x => x * 2}; handlers["double"](21);`, 42},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []vmTestCase{
+		{`let r = 0; try { throw 5; } catch (e) { r = e; }; r`, 5},
+		{`let r = 0; try { throw 1; } catch { r = 2; }; r`, 2},
+		{`let r = 0; try { r = 1; } catch (e) { r = 2; }; r`, 1},
+		{`let r = ""; try { 1 + "a"; } catch (e) { r = e["message"]; }; r`, "unsupported type for binary operation: INTEGER STRING"},
+		{`let r = ""; try { let a = [1]; a[5] = 2; } catch (e) { r = e["message"]; }; r`, "index out of bounds: 5, length is 1"},
+		{`let r = ""; try { range(0, 1, 0); } catch (e) { r = e["message"]; }; r`, "step of `range` must not be zero"},
+		{`let r = 0; try { 1 + "a"; } catch (e) { r = e["missing"]; }; r`, Null},
+		{`let r = ""; try { 1 + "a"; } catch (e) { r = e.message; }; r`, "unsupported type for binary operation: INTEGER STRING"},
+		{`fn f() { 1 / 0 } let r = ""; try { f(); } catch (e) { r = e.stack[0] + " " + e.stack[1]; }; r`, "f <main>"},
+		{`let r = 0; try { 1 + "a"; } catch (e) { r = e.missing; }; r`, Null},
+		{
+			`fn inner() { 1 + "a" }
+			fn outer() { inner() }
+			let r = "";
+			try { outer(); } catch (e) { r = e["stack"][0] + " " + e["stack"][1] + " " + e["stack"][2]; };
+			r`,
+			"inner outer <main>",
+		},
+		{`let f = fn() { 1 + "a" }; let r = ""; try { f(); } catch (e) { r = e["stack"][0]; }; r`, "f"},
+		{`fn call(f) { f() } let r = ""; try { call(fn() { 1 + "a" }); } catch (e) { r = e["stack"][0] + " " + e["stack"][1]; }; r`, "<anonymous> call"},
+		{`fn boom() { throw 3 } let r = 0; try { r = 1 + boom(); } catch (e) { r = e; }; r + 1`, 4},
+		{`let r = ""; try { r = "t"; } finally { r = r + "f"; }; r`, "tf"},
+		{`let r = ""; try { try { throw "a"; } finally { r = "f"; } } catch (e) { r = r + e; }; r`, "fa"},
+		{
+			`let r = "";
+			try {
+				try { throw "a"; } catch (e) { throw e + "b"; } finally { r = r + "f"; }
+			} catch (e) { r = r + e; };
+			r`,
+			"fab",
+		},
+		{`fn f() { try { return 1; } finally { 2; } } f()`, 1},
+		{`fn f() { try { return 1; } finally { return 2; } } f()`, 2},
+		{`let log = ""; fn f() { try { log = log + "t"; return 1; } finally { log = log + "f"; } } f(); log`, "tf"},
+		{`fn f() { try { throw 1; } catch (e) { return e + 1; } } f()`, 2},
+		{
+			`let total = 0;
+			let i = 0;
+			while i < 5 {
+				i = i + 1;
+				try { if (i == 2) { continue; } if (i == 4) { break; } total = total + i; } finally { total = total + 10; }
+			}
+			total`,
+			44,
+		},
+		{`let r = 0; for x in [1, 2, 3] { try { throw x; } catch (e) { r = r + e; } } r`, 6},
+		{`try { throw "boom"; } catch (e) { e; }`, "boom"},
+		{`fn() { try { 1; } catch (e) { 2; } }()`, 1},
+		{`fn() { try { throw 1; } catch (e) { 2; } }()`, 2},
+		{`fn() { try { 1; } finally { 2; } }()`, 1},
+		{`fn() { try { let a = 1; } catch (e) { 2; } }()`, Null},
+		{`if true { try { throw 1; } catch { 3; } }`, 3},
+		{
+			`let r = 0;
+			try {
+				try { throw 1; } finally {
+					try { try { throw 2; } finally { r = 10; } } catch (e) { r = r + e; }
+				}
+			} catch (e) { r = r * 10 + e; };
+			r`,
+			121,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestUncaughtExceptions(t *testing.T) {
+	tests := []struct {
+		input              string
+		expected           string
+		expectedStackTrace []string
+	}{
+		{`throw "boom";`, "uncaught exception: boom", []string{"<main>"}},
+		{`throw [1, 2];`, "uncaught exception: [1, 2]", []string{"<main>"}},
+		{`fn f() { throw "boom"; } fn g() { f() } g();`, "uncaught exception: boom", []string{"f", "g", "<main>"}},
+		{`fn f() { 1 + "a" } f();`, "unsupported type for binary operation: INTEGER STRING", []string{"f", "<main>"}},
+		{`try { throw 1; } catch (e) { throw e + 1; }`, "uncaught exception: 2", []string{"<main>"}},
+		{`try { 1 + "a"; } catch (e) { throw e; }`, "unsupported type for binary operation: INTEGER STRING", []string{"<main>"}},
+		{`fn f() { 1 + "a" } fn g() { try { f(); } catch (e) { throw e; } } g();`, "unsupported type for binary operation: INTEGER STRING", []string{"f", "g", "<main>"}},
+		{`fn* g() { yield 1 / 0; } fn f() { next(g()) } f();`, "division by zero", []string{"g", "f", "<main>"}},
+		{`fn* g() { throw "boom"; } for x in g() {}`, "uncaught exception: boom", []string{"g", "<main>"}},
+		{`fn* g() { throw "boom"; } fn f() { next(g()) } f();`, "uncaught exception: boom", []string{"g", "f", "<main>"}},
+		{`fn f() { throw "boom"; } join(spawn f())`, "uncaught exception: boom", []string{"f", "<main>"}},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := compiler.New()
+		if err := compiler.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(compiler.Bytecode())
+
+		err := vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error for %q, got none", tt.input)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error. expected=%q, got=%q", tt.expected, err.Error())
+		}
+
+		uncaught, ok := err.(*UncaughtException)
+		if !ok {
+			t.Fatalf("error is not *UncaughtException. got=%T", err)
+		}
+
+		stackTrace := uncaught.StackTrace()
+		if len(stackTrace) != len(tt.expectedStackTrace) {
+			t.Fatalf("wrong stack trace. expected=%v, got=%v", tt.expectedStackTrace, stackTrace)
+		}
+
+		for i, frame := range tt.expectedStackTrace {
+			if stackTrace[i] != frame {
+				t.Errorf("wrong stack trace. expected=%v, got=%v", tt.expectedStackTrace, stackTrace)
+			}
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+
+	modules := map[string]string{
+		"lib/strings.qrk": `
+			import "util.qrk" as util;
+			let calls = 0;
+			export fn shout(s) { calls = calls + 1; util.twice(s) + "!" }
+			export fn callsCount() { calls }
+			export let greeting = "hi";
+		`,
+		"lib/util.qrk": `export fn twice(s) { s + s }`,
+		"lib/geo.qrk":  `export struct Point { x, y }`,
+		"lib/gen.qrk":  `export fn* evens(limit) { let i = 0; while i < limit { yield i; i = i + 2; } }`,
+		"vendor/answer.qrk": `
+			fn compute() { 40 + offset() }
+			fn offset() { 2 }
+			export let answer = compute();
+		`,
+	}
+	for name, content := range modules {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []vmTestCase{
+		{`import "lib/strings.qrk" as s; s.shout("a")`, "aa!"},
+		{`import "lib/strings.qrk" as s; s.shout(s.greeting)`, "hihi!"},
+		{`import "lib/geo.qrk" as geo; let p = geo.Point("a", "b"); p.x = "c"; p.x + p.y`, "cb"},
+		{`import "lib/gen.qrk" as gen; let sum = 0; for e in gen.evens(7) { sum = sum + e; } sum`, 12},
+		{`import "lib/strings.qrk" as s; let calls = 10; s.shout("a"); s.shout("b"); calls + s.callsCount()`, 12},
+		{`import "lib/strings.qrk" as s; import "lib/strings.qrk" as again; s.shout("a"); again.callsCount()`, 1},
+		{`import "lib/strings.qrk" as s; fn f() { s.shout } f()("x")`, "xx!"},
+		{`import "answer.qrk" as a; a.answer`, 42},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		comp.SetLoader(module.NewLoader[*compiler.Module](filepath.Join(dir, "main.qrk"), filepath.Join(dir, "vendor")))
+
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		testExpectedObject(t, tt.expected, vm.LastPoppedStackElem())
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []vmTestCase{
+		{`fn add(a, b = 10) { a + b } add(1)`, 11},
+		{`fn add(a, b = 10) { a + b } add(1, 2)`, 3},
+		{`fn add(a, b = a * 2, c = a + b) { [a, b, c] } add(1)`, []int{1, 2, 3}},
+		{`fn add(a, b = a * 2, c = a + b) { [a, b, c] } add(1, 5)`, []int{1, 5, 6}},
+		{`let calls = 0; fn next() { calls = calls + 1; calls } fn f(x = next()) { x } f(); f(); f(7); f()`, 3},
+		{`let base = 100; fn f() { let g = fn(x = base) { x }; g() } f()`, 100},
+		{`fn f(x = 1) { let y = 2; x + y } f()`, 3},
+		{`fn log(level, msg = "", ...rest) { rest } log(1)`, []int{}},
+		{`fn log(level, msg = "", ...rest) { rest } log(1, "a", 2, 3)`, []int{2, 3}},
+		{`fn log(level, msg = "", ...rest) { msg } log(1)`, ""},
+		{`fn all(...items) { items } all()`, []int{}},
+		{`fn all(...items) { items } all(1, 2, 3)`, []int{1, 2, 3}},
+		{`let f = fn(a, ...rest) { fn() { rest } }; f(1, 2, 3)()`, []int{2, 3}},
+		{`let add = (a, b = 10) => a + b; add(1)`, 11},
+		{`let add = (a, b = 10) => a + b; add(1, 2)`, 3},
+		{`let all = (...items) => items; all(1, 2, 3)`, []int{1, 2, 3}},
+		{`let tail = (a, ...rest) => rest; tail(1, 2, 3)`, []int{2, 3}},
+		{`let total = 0; for i in range(4) { total = total + i; } total`, 6},
+		{`let total = 0; for i in range(2, 5) { total = total + i; } total`, 9},
+		{`let total = 0; for i in range(10, 0, -3) { total = total + i; } total`, 22},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStructs(t *testing.T) {
+	tests := []vmTestCase{
+		{`struct Point { x, y } let p = Point(1, 2); p.x + p.y`, 3},
+		{`struct Point { x, y } let p = Point(1, 2); p.x = 10; p.x`, 10},
+		{`struct Point { x, y } let p = Point(1, 2); let q = p; q.y = 5; p.y`, 5},
+		{`struct Point { x, y } struct Line { from, to } let l = Line(Point(1, 2), Point(3, 4)); l.to.x`, 3},
+		{`struct Point { x, y } struct Line { from, to } let l = Line(Point(1, 2), Point(3, 4)); l.from.y = 7; l.from.y`, 7},
+		{`struct Point { x, y } [Point(1, 2), Point(3, 4)][1].y`, 4},
+		{`struct Point { x, y } fn norm(p) { p.x * p.x + p.y * p.y } norm(Point(3, 4))`, 25},
+		{`fn make() { struct Pair { first, second } Pair(1, "a") } make().second`, "a"},
+		{`struct Point { x, y } let ctor = Point; ctor(5, 6).y`, 6},
+		{`struct Point { x, y } let p = Point(1, 2); p == p`, true},
+		{`struct Point { x, y } Point(1, 2) == Point(1, 2)`, false},
+		{`struct Point { x, y } let p = Point(1, 2); try { p.z } catch (e) { e["message"] }`, "no such field: Point.z"},
+		{`fn mk() { Point(1, 2) } struct Point { x, y } mk().x`, 1},
+		{`fn() { fn mk() { Pair(1, 2) } struct Pair { first, second } mk().second }()`, 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input:    `struct Point { x, y } Point(1, 2).z`,
+			expected: ErrNoSuchField("Point", "z"),
+		},
+		{
+			input:    `struct Point { x, y } let p = Point(1, 2); p.z = 3;`,
+			expected: ErrNoSuchField("Point", "z"),
+		},
+		{
+			input:    `struct Point { x, y } Point(1);`,
+			expected: ErrWrongNumberOfArguments("Point", object.ExactArity(2), 1),
+		},
+		{
+			input:    `[1].x`,
+			expected: ErrMemberAccessNotSupported(object.ARRAY_OBJ),
+		},
+		{
+			input:    `let n = 1; n.x = 2;`,
+			expected: ErrMemberAccessNotSupported(object.INTEGER_OBJ),
+		},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err := vm.Run()
+		if err == nil {
+			t.Fatalf("expected vm error for %q, got none", tt.input)
+		}
+
+		if err.Error() != tt.expected.(error).Error() {
+			t.Errorf("wrong vm error. got=%q, want=%q", err.Error(), tt.expected)
+		}
+	}
+}
+
+func TestHashMapMembers(t *testing.T) {
+	tests := []vmTestCase{
+		{`let m = {"x": 1}; m.x`, 1},
+		{`let m = {"x": 1}; m.y`, Null},
+		{`let m = {"x": 1}; m.x = 2; m["x"]`, 2},
+		{`let m = {}; m.y = 3; m.y`, 3},
+		{`let m = {"inner": {"v": 4}}; m.inner.v`, 4},
+		{`let m = {"inner": {"v": 4}}; m.inner.v = 5; m["inner"]["v"]`, 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []vmTestCase{
+		{`let p = {"name": "Ann", "greet": fn(self) { "hi, " + self.name }}; p.greet()`, "hi, Ann"},
+		{`let p = {"name": "Ann", "greet": fn(self, greeting = "hi") { greeting + ", " + self.name }}; p.greet("yo")`, "yo, Ann"},
+		{`let p = {"age": 1, "birthday": fn(self) { self.age = self.age + 1; }}; p.birthday(); p.birthday(); p.age`, 3},
+		{`let p = {"double": fn(x) { x * 2 }}; p.double(21)`, 42},
+		{`let p = {"live": fn() { "living" }}; p.live()`, "living"},
+		{`let p = {"len": len}; p.len("abc")`, 3},
+		{`let p = {"name": "Ann", "greet": fn(self) { self.name }}; p["greet"]({"name": "Bob"})`, "Bob"},
+		{`let p = {"name": "Ann", "greet": fn(self) { self.name }}; let greet = p.greet; greet(p)`, "Ann"},
+		{`fn make() { {"n": 0, "inc": fn(self) { self.n = self.n + 1; self }} } make().inc().inc().n`, 2},
+		{`struct Counter { n, inc } let c = Counter(0, fn(self, by) { self.n = self.n + by; }); c.inc(5); c.n`, 5},
+		{`let p = {"args": fn(self, ...rest) { rest }}; p.args(1, 2)`, []int{1, 2}},
+		{`let p = {"name": "Ann"}; try { p.name() } catch (e) { e["message"] }`, "calling a non-function object: STRING"},
+		{`let p = {"f": fn(self) { 1 }}; try { p.f(1) } catch (e) { e["message"] }`, "wrong number of arguments passed to <anonymous>, expected=1, got=2"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []vmTestCase{
+		{`fn* count() { yield 1; yield 2; } let g = count(); [next(g), next(g)]`, []int{1, 2}},
+		{`fn* count() { yield 1; yield 2; } let g = count(); next(g); next(g); next(g); next(g)`, Null},
+		{`fn* count(from, to = from + 2) { let i = from; while i <= to { yield i; i = i + 1; } } let sum = 0; for x in count(3) { sum = sum + x; } sum`, 12},
+		{`fn* letters() { yield "a"; yield "b"; } let keys = 0; for i, x in letters() { keys = keys + i; } keys`, 1},
+		{`fn* nat() { let n = 0; while true { yield n; n = n + 1; } } let sum = 0; for n in nat() { if n > 4 { break; } sum = sum + n; } sum`, 10},
+		{`fn* empty() { yield; } next(empty())`, Null},
+		{`fn* early() { yield 1; return 2; yield 3; } let g = early(); next(g); next(g)`, Null},
+		{`let g = fn*(...xs) { for x in xs { yield x * 2; } }; let out = ""; for x in g(1, 2, 3) { out = "${out}${x}"; } out`, "246"},
+		{`fn* inner() { yield 1; yield 2; } fn* outer() { for x in inner() { yield x * 10; } } let g = outer(); next(g) + next(g)`, 30},
+		{`fn* counter() { let n = 0; while true { n = n + 1; yield n; } } let a = counter(); let b = counter(); next(a); next(a); next(b)`, 1},
+		{`let calls = 0; fn* lazy() { calls = calls + 1; yield calls; } let g = lazy(); calls`, 0},
+		{`fn* safe() { try { yield "a"; throw "x"; } catch (e) { yield e; } } let g = safe(); next(g) + next(g)`, "ax"},
+		{`fn* safe() { try { yield "a"; throw "x"; } catch (e) { yield e; } } let g = safe(); next(g); fn deep(a, b) { [a, b, next(g)] } deep(1, 2)[2]`, "x"},
+		{`fn* fails() { yield 1; throw "boom"; } let g = fails(); next(g); try { next(g) } catch (e) { e }`, "boom"},
+		{`fn* fails() { throw "boom"; } let g = fails(); try { next(g) } catch (e) {} next(g)`, Null},
+		{`fn* divide() { yield 1 / 0; } try { for x in divide() {} } catch (e) { e["message"] }`, "division by zero"},
+		{`let holder = {}; fn* selfish() { yield next(holder.gen); } holder.gen = selfish(); try { next(holder.gen) } catch (e) { e["message"] }`, "generator selfish is already running"},
+		{`try { next(1) } catch (e) { e["message"] }`, "argument to `next` must be GENERATOR, got INTEGER"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestTasks(t *testing.T) {
+	tests := []vmTestCase{
+		{`fn square(x) { x * x } let tasks = [spawn square(2), spawn square(3)]; join(tasks[0]) + join(tasks[1])`, 13},
+		{`join(spawn fn() { "done" })`, "done"},
+		{`join(spawn fn() { })`, Null},
+		{`join(spawn len("abc"))`, 3},
+		{`let counter = 1; let t = spawn fn() { counter = counter + 100; counter }; join(t) + counter`, 102},
+		{`let inc = fn() { let n = 0; fn() { n = n + 1; n } }(); join(spawn inc); inc()`, 1},
+		{`fn fib(n) { if n < 2 { return n; } fib(n - 1) + fib(n - 2) } join(spawn fib(15))`, 610},
+		{`let t = spawn fn() { throw "boom"; }; try { join(t) } catch (e) { e }`, "boom"},
+		{`let t = spawn fn() { 1 / 0 }; try { join(t) } catch (e) { e["message"] }`, "division by zero"},
+		{`let t = spawn fn(x) { x }(1, 2); try { join(t) } catch (e) { e["message"] }`, "wrong number of arguments passed to <anonymous>, expected=1, got=2"},
+		{`let t = spawn 1; try { join(t) } catch (e) { e["message"] }`, "calling a non-function object: INTEGER"},
+		{`let c = channel(); try { recv(c) } catch (e) { e["message"] }`, "deadlock: recv on channel with no senders"},
+		{`let c = channel(); try { send(c, 1) } catch (e) { e["message"] }`, "deadlock: send on channel with no receivers"},
+		{`try { select([channel(), channel(1)]) } catch (e) { e["message"] }`, "deadlock: select on channels with no senders"},
+		{`let c = channel(); let t = spawn fn() { recv(c) }; try { join(t) } catch (e) { e["message"][:9] }`, "deadlock:"},
+		{`let c = channel(); try { recv(c) } catch { 0 } spawn fn() { send(c, 5) }; recv(c)`, 5},
+		{`try { join(1) } catch (e) { e["message"] }`, "argument to `join` must be TASK, got INTEGER"},
+		{`let h = {"n": 1}; join(spawn fn() { h["n"] = 2; }); h["n"]`, 1},
+		{`let h = {"n": 1}; join(spawn fn(m) { m["n"] = 2; }(h)); h["n"]`, 1},
+		{`let h = {"n": 1}; let fs = [fn() { h["n"] = 2; }]; join(spawn fn() { fs[0](); }); h["n"]`, 1},
+		{`let h = {"n": 1}; let t = spawn fn() { h["n"] = 2; h }; join(t)["n"]`, 2},
+		{`let a = [1]; let pair = [a, a]; join(spawn fn() { pair[0][0] = 5; pair[1][0] }())`, 5},
+		{`let ch = channel(1); let a = [1]; send(ch, a); a[0] = 2; recv(ch)[0]`, 1},
+		{`fn* gen() { yield 1; } let g = gen(); try { join(spawn fn() { next(g) }) } catch (e) { e["message"] }`, "generator gen belongs to another task"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestChannels(t *testing.T) {
+	tests := []vmTestCase{
+		{`let ch = channel(); spawn fn() { send(ch, 42); }; recv(ch)`, 42},
+		{`let ch = channel(); fn worker(id) { send(ch, id * 10); } for i in range(3) { spawn worker(i); } recv(ch) + recv(ch) + recv(ch)`, 30},
+		{`let ch = channel(2); send(ch, 1); send(ch, 2); close(ch); [recv(ch), recv(ch)]`, []int{1, 2}},
+		{`let ch = channel(1); close(ch); recv(ch)`, Null},
+		{`let ch = channel(); let t = spawn fn() { let sum = 0; while true { let v = recv(ch); if !v { break; } sum = sum + v; } sum }; for i in range(5) { send(ch, i); } close(ch); join(t)`, 10},
+		{`let a = channel(); let b = channel(); spawn fn() { send(b, "b"); }; select([a, b])[0]`, 1},
+		{`let a = channel(); let b = channel(); spawn fn() { send(b, "b"); }; select([a, b])[1]`, "b"},
+		{`let a = channel(); close(a); select([a])[1]`, Null},
+		{`let ch = channel(); close(ch); try { send(ch, 1) } catch (e) { e["message"] }`, "send on closed channel"},
+		{`let ch = channel(); close(ch); try { close(ch) } catch (e) { e["message"] }`, "close of closed channel"},
+		{`try { channel(-1) } catch (e) { e["message"] }`, "capacity of `channel` must not be negative, got -1"},
+		{`try { recv(1) } catch (e) { e["message"] }`, "argument to `recv` must be CHANNEL, got INTEGER"},
+		{`try { select([channel(), 1]) } catch (e) { e["message"] }`, "argument to `select` must be ARRAY of CHANNEL, got INTEGER at 1"},
+		{`try { select([]) } catch (e) { e["message"] }`, "argument to `select` must not be empty"},
+	}
+
+	runVmTests(t, tests)
+}